@@ -3,6 +3,7 @@ package handlers
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"image"
 
@@ -10,12 +11,15 @@ import (
 	"image/jpeg"
 	"image/png"
 	"io"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
 
 	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/services"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
 )
 
 // ImageHandler holds a reference to the service layer
@@ -40,6 +44,9 @@ type EmbedMetadata struct {
 	Title         *string `json:"title"`
 	Description   *string `json:"description"`
 	IsAIGenerated bool    `json:"is_ai_generated"`
+	// Owner and Tags are optional; similarity searches can filter on them.
+	Owner *string  `json:"owner"`
+	Tags  []string `json:"tags"`
 	// CapturedAt is optional; expected as RFC3339 string e.g. "2024-01-15T10:30:00Z"
 	CapturedAt *string `json:"captured_at"`
 }
@@ -62,11 +69,18 @@ type errorResponse struct {
 // Expects multipart/form-data with:
 //   - "image"    → image file  (JPEG or PNG)
 //   - "metadata" → JSON string (EmbedMetadata)
+//   - "mode"     → optional, "robust" (default) or "reversible"
+//   - "add_layer"→ optional boolean; mark an already watermarked image on
+//                  the next free layer instead of answering 409
 //
 // Returns:
 //   - The watermarked image as the response body (same format as input)
-//   - Header  X-Fingerprint: <comma-separated float64 values>
+//   - Header  X-Fingerprint: <base64 of the 1024 little-endian float32s>
 //   - Header  X-Image-ID:    <uuid of the stored metadata record>
+//   - Headers X-Duplicate-Of, X-Duplicate-Score, X-Duplicate-Action when
+//     EMBED_DUPLICATE_ACTION is set and a catalogued image matched: the
+//     upload is then refused with 409 ("reject"), or embedded and flagged
+//     ("warn") or recorded as derived from it ("link")
 
 func (h *ImageHandler) ImageWatermarkHandler(c *fiber.Ctx) error {
 
@@ -140,16 +154,50 @@ func (h *ImageHandler) ImageWatermarkHandler(c *fiber.Ctx) error {
 		Description:   embedMeta.Description,
 		MimeType:      &mimeType,
 		IsAIGenerated: embedMeta.IsAIGenerated,
+		Owner:         embedMeta.Owner,
+		Tags:          embedMeta.Tags,
 		CapturedAt:    capturedAt,
 	}
 
+	switch mode := strings.TrimSpace(c.FormValue("mode")); mode {
+	case "", "robust":
+	case "reversible":
+		serviceReq.Reversible = true
+	default:
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse{
+			Error: "invalid mode '" + mode + "', expected 'robust' or 'reversible'",
+		})
+	}
+
+	if layerStr := strings.TrimSpace(c.FormValue("add_layer")); layerStr != "" {
+		addLayer, err := strconv.ParseBool(layerStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse{
+				Error: "'add_layer' must be a boolean",
+			})
+		}
+		serviceReq.AddLayer = addLayer
+	}
+
 	// ── 6. Call service ───────────────────────────────────────────────
-	watermarkedImg, fingerprint, err := h.imageService.EmbedWatermarkInImage(c.Context(), img, serviceReq)
+	watermarkedImg, fingerprint, duplicate, err := h.imageService.EmbedWatermarkInImage(c.Context(), img, serviceReq)
+	if duplicate != nil {
+		setDuplicateHeaders(c, duplicate)
+	}
 	if err != nil {
 		// "already watermarked" is a 409 Conflict, everything else is 500
 		status := fiber.StatusInternalServerError
-		if err.Error() == "image is already watermarked" {
+		switch err.Error() {
+		case "image is already watermarked",
+			"image has no free watermark layer",
+			"image is a near-duplicate of an existing image":
 			status = fiber.StatusConflict
+		case "layered watermarks require robust mode":
+			status = fiber.StatusBadRequest
+		case "image has too little capacity for a reversible watermark":
+			status = fiber.StatusUnprocessableEntity
+		case "reversible watermarking is not configured":
+			status = fiber.StatusServiceUnavailable
 		}
 		return c.Status(status).JSON(errorResponse{Error: err.Error()})
 	}
@@ -177,23 +225,22 @@ func (h *ImageHandler) ImageWatermarkHandler(c *fiber.Ctx) error {
 		})
 	}
 
-	// ── 8. Attach fingerprint as response header ──────────────────────
-	// Serialise []float64 → JSON array and put it in X-Fingerprint header.
-	fpJSON, err := json.Marshal(fingerprint)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse{
-			Error: "failed to serialise fingerprint",
-		})
-	}
-
-	// ── 9. Stream image back to client ────────────────────────────────
+	// ── 8. Stream image back to client ────────────────────────────────
+	// The fingerprint goes in X-Fingerprint as base64 (~5.5 KB).
 	c.Set(fiber.HeaderContentType, "image/"+format)
 	c.Set("Content-Disposition", "attachment; filename=watermarked."+format)
-	c.Set("X-Fingerprint", string(fpJSON)) // e.g. [0.12,0.98, ...]
+	c.Set("X-Fingerprint", fingerprint.String())
 
 	return c.Send(buf.Bytes())
 }
 
+// setDuplicateHeaders reports the catalogued image an upload duplicates.
+func setDuplicateHeaders(c *fiber.Ctx, d *services.DuplicateMatch) {
+	c.Set("X-Duplicate-Of", d.ImageID.String())
+	c.Set("X-Duplicate-Score", strconv.FormatFloat(float64(d.Score), 'f', 4, 32))
+	c.Set("X-Duplicate-Action", string(d.Action))
+}
+
 // -----------------------------------------------------------------------
 // HANDLER 2 — Authenticate image
 // -----------------------------------------------------------------------
@@ -202,14 +249,42 @@ func (h *ImageHandler) ImageWatermarkHandler(c *fiber.Ctx) error {
 //   - "image" → image file (JPEG or PNG)
 //   - "k"     → optional integer string, number of similar images to return
 //               (defaults to 5 if omitted)
+//   - "match_orientations" → optional boolean; also match mirrored and
+//               rotated copies and report the transform per similar image
+//   - "colour_weight" → optional number 0..1; share of colour similarity
+//               in the ranking (default 0, structure only)
+//   - "min_score" → optional number 0..1; leave out similar images
+//               scoring below it (default 0, no limit)
+//   - "fallback" → optional boolean; when the watermark can't be read,
+//               answer with the similarity search and a "provenance" guess
+//               instead of 404
 //
 // Returns JSON:
 //
 //	{
 //	  "watermark_valid": true,
 //	  "extracted_metadata": { ...models.ImageMetadata fields... },
+//	  "layers": [ { "Layer": 0, "Metadata": {...} }, ... ],
 //	  "similar_images": [ { ...models.ImageMetadata... }, ... ],
-//	  "similarity_scores": [0.98, 0.94, ...]
+//	  "similarity_scores": [0.98, 0.94, ...],
+//	  "similar_transforms": ["identity", "rotate_90", ...],
+//	  "structure_scores": [...], "colour_scores": [...]   (colour_weight > 0)
+//	  "similar_verdicts": ["exact-copy", "related", ...],
+//	  "verdict": "exact-copy",
+//	  "integrity": {
+//	    "ImageID": "...", "Score": 0.998,
+//	    "Verdict": "watermark matches content"
+//	  },
+//	  "provenance": null
+//	}
+//
+// In fallback mode an image without a readable watermark comes back with
+// watermark_valid false, no layers or integrity, and:
+//
+//	"provenance": {
+//	  "Reason": "no watermark detected in image",
+//	  "Verdict": "watermark missing, likely derived from <uuid>",
+//	  "Original": {...}, "Score": 0.97
 //	}
 
 func (h *ImageHandler) ImageAuthHandler(c *fiber.Ctx) error {
@@ -244,7 +319,7 @@ func (h *ImageHandler) ImageAuthHandler(c *fiber.Ctx) error {
 		})
 	}
 
-	// ── 2. Parse optional k (number of similar images) ────────────────
+	// ── 2. Parse optional k and search options ────────────────────────
 	k := 5 // sensible default
 	if kStr := strings.TrimSpace(c.FormValue("k")); kStr != "" {
 		if _, err := fmt.Sscanf(kStr, "%d", &k); err != nil || k < 1 {
@@ -254,8 +329,46 @@ func (h *ImageHandler) ImageAuthHandler(c *fiber.Ctx) error {
 		}
 	}
 
+	authReq := services.AuthRequest{K: k}
+	if orientStr := strings.TrimSpace(c.FormValue("match_orientations")); orientStr != "" {
+		matchOrientations, err := strconv.ParseBool(orientStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse{
+				Error: "'match_orientations' must be a boolean",
+			})
+		}
+		authReq.MatchOrientations = matchOrientations
+	}
+	if wStr := strings.TrimSpace(c.FormValue("colour_weight")); wStr != "" {
+		w, err := strconv.ParseFloat(wStr, 64)
+		if err != nil || w < 0 || w > 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse{
+				Error: "'colour_weight' must be a number between 0 and 1",
+			})
+		}
+		authReq.ColourWeight = w
+	}
+	if mStr := strings.TrimSpace(c.FormValue("min_score")); mStr != "" {
+		m, err := strconv.ParseFloat(mStr, 32)
+		if err != nil || m < 0 || m > 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse{
+				Error: "'min_score' must be a number between 0 and 1",
+			})
+		}
+		authReq.MinScore = float32(m)
+	}
+	if fStr := strings.TrimSpace(c.FormValue("fallback")); fStr != "" {
+		fallback, err := strconv.ParseBool(fStr)
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse{
+				Error: "'fallback' must be a boolean",
+			})
+		}
+		authReq.Fallback = fallback
+	}
+
 	// ── 3. Call service ───────────────────────────────────────────────
-	authResult, err := h.imageService.ImageAuth(c.Context(), img, k)
+	authResult, err := h.imageService.ImageAuth(c.Context(), img, authReq)
 	if err != nil {
 		// Distinguish "no watermark" (404) from other failures (500)
 		status := fiber.StatusInternalServerError
@@ -265,7 +378,8 @@ func (h *ImageHandler) ImageAuthHandler(c *fiber.Ctx) error {
 		case "no watermark detected in image":
 			status = fiber.StatusNotFound
 		case "failed to extract watermark",
-			"metadata not found for extracted watermark ID":
+			"metadata not found for extracted watermark ID",
+			"colour fingerprints are not available":
 			status = fiber.StatusUnprocessableEntity
 		}
 
@@ -275,3 +389,568 @@ func (h *ImageHandler) ImageAuthHandler(c *fiber.Ctx) error {
 	// ── 4. Return structured JSON result ──────────────────────────────
 	return c.Status(fiber.StatusOK).JSON(authResult)
 }
+
+// -----------------------------------------------------------------------
+// HANDLER 3 — Remove a reversible watermark (protected)
+// -----------------------------------------------------------------------
+//
+// Expects multipart/form-data with:
+//   - "image" → PNG produced by the watermark endpoint in reversible mode
+//
+// Returns:
+//   - The restored original as a PNG body
+//   - Header  X-Image-ID: <uuid of the metadata record the mark pointed to>
+
+func (h *ImageHandler) ImageUnwatermarkHandler(c *fiber.Ctx) error {
+
+	// ── 1. Receive image ──────────────────────────────────────────────
+	fileHeader, err := c.FormFile("image")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse{
+			Error: "field 'image' is required (multipart/form-data)",
+		})
+	}
+
+	src, err := fileHeader.Open()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse{
+			Error: "could not open uploaded image",
+		})
+	}
+	defer src.Close()
+
+	imgBytes, err := io.ReadAll(src)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse{
+			Error: "could not read uploaded image",
+		})
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(imgBytes))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse{
+			Error: "invalid image file: " + err.Error(),
+		})
+	}
+
+	// ── 2. Call service ───────────────────────────────────────────────
+	original, meta, err := h.imageService.RemoveWatermark(c.Context(), img)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		switch err.Error() {
+		case "no reversible watermark found in image":
+			status = fiber.StatusNotFound
+		case "metadata not found for extracted watermark ID",
+			"all payloads failed CRC / flag validation":
+			status = fiber.StatusUnprocessableEntity
+		case "reversible watermarking is not configured":
+			status = fiber.StatusServiceUnavailable
+		}
+		return c.Status(status).JSON(errorResponse{Error: err.Error()})
+	}
+
+	// ── 3. Stream the original back losslessly ────────────────────────
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, original); err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse{
+			Error: "failed to encode restored image: " + err.Error(),
+		})
+	}
+
+	c.Set(fiber.HeaderContentType, "image/png")
+	c.Set("Content-Disposition", "attachment; filename=original.png")
+	c.Set("X-Image-ID", meta.ID.String())
+
+	return c.Send(buf.Bytes())
+}
+
+// -----------------------------------------------------------------------
+// HANDLER 4 — Near-duplicate lookup by perceptual hash
+// -----------------------------------------------------------------------
+//
+// Expects multipart/form-data with either:
+//   - "image"        → image file (JPEG or PNG) to hash, or
+//   - "hash"         → a hash in hex, as returned in "Query"
+//
+// and optionally:
+//   - "kind"         → phash (default), phash256, dhash, ahash or whash
+//   - "max_distance" → largest Hamming distance in bits that still counts
+//                      as a match (defaults to ~15% of the hash length)
+//   - "limit"        → maximum number of matches (defaults to 10)
+//
+// Returns JSON:
+//
+//	{
+//	  "Kind": "phash",
+//	  "Query": "c3d1e0f0a8b4c2d1",
+//	  "Matches": [ { "Distance": 2, "Metadata": {...} }, ... ]
+//	}
+
+func (h *ImageHandler) ImageHashLookupHandler(c *fiber.Ctx) error {
+
+	// ── 1. Parse hash kind and limits ─────────────────────────────────
+	req := services.HashLookupRequest{
+		Kind:  fingerprint.PHash,
+		Limit: 10,
+	}
+	if kind := strings.TrimSpace(c.FormValue("kind")); kind != "" {
+		req.Kind = fingerprint.HashKind(kind)
+		if req.Kind.Bits() == 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse{
+				Error: "invalid kind '" + kind + "', expected phash, phash256, dhash, ahash or whash",
+			})
+		}
+	}
+
+	req.MaxDistance = services.DefaultMaxDistance(req.Kind)
+	if dStr := strings.TrimSpace(c.FormValue("max_distance")); dStr != "" {
+		d, err := strconv.Atoi(dStr)
+		if err != nil || d < 0 || d > req.Kind.Bits() {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse{
+				Error: fmt.Sprintf("'max_distance' must be an integer between 0 and %d", req.Kind.Bits()),
+			})
+		}
+		req.MaxDistance = d
+	}
+
+	if lStr := strings.TrimSpace(c.FormValue("limit")); lStr != "" {
+		l, err := strconv.Atoi(lStr)
+		if err != nil || l < 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse{
+				Error: "'limit' must be a positive integer",
+			})
+		}
+		req.Limit = l
+	}
+
+	// ── 2. Receive the hash or the image to hash ──────────────────────
+	if hashStr := strings.TrimSpace(c.FormValue("hash")); hashStr != "" {
+		hash, err := fingerprint.ParseHash(hashStr)
+		if err != nil || len(hash)*64 != req.Kind.Bits() {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse{
+				Error: fmt.Sprintf("'hash' must be %d hex digits for kind %s", req.Kind.Bits()/4, req.Kind),
+			})
+		}
+		req.Hash = hash
+	} else {
+		fileHeader, err := c.FormFile("image")
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse{
+				Error: "field 'image' or 'hash' is required (multipart/form-data)",
+			})
+		}
+
+		src, err := fileHeader.Open()
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse{
+				Error: "could not open uploaded image",
+			})
+		}
+		defer src.Close()
+
+		imgBytes, err := io.ReadAll(src)
+		if err != nil {
+			return c.Status(fiber.StatusInternalServerError).JSON(errorResponse{
+				Error: "could not read uploaded image",
+			})
+		}
+
+		img, _, err := image.Decode(bytes.NewReader(imgBytes))
+		if err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse{
+				Error: "invalid image file: " + err.Error(),
+			})
+		}
+		req.Image = img
+	}
+
+	// ── 3. Call service ───────────────────────────────────────────────
+	result, err := h.imageService.FindByHash(c.Context(), req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse{Error: err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
+// -----------------------------------------------------------------------
+// HANDLER 5 — Crop lookup by local features
+// -----------------------------------------------------------------------
+//
+// Expects multipart/form-data with:
+//   - "image" → image file (JPEG or PNG), possibly cropped and rescaled
+//     from a watermarked original
+//
+// and optionally:
+//   - "limit" → maximum number of originals (defaults to 5)
+//
+// Returns JSON:
+//
+//	{
+//	  "Keypoints": 412,
+//	  "Matches": [
+//	    {
+//	      "Rect": { "x": 240, "y": 180, "width": 400, "height": 300 },
+//	      "Scale": 1.0, "Inliers": 96, "Matches": 131,
+//	      "Metadata": {...}
+//	    }
+//	  ]
+//	}
+
+func (h *ImageHandler) ImageCropLookupHandler(c *fiber.Ctx) error {
+
+	// ── 1. Parse limit ────────────────────────────────────────────────
+	req := services.CropLookupRequest{Limit: 5}
+	if lStr := strings.TrimSpace(c.FormValue("limit")); lStr != "" {
+		l, err := strconv.Atoi(lStr)
+		if err != nil || l < 1 {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse{
+				Error: "'limit' must be a positive integer",
+			})
+		}
+		req.Limit = l
+	}
+
+	// ── 2. Receive the image ──────────────────────────────────────────
+	fileHeader, err := c.FormFile("image")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse{
+			Error: "field 'image' is required (multipart/form-data)",
+		})
+	}
+
+	src, err := fileHeader.Open()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse{
+			Error: "could not open uploaded image",
+		})
+	}
+	defer src.Close()
+
+	imgBytes, err := io.ReadAll(src)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse{
+			Error: "could not read uploaded image",
+		})
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(imgBytes))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse{
+			Error: "invalid image file: " + err.Error(),
+		})
+	}
+	req.Image = img
+
+	// ── 3. Call service ───────────────────────────────────────────────
+	result, err := h.imageService.FindCrops(c.Context(), req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse{Error: err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(result)
+}
+
+// -----------------------------------------------------------------------
+// HANDLER 6 — Fingerprint index progress (protected)
+// -----------------------------------------------------------------------
+//
+// Returns JSON:
+//
+//	{
+//	  "target_version": 3,
+//	  "total": 1200, "current": 1150, "stale": 30, "unindexed": 15,
+//	  "pending": 3, "failed": 2,
+//	  "percent": 95.8,
+//	  "worker": { "running": false, "last_run": "...", "processed": 48, ... }
+//	}
+
+func (h *ImageHandler) IndexProgressHandler(c *fiber.Ctx) error {
+	progress, err := h.imageService.IndexProgress(c.Context())
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse{Error: err.Error()})
+	}
+	return c.Status(fiber.StatusOK).JSON(progress)
+}
+
+// -----------------------------------------------------------------------
+// HANDLER 7 — Similarity search by image ID, fingerprint or image
+// -----------------------------------------------------------------------
+//
+//   GET  /similar/:id          → neighbours of a catalogued image (itself
+//                                 left out)
+//   POST /similar/fingerprint  → body: the X-Fingerprint value, as is or
+//                                 as a JSON string (a JSON array of
+//                                 numbers is still accepted)
+//   POST /similar/image        → multipart/form-data "image" (JPEG or PNG)
+//
+// All three take, as query or form fields:
+//   - "limit"     → page size, 1..100 (defaults to 10)
+//   - "offset"    → neighbours to skip (defaults to 0)
+//   - "min_score" → optional number 0..1; leave out weaker neighbours
+//
+// and these optional filters on the neighbours' attributes, all of which
+// must hold:
+//   - "is_ai_generated" → boolean
+//   - "owner"           → exact owner
+//   - "mime_type"       → e.g. image/jpeg
+//   - "tags"            → comma-separated; every tag must be present
+//   - "created_from"    → uploaded at or after (RFC3339 or YYYY-MM-DD)
+//   - "created_to"      → uploaded before (RFC3339 or YYYY-MM-DD)
+//
+// e.g. ?is_ai_generated=false&owner=acme&created_from=2025-01-01&created_to=2026-01-01
+//
+// and return the same JSON page:
+//
+//	{
+//	  "Limit": 10, "Offset": 0, "NextOffset": 10,
+//	  "Neighbours": [
+//	    { "ImageID": "...", "Score": 0.97, "Verdict": "near-duplicate",
+//	      "Metadata": {...} }
+//	  ]
+//	}
+//
+// NextOffset is null on the last page.
+
+// parseSimilarRequest reads the paging fields shared by the three
+// similarity endpoints.
+func parseSimilarRequest(c *fiber.Ctx) (services.SimilarRequest, error) {
+	req := services.SimilarRequest{Limit: 10}
+
+	if lStr := strings.TrimSpace(c.FormValue("limit")); lStr != "" {
+		l, err := strconv.Atoi(lStr)
+		if err != nil || l < 1 || l > services.MaxSimilarLimit {
+			return req, fmt.Errorf("'limit' must be an integer between 1 and %d", services.MaxSimilarLimit)
+		}
+		req.Limit = l
+	}
+	if oStr := strings.TrimSpace(c.FormValue("offset")); oStr != "" {
+		o, err := strconv.Atoi(oStr)
+		if err != nil || o < 0 {
+			return req, errors.New("'offset' must be a non-negative integer")
+		}
+		req.Offset = o
+	}
+	if mStr := strings.TrimSpace(c.FormValue("min_score")); mStr != "" {
+		m, err := strconv.ParseFloat(mStr, 32)
+		if err != nil || m < 0 || m > 1 {
+			return req, errors.New("'min_score' must be a number between 0 and 1")
+		}
+		req.MinScore = float32(m)
+	}
+
+	filter, err := parseSimilarFilter(c)
+	if err != nil {
+		return req, err
+	}
+	if !filter.Empty() {
+		req.Filter = filter
+	}
+	return req, nil
+}
+
+// parseSimilarFilter reads the attribute filters of the similarity
+// endpoints.
+func parseSimilarFilter(c *fiber.Ctx) (*fingerprint.Filter, error) {
+	f := &fingerprint.Filter{
+		Owner:    strings.TrimSpace(c.FormValue("owner")),
+		MimeType: strings.TrimSpace(c.FormValue("mime_type")),
+	}
+
+	if aiStr := strings.TrimSpace(c.FormValue("is_ai_generated")); aiStr != "" {
+		ai, err := strconv.ParseBool(aiStr)
+		if err != nil {
+			return nil, errors.New("'is_ai_generated' must be a boolean")
+		}
+		f.IsAIGenerated = &ai
+	}
+	for _, tag := range strings.Split(c.FormValue("tags"), ",") {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			f.Tags = append(f.Tags, tag)
+		}
+	}
+
+	var err error
+	if f.CreatedFrom, err = parseFilterTime(c.FormValue("created_from")); err != nil {
+		return nil, errors.New("'created_from' must be RFC3339 or YYYY-MM-DD")
+	}
+	if f.CreatedTo, err = parseFilterTime(c.FormValue("created_to")); err != nil {
+		return nil, errors.New("'created_to' must be RFC3339 or YYYY-MM-DD")
+	}
+	if !f.CreatedFrom.IsZero() && !f.CreatedTo.IsZero() && !f.CreatedTo.After(f.CreatedFrom) {
+		return nil, errors.New("'created_to' must be after 'created_from'")
+	}
+	return f, nil
+}
+
+// parseFilterTime accepts an RFC3339 time or a UTC date; empty is zero.
+func parseFilterTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
+
+func (h *ImageHandler) SimilarByIDHandler(c *fiber.Ctx) error {
+
+	// ── 1. Parse ID and paging ────────────────────────────────────────
+	id, err := uuid.Parse(c.Params("id"))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse{
+			Error: "invalid image ID",
+		})
+	}
+	req, err := parseSimilarRequest(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse{Error: err.Error()})
+	}
+
+	// ── 2. Call service ───────────────────────────────────────────────
+	page, err := h.imageService.SimilarToImage(c.Context(), id, req)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		switch err.Error() {
+		case "image not found":
+			status = fiber.StatusNotFound
+		case "no fingerprint stored for image":
+			status = fiber.StatusUnprocessableEntity
+		}
+		return c.Status(status).JSON(errorResponse{Error: err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(page)
+}
+
+func (h *ImageHandler) SimilarByFingerprintHandler(c *fiber.Ctx) error {
+
+	// ── 1. Parse paging and the fingerprint body ──────────────────────
+	req, err := parseSimilarRequest(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse{Error: err.Error()})
+	}
+
+	var vec fingerprint.Vector
+	body := bytes.TrimSpace(c.Body())
+	if err := json.Unmarshal(body, &vec); err != nil {
+		if vec, err = fingerprint.ParseVector(string(body)); err != nil {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse{
+				Error: "body must be the base64 fingerprint from X-Fingerprint",
+			})
+		}
+	}
+
+	// ── 2. Call service ───────────────────────────────────────────────
+	page, err := h.imageService.SimilarToFingerprint(c.Context(), vec, req)
+	if err != nil {
+		status := fiber.StatusInternalServerError
+		if err.Error() == "fingerprint has the wrong dimension" {
+			status = fiber.StatusBadRequest
+		}
+		return c.Status(status).JSON(errorResponse{Error: err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(page)
+}
+
+func (h *ImageHandler) SimilarByImageHandler(c *fiber.Ctx) error {
+
+	// ── 1. Parse paging ───────────────────────────────────────────────
+	req, err := parseSimilarRequest(c)
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse{Error: err.Error()})
+	}
+
+	// ── 2. Receive the image ──────────────────────────────────────────
+	fileHeader, err := c.FormFile("image")
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse{
+			Error: "field 'image' is required (multipart/form-data)",
+		})
+	}
+
+	src, err := fileHeader.Open()
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse{
+			Error: "could not open uploaded image",
+		})
+	}
+	defer src.Close()
+
+	imgBytes, err := io.ReadAll(src)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse{
+			Error: "could not read uploaded image",
+		})
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(imgBytes))
+	if err != nil {
+		return c.Status(fiber.StatusBadRequest).JSON(errorResponse{
+			Error: "invalid image file: " + err.Error(),
+		})
+	}
+
+	// ── 3. Call service ───────────────────────────────────────────────
+	page, err := h.imageService.SimilarToImageContent(c.Context(), img, req)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse{Error: err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(page)
+}
+
+// -----------------------------------------------------------------------
+// HANDLER 8 — Duplicate clusters (protected)
+// -----------------------------------------------------------------------
+//
+// Lists the clusters of near-identical images saved by the last run of
+// cmd/cluster-images, largest first. Query fields:
+//   - "limit"  → clusters per page, 1..100 (defaults to 20)
+//   - "offset" → clusters to skip (defaults to 0)
+//
+// Returns JSON:
+//
+//	{
+//	  "Total": 42, "Limit": 20, "Offset": 0,
+//	  "Clusters": [
+//	    { "ID": 1, "Size": 3, "Members": [ {...}, {...}, {...} ] }
+//	  ]
+//	}
+//
+// Members are ordered oldest record first.
+
+func (h *ImageHandler) ClusterReportHandler(c *fiber.Ctx) error {
+
+	// ── 1. Parse paging ───────────────────────────────────────────────
+	limit, offset := 20, 0
+	if lStr := strings.TrimSpace(c.Query("limit")); lStr != "" {
+		l, err := strconv.Atoi(lStr)
+		if err != nil || l < 1 || l > services.MaxClusterPage {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse{
+				Error: fmt.Sprintf("'limit' must be an integer between 1 and %d", services.MaxClusterPage),
+			})
+		}
+		limit = l
+	}
+	if oStr := strings.TrimSpace(c.Query("offset")); oStr != "" {
+		o, err := strconv.Atoi(oStr)
+		if err != nil || o < 0 {
+			return c.Status(fiber.StatusBadRequest).JSON(errorResponse{
+				Error: "'offset' must be a non-negative integer",
+			})
+		}
+		offset = o
+	}
+
+	// ── 2. Call service ───────────────────────────────────────────────
+	page, err := h.imageService.ClusterReport(c.Context(), limit, offset)
+	if err != nil {
+		return c.Status(fiber.StatusInternalServerError).JSON(errorResponse{Error: err.Error()})
+	}
+
+	return c.Status(fiber.StatusOK).JSON(page)
+}
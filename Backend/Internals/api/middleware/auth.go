@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"crypto/subtle"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequireAPIKey only lets a request through when its X-API-KEY header
+// matches key. An empty key disables the protected routes entirely.
+func RequireAPIKey(key string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if key == "" {
+			return c.Status(fiber.StatusForbidden).JSON(fiber.Map{
+				"error": "protected endpoints are disabled (no API key configured)",
+			})
+		}
+
+		given := c.Get("X-API-KEY")
+		if subtle.ConstantTimeCompare([]byte(given), []byte(key)) != 1 {
+			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
+				"error": "missing or invalid X-API-KEY",
+			})
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,39 @@
+package routes
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/cors"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/api/handlers"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/api/middleware"
+)
+
+// NewApp builds the Fiber app with every API route mounted. main.go serves
+// it and the integration tests drive it in process.
+func NewApp(imageHandler *handlers.ImageHandler, adminAPIKey string) *fiber.App {
+	app := fiber.New(fiber.Config{
+		BodyLimit: 20 * 1024 * 1024, // 20 MB
+	})
+
+	app.Use(cors.New(cors.Config{
+		AllowOrigins:     "http://localhost:3000,http://localhost:5173", // Your frontend URLs
+		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
+		AllowHeaders:     "Origin,Content-Type,Accept,Authorization,X-API-KEY",
+		AllowCredentials: true, // IMPORTANT: Allow cookies to be sent
+	}))
+
+	api := app.Group("/api/v1")
+
+	api.Post("/watermark", imageHandler.ImageWatermarkHandler)
+	api.Post("/authenticate", imageHandler.ImageAuthHandler)
+	api.Post("/lookup/hash", imageHandler.ImageHashLookupHandler)
+	api.Post("/lookup/crop", imageHandler.ImageCropLookupHandler)
+	api.Get("/similar/:id", imageHandler.SimilarByIDHandler)
+	api.Post("/similar/fingerprint", imageHandler.SimilarByFingerprintHandler)
+	api.Post("/similar/image", imageHandler.SimilarByImageHandler)
+	api.Post("/unwatermark", middleware.RequireAPIKey(adminAPIKey), imageHandler.ImageUnwatermarkHandler)
+	api.Get("/admin/index", middleware.RequireAPIKey(adminAPIKey), imageHandler.IndexProgressHandler)
+	api.Get("/admin/clusters", middleware.RequireAPIKey(adminAPIKey), imageHandler.ClusterReportHandler)
+
+	return app
+}
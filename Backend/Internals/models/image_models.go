@@ -54,6 +54,9 @@ type ImageMetadata struct {
 	WidthPx       *int
 	HeightPx      *int
 	IsAIGenerated bool
+	Owner         *string    // organisation or account the image belongs to
+	Tags          []string   // never nil when read back
+	DerivedFrom   *uuid.UUID // the catalogued image this one duplicates
 	CapturedAt    *time.Time
 	CreatedAt     time.Time
 	UpdatedAt     time.Time
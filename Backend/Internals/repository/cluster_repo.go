@@ -0,0 +1,94 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+)
+
+// Cluster is one group of near-identical images found by the clustering
+// job. Members are ordered by serial_id, so the oldest record comes first.
+type Cluster struct {
+	ID      int64
+	Members []uuid.UUID
+}
+
+// ReplaceClusters stores the result of a clustering run in place of the
+// previous one. Cluster i gets ID i+1, so pass them largest first; rows
+// deleted since the run are skipped.
+func (db *DB) ReplaceClusters(ctx context.Context, clusters [][]uuid.UUID) error {
+	tx, err := db.pool.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM image_clusters;`); err != nil {
+		return err
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `
+    INSERT INTO image_clusters (image_id, cluster_id)
+    SELECT id, $2::integer FROM image_metadata WHERE id = $1;
+    `)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+
+	for i, members := range clusters {
+		for _, id := range members {
+			if _, err := stmt.ExecContext(ctx, id, int64(i+1)); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// ListClusters returns a page of clusters that still have at least two
+// members, by ID, and how many such clusters there are.
+func (db *DB) ListClusters(ctx context.Context, limit, offset int) ([]Cluster, int, error) {
+	var total int
+	err := db.pool.QueryRowContext(ctx, `
+    SELECT COUNT(*) FROM (
+        SELECT cluster_id FROM image_clusters GROUP BY cluster_id HAVING COUNT(*) > 1
+    ) c;
+    `).Scan(&total)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := `
+    SELECT c.cluster_id, c.image_id
+    FROM image_clusters c
+    JOIN image_metadata m ON m.id = c.image_id
+    WHERE c.cluster_id IN (
+        SELECT cluster_id FROM image_clusters
+        GROUP BY cluster_id HAVING COUNT(*) > 1
+        ORDER BY cluster_id
+        LIMIT $1 OFFSET $2
+    )
+    ORDER BY c.cluster_id, m.serial_id;
+    `
+	rows, err := db.pool.QueryContext(ctx, query, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	var clusters []Cluster
+	for rows.Next() {
+		var clusterID int64
+		var id uuid.UUID
+		if err := rows.Scan(&clusterID, &id); err != nil {
+			return nil, 0, err
+		}
+		if n := len(clusters); n == 0 || clusters[n-1].ID != clusterID {
+			clusters = append(clusters, Cluster{ID: clusterID})
+		}
+		last := &clusters[len(clusters)-1]
+		last.Members = append(last.Members, id)
+	}
+	return clusters, total, rows.Err()
+}
@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/features"
+	"github.com/google/uuid"
+)
+
+// FeatureCandidate is an image that shares LSH buckets with a query.
+// Votes counts the distinct shared buckets.
+type FeatureCandidate struct {
+	ImageID uuid.UUID
+	Votes   int
+}
+
+// distinctKeys drops repeated bucket keys, keeping first-seen order.
+func distinctKeys(keys []int64) []int64 {
+	seen := make(map[int64]bool, len(keys))
+	out := make([]int64, 0, len(keys))
+	for _, k := range keys {
+		if !seen[k] {
+			seen[k] = true
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// InsertImageFeatures stores (or replaces) the local features of an image
+// together with its bucket index rows.
+func (db *DB) InsertImageFeatures(
+	ctx context.Context,
+	imageID uuid.UUID,
+	set *features.Set,
+) error {
+
+	data, err := set.MarshalBinary()
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.pool.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := insertFeatures(ctx, tx, imageID, set, data); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertFeatures replaces the image_features row and bucket index of an
+// image with set, already marshalled as data. The caller provides the
+// transaction.
+func insertFeatures(
+	ctx context.Context,
+	ex execer,
+	imageID uuid.UUID,
+	set *features.Set,
+	data []byte,
+) error {
+
+	query := `
+    INSERT INTO image_features (image_id, keypoints, data)
+    VALUES ($1, $2, $3)
+    ON CONFLICT (image_id) DO UPDATE SET
+        keypoints = EXCLUDED.keypoints,
+        data      = EXCLUDED.data;
+    `
+	if _, err := ex.ExecContext(ctx, query, imageID, len(set.Keypoints), data); err != nil {
+		return err
+	}
+
+	if _, err := ex.ExecContext(ctx,
+		`DELETE FROM image_feature_buckets WHERE image_id = $1;`, imageID,
+	); err != nil {
+		return err
+	}
+
+	query = `
+    INSERT INTO image_feature_buckets (bucket_key, image_id)
+    SELECT unnest($2::bigint[]), $1;
+    `
+	_, err := ex.ExecContext(ctx, query, imageID, distinctKeys(set.BucketKeys()))
+	return err
+}
+
+// GetImageFeaturesBatch returns the stored feature sets of the given
+// images; images without features are left out of the map.
+func (db *DB) GetImageFeaturesBatch(
+	ctx context.Context,
+	ids []uuid.UUID,
+) (map[uuid.UUID]*features.Set, error) {
+
+	result := make(map[uuid.UUID]*features.Set)
+	if len(ids) == 0 {
+		return result, nil
+	}
+
+	query := `
+    SELECT image_id, data
+    FROM image_features
+    WHERE image_id = ANY($1::uuid[]);
+    `
+
+	strIDs := make([]string, len(ids))
+	for i, id := range ids {
+		strIDs[i] = id.String()
+	}
+
+	rows, err := db.pool.QueryContext(ctx, query, strIDs)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uuid.UUID
+		var data []byte
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		set := new(features.Set)
+		if err := set.UnmarshalBinary(data); err != nil {
+			return nil, fmt.Errorf("decode features of %s: %w", id, err)
+		}
+		result[id] = set
+	}
+	return result, rows.Err()
+}
+
+// FindFeatureCandidates returns up to limit images sharing the most
+// buckets with keys, most votes first.
+func (db *DB) FindFeatureCandidates(
+	ctx context.Context,
+	keys []int64,
+	limit int,
+) ([]FeatureCandidate, error) {
+
+	query := `
+    SELECT image_id, count(*) AS votes
+    FROM image_feature_buckets
+    WHERE bucket_key = ANY($1::bigint[])
+    GROUP BY image_id
+    ORDER BY votes DESC, image_id
+    LIMIT $2;
+    `
+
+	rows, err := db.pool.QueryContext(ctx, query, distinctKeys(keys), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candidates []FeatureCandidate
+	for rows.Next() {
+		var c FeatureCandidate
+		if err := rows.Scan(&c.ImageID, &c.Votes); err != nil {
+			return nil, err
+		}
+		candidates = append(candidates, c)
+	}
+	return candidates, rows.Err()
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
+	"github.com/google/uuid"
+)
+
+// HashMatch is one image found by a Hamming-distance lookup.
+type HashMatch struct {
+	ImageID  uuid.UUID
+	Distance int
+}
+
+// hashColumns maps each hash kind to its image_hashes column. Only names
+// from this map are ever formatted into SQL.
+var hashColumns = map[fingerprint.HashKind]string{
+	fingerprint.PHash:    "phash",
+	fingerprint.PHash256: "phash256",
+	fingerprint.DHash:    "dhash",
+	fingerprint.AHash:    "ahash",
+	fingerprint.WHash:    "whash",
+}
+
+// checkHashes makes sure every kind is present with the right length.
+func checkHashes(hashes fingerprint.ImageHashes) error {
+	for _, kind := range fingerprint.HashKinds {
+		if err := checkHash(kind, hashes[kind]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func checkHash(kind fingerprint.HashKind, h fingerprint.Hash) error {
+	if _, ok := hashColumns[kind]; !ok {
+		return fmt.Errorf("unknown hash kind %q", kind)
+	}
+	if len(h)*64 != kind.Bits() {
+		return fmt.Errorf("%s must be %d bits, got %d", kind, kind.Bits(), len(h)*64)
+	}
+	return nil
+}
+
+// execer runs a statement on the pool or inside a transaction, so the
+// hash and feature writes can join the transaction that inserts the image
+// row.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// InsertImageHashes stores (or replaces) the perceptual hashes of an image.
+func (db *DB) InsertImageHashes(
+	ctx context.Context,
+	imageID uuid.UUID,
+	hashes fingerprint.ImageHashes,
+) error {
+
+	if err := checkHashes(hashes); err != nil {
+		return err
+	}
+	return insertHashes(ctx, db.pool, imageID, hashes)
+}
+
+// insertHashes upserts the image_hashes row of an already checked set.
+func insertHashes(
+	ctx context.Context,
+	ex execer,
+	imageID uuid.UUID,
+	hashes fingerprint.ImageHashes,
+) error {
+
+	query := `
+    INSERT INTO image_hashes (image_id, phash, phash256, dhash, ahash, whash)
+    VALUES ($1, $2::bit(64), $3::bit(256), $4::bit(64), $5::bit(64), $6::bit(64))
+    ON CONFLICT (image_id) DO UPDATE SET
+        phash    = EXCLUDED.phash,
+        phash256 = EXCLUDED.phash256,
+        dhash    = EXCLUDED.dhash,
+        ahash    = EXCLUDED.ahash,
+        whash    = EXCLUDED.whash;
+    `
+
+	_, err := ex.ExecContext(
+		ctx, query,
+		imageID,
+		hashes[fingerprint.PHash].BitString(),
+		hashes[fingerprint.PHash256].BitString(),
+		hashes[fingerprint.DHash].BitString(),
+		hashes[fingerprint.AHash].BitString(),
+		hashes[fingerprint.WHash].BitString(),
+	)
+	return err
+}
+
+// GetImageHashes returns the stored hashes of an image, or nil if none.
+func (db *DB) GetImageHashes(
+	ctx context.Context,
+	imageID uuid.UUID,
+) (fingerprint.ImageHashes, error) {
+
+	query := `
+    SELECT phash::text, phash256::text, dhash::text, ahash::text, whash::text
+    FROM image_hashes
+    WHERE image_id = $1;
+    `
+
+	var cols [5]string
+	err := db.pool.QueryRowContext(ctx, query, imageID).Scan(
+		&cols[0], &cols[1], &cols[2], &cols[3], &cols[4],
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	hashes := make(fingerprint.ImageHashes, len(cols))
+	for i, kind := range fingerprint.HashKinds { // same order as the SELECT
+		h, err := fingerprint.ParseBitString(cols[i])
+		if err != nil {
+			return nil, fmt.Errorf("decode %s: %w", kind, err)
+		}
+		hashes[kind] = h
+	}
+	return hashes, nil
+}
+
+// FindByHash returns up to limit images whose hash of the given kind is
+// within maxDistance bits of h, closest first.
+func (db *DB) FindByHash(
+	ctx context.Context,
+	kind fingerprint.HashKind,
+	h fingerprint.Hash,
+	maxDistance int,
+	limit int,
+) ([]HashMatch, error) {
+
+	if err := checkHash(kind, h); err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf(`
+    SELECT image_id, distance
+    FROM (
+        SELECT image_id, bit_count(%[1]s # $1::bit(%[2]d)) AS distance
+        FROM image_hashes
+    ) d
+    WHERE distance <= $2
+    ORDER BY distance, image_id
+    LIMIT $3;
+    `, hashColumns[kind], kind.Bits())
+
+	rows, err := db.pool.QueryContext(ctx, query, h.BitString(), maxDistance, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []HashMatch
+	for rows.Next() {
+		var m HashMatch
+		if err := rows.Scan(&m.ImageID, &m.Distance); err != nil {
+			return nil, err
+		}
+		matches = append(matches, m)
+	}
+	return matches, rows.Err()
+}
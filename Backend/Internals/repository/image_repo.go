@@ -73,7 +73,7 @@
 
 // // MarkAsIndexed updates the Qdrant sync status after the fingerprint vector
 // // has been successfully stored in Qdrant.
-// // Call this immediately after a successful QdrantDB.StoreFingerprint.
+// // Call this immediately after a successful VectorStore.Store.
 // func (db *DB) MarkAsIndexed(ctx context.Context, id uuid.UUID, version string) error {
 // 	_, err := db.pool.ExecContext(ctx, `   -- ← ExecContext, not Exec
 // 		UPDATE image_metadata
@@ -119,7 +119,7 @@
 // }
 
 // // SoftDeleteImage marks an image as deleted without removing the row.
-// // Also call QdrantDB.DeleteFingerprint for the same UUID to remove the vector.
+// // Also call VectorStore.Delete for the same UUID to remove the vector.
 // func (db *DB) SoftDeleteImage(ctx context.Context, id uuid.UUID) error {
 // 	_, err := db.pool.ExecContext(ctx,     // ← ExecContext, not Exec
 // 		`UPDATE image_metadata SET is_deleted = TRUE WHERE id = $1`, id)
@@ -260,6 +260,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"strings"
 
@@ -290,9 +291,12 @@ func (db *DB) InsertImageMetadata(
         width_px,
         height_px,
         is_ai_generated,
+        owner,
+        tags,
+        derived_from,
         captured_at
     )
-    VALUES ($1,$2,$3,$4,$5,$6,$7)
+    VALUES ($1,$2,$3,$4,$5,$6,$7,COALESCE($8::text[], '{}'),$9,$10)
     RETURNING id, serial_id;
     `
 
@@ -307,6 +311,9 @@ func (db *DB) InsertImageMetadata(
 		m.WidthPx,
 		m.HeightPx,
 		m.IsAIGenerated,
+		m.Owner,
+		m.Tags,
+		m.DerivedFrom,
 		m.CapturedAt,
 	).Scan(&id, &serialID)
 
@@ -333,6 +340,9 @@ func (db *DB) GetImageMetadataBySerialID(
         width_px,
         height_px,
         is_ai_generated,
+        owner,
+        to_json(tags)::text,
+        derived_from,
         captured_at,
         created_at,
         updated_at
@@ -350,6 +360,9 @@ func (db *DB) GetImageMetadataBySerialID(
 		&m.WidthPx,
 		&m.HeightPx,
 		&m.IsAIGenerated,
+		&m.Owner,
+		(*textArray)(&m.Tags),
+		&m.DerivedFrom,
 		&m.CapturedAt,
 		&m.CreatedAt,
 		&m.UpdatedAt,
@@ -380,6 +393,9 @@ func (db *DB) GetImageMetadata(
         width_px,
         height_px,
         is_ai_generated,
+        owner,
+        to_json(tags)::text,
+        derived_from,
         captured_at,
         created_at,
         updated_at
@@ -397,6 +413,9 @@ func (db *DB) GetImageMetadata(
 		&m.WidthPx,
 		&m.HeightPx,
 		&m.IsAIGenerated,
+		&m.Owner,
+		(*textArray)(&m.Tags),
+		&m.DerivedFrom,
 		&m.CapturedAt,
 		&m.CreatedAt,
 		&m.UpdatedAt,
@@ -438,6 +457,9 @@ func (db *DB) GetImageMetadataBatch(
         width_px,
         height_px,
         is_ai_generated,
+        owner,
+        to_json(tags)::text,
+        derived_from,
         captured_at,
         created_at,
         updated_at
@@ -463,6 +485,9 @@ func (db *DB) GetImageMetadataBatch(
 			&m.WidthPx,
 			&m.HeightPx,
 			&m.IsAIGenerated,
+			&m.Owner,
+			(*textArray)(&m.Tags),
+			&m.DerivedFrom,
 			&m.CapturedAt,
 			&m.CreatedAt,
 			&m.UpdatedAt,
@@ -475,3 +500,32 @@ func (db *DB) GetImageMetadataBatch(
 
 	return result, rows.Err()
 }
+
+// textArray scans a TEXT[] column selected as to_json(col)::text, which
+// database/sql can't scan into a []string directly.
+type textArray []string
+
+func (a *textArray) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case string:
+		data = []byte(v)
+	case []byte:
+		data = v
+	case nil:
+		*a = []string{}
+		return nil
+	default:
+		return fmt.Errorf("cannot scan %T into a text array", src)
+	}
+
+	var out []string
+	if err := json.Unmarshal(data, &out); err != nil {
+		return fmt.Errorf("scan text array: %w", err)
+	}
+	if out == nil {
+		out = []string{}
+	}
+	*a = out
+	return nil
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/models"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/features"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
+	"github.com/google/uuid"
+)
+
+// ImageMetadataStore is the image_metadata access the services need.
+// *DB implements it on Postgres and *MemoryDB in process.
+//
+// Lookups that find nothing return (nil, nil); the batch lookup simply
+// leaves unknown IDs out of the map.
+type ImageMetadataStore interface {
+	InsertImageMetadata(ctx context.Context, m models.ImageMetadata) (uuid.UUID, int64, error)
+	GetImageMetadataBySerialID(ctx context.Context, serialID int64) (*models.ImageMetadata, error)
+	GetImageMetadata(ctx context.Context, id uuid.UUID) (*models.ImageMetadata, error)
+	GetImageMetadataBatch(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*models.ImageMetadata, error)
+}
+
+// HashStore keeps the perceptual hashes of each image and answers
+// Hamming-distance lookups without going through the vector store.
+type HashStore interface {
+	InsertImageHashes(ctx context.Context, imageID uuid.UUID, hashes fingerprint.ImageHashes) error
+	GetImageHashes(ctx context.Context, imageID uuid.UUID) (fingerprint.ImageHashes, error)
+	FindByHash(ctx context.Context, kind fingerprint.HashKind, h fingerprint.Hash, maxDistance, limit int) ([]HashMatch, error)
+}
+
+// FeatureStore keeps the local features of each image plus an index of
+// their LSH buckets, which narrows a crop query down to a few candidates.
+type FeatureStore interface {
+	InsertImageFeatures(ctx context.Context, imageID uuid.UUID, set *features.Set) error
+	GetImageFeaturesBatch(ctx context.Context, ids []uuid.UUID) (map[uuid.UUID]*features.Set, error)
+	FindFeatureCandidates(ctx context.Context, keys []int64, limit int) ([]FeatureCandidate, error)
+}
+
+// IndexStore tracks which rows have a fingerprint in the vector store and
+// at which version, so stale or missing vectors can be found and redone.
+type IndexStore interface {
+	MarkIndexed(ctx context.Context, id uuid.UUID, v fingerprint.Version) error
+	MarkIndexFailed(ctx context.Context, id uuid.UUID, reason string) error
+	ListPendingIndex(ctx context.Context, v fingerprint.Version, limit int) ([]uuid.UUID, error)
+	CountPendingIndex(ctx context.Context, v fingerprint.Version) (map[fingerprint.Version]int, error)
+	GetIndexStats(ctx context.Context, v fingerprint.Version) (IndexStats, error)
+}
+
+// OutboxStore writes image rows together with their lookups and pending
+// vector-store writes, and hands the latter out to the dispatcher until
+// they succeed.
+type OutboxStore interface {
+	ReserveSerialID(ctx context.Context) (int64, error)
+	InsertImageWithOutbox(ctx context.Context, m models.ImageMetadata, lookups ImageLookups, e OutboxEntry) (OutboxEntry, error)
+	ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]OutboxEntry, error)
+	CompleteOutbox(ctx context.Context, e OutboxEntry) error
+	FailOutbox(ctx context.Context, id int64, reason string, retryAt time.Time) error
+	ListIndexRows(ctx context.Context, afterSerial int64, limit int) ([]IndexRow, error)
+}
+
+// ClusterStore keeps the groups of near-identical images found by the
+// last clustering run.
+type ClusterStore interface {
+	ReplaceClusters(ctx context.Context, clusters [][]uuid.UUID) error
+	ListClusters(ctx context.Context, limit, offset int) ([]Cluster, int, error)
+}
+
+// Store is everything ImageService persists outside the vector store.
+type Store interface {
+	ImageMetadataStore
+	HashStore
+	FeatureStore
+	IndexStore
+	OutboxStore
+	ClusterStore
+}
+
+var (
+	_ Store = (*DB)(nil)
+	_ Store = (*MemoryDB)(nil)
+)
@@ -0,0 +1,652 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"math/rand"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/models"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/features"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
+	"github.com/google/uuid"
+	_ "github.com/jackc/pgx/v5/stdlib"
+)
+
+// The Postgres run needs a disposable database, e.g.
+//
+//	TEST_DATABASE_URL="host=localhost port=5432 user=... dbname=media_test sslmode=disable" go test ./internals/repository/
+//
+// schema.sql is applied before the suite runs; rows created by the tests
+// are deleted again afterwards.
+const testDSNEnv = "TEST_DATABASE_URL"
+
+type storeFactory struct {
+	name string
+	open func(t *testing.T) (Store, func(id uuid.UUID))
+}
+
+func stores() []storeFactory {
+	return []storeFactory{
+		{name: "memory", open: func(t *testing.T) (Store, func(uuid.UUID)) {
+			return NewMemoryDB(), func(uuid.UUID) {}
+		}},
+		{name: "postgres", open: openPostgres},
+	}
+}
+
+func openPostgres(t *testing.T) (Store, func(uuid.UUID)) {
+	dsn := os.Getenv(testDSNEnv)
+	if dsn == "" {
+		t.Skipf("%s not set", testDSNEnv)
+	}
+
+	pool, err := sql.Open("pgx", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pool.Close() })
+
+	schema, err := os.ReadFile("../../schema.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := pool.Exec(string(schema)); err != nil {
+		t.Fatalf("apply schema: %v", err)
+	}
+
+	cleanup := func(id uuid.UUID) {
+		t.Cleanup(func() {
+			pool.Exec(`DELETE FROM image_metadata WHERE id = $1`, id)
+		})
+	}
+	return NewDB(pool), cleanup
+}
+
+func strPtr(s string) *string { return &s }
+func intPtr(n int) *int       { return &n }
+
+func TestImageMetadataStore(t *testing.T) {
+	captured := time.Date(2024, 5, 17, 9, 30, 0, 123000, time.UTC)
+
+	tests := []struct {
+		name string
+		meta models.ImageMetadata
+	}{
+		{
+			name: "all fields",
+			meta: models.ImageMetadata{
+				Title:         strPtr("Harbour at dawn"),
+				Description:   strPtr("Long exposure"),
+				MimeType:      strPtr("image/png"),
+				WidthPx:       intPtr(4032),
+				HeightPx:      intPtr(3024),
+				IsAIGenerated: true,
+				Owner:         strPtr("acme"),
+				Tags:          []string{"harbour", "night, long exposure"},
+				CapturedAt:    &captured,
+			},
+		},
+		{
+			name: "nullable fields empty",
+			meta: models.ImageMetadata{},
+		},
+	}
+
+	for _, sf := range stores() {
+		t.Run(sf.name, func(t *testing.T) {
+			store, cleanup := sf.open(t)
+			ctx := context.Background()
+
+			var ids []uuid.UUID
+			var lastSerial int64
+
+			for _, tt := range tests {
+				t.Run(tt.name, func(t *testing.T) {
+					id, serial, err := store.InsertImageMetadata(ctx, tt.meta)
+					if err != nil {
+						t.Fatalf("InsertImageMetadata: %v", err)
+					}
+					cleanup(id)
+					if id == uuid.Nil {
+						t.Fatal("InsertImageMetadata returned a nil UUID")
+					}
+					if serial <= lastSerial {
+						t.Fatalf("serial_id %d not increasing (previous %d)", serial, lastSerial)
+					}
+					lastSerial = serial
+					ids = append(ids, id)
+
+					bySerial, err := store.GetImageMetadataBySerialID(ctx, serial)
+					if err != nil {
+						t.Fatalf("GetImageMetadataBySerialID: %v", err)
+					}
+					checkMetadata(t, bySerial, id, serial, tt.meta)
+
+					byID, err := store.GetImageMetadata(ctx, id)
+					if err != nil {
+						t.Fatalf("GetImageMetadata: %v", err)
+					}
+					checkMetadata(t, byID, id, serial, tt.meta)
+				})
+			}
+
+			t.Run("missing rows", func(t *testing.T) {
+				m, err := store.GetImageMetadataBySerialID(ctx, lastSerial+1_000_000)
+				if m != nil || err != nil {
+					t.Fatalf("GetImageMetadataBySerialID(unknown) = %v, %v; want nil, nil", m, err)
+				}
+				m, err = store.GetImageMetadata(ctx, uuid.New())
+				if m != nil || err != nil {
+					t.Fatalf("GetImageMetadata(unknown) = %v, %v; want nil, nil", m, err)
+				}
+			})
+
+			t.Run("batch", func(t *testing.T) {
+				unknown := uuid.New()
+				got, err := store.GetImageMetadataBatch(ctx, append([]uuid.UUID{unknown}, ids...))
+				if err != nil {
+					t.Fatalf("GetImageMetadataBatch: %v", err)
+				}
+				if len(got) != len(ids) {
+					t.Fatalf("batch returned %d rows, want %d", len(got), len(ids))
+				}
+				for _, id := range ids {
+					if got[id] == nil || got[id].ID != id {
+						t.Fatalf("batch missing %s", id)
+					}
+				}
+
+				empty, err := store.GetImageMetadataBatch(ctx, nil)
+				if err != nil || empty == nil || len(empty) != 0 {
+					t.Fatalf("GetImageMetadataBatch(nil) = %v, %v; want empty map", empty, err)
+				}
+			})
+
+			t.Run("derived from", func(t *testing.T) {
+				id, _, err := store.InsertImageMetadata(ctx, models.ImageMetadata{DerivedFrom: &ids[0]})
+				if err != nil {
+					t.Fatalf("InsertImageMetadata: %v", err)
+				}
+				cleanup(id)
+
+				got, err := store.GetImageMetadata(ctx, id)
+				if err != nil || got == nil || got.DerivedFrom == nil || *got.DerivedFrom != ids[0] {
+					t.Fatalf("GetImageMetadata = %+v, %v; want DerivedFrom %s", got, err, ids[0])
+				}
+				if first, _ := store.GetImageMetadata(ctx, ids[0]); first.DerivedFrom != nil {
+					t.Fatalf("DerivedFrom of the original = %s, want nil", first.DerivedFrom)
+				}
+			})
+		})
+	}
+}
+
+func checkMetadata(t *testing.T, got *models.ImageMetadata, id uuid.UUID, serial int64, want models.ImageMetadata) {
+	t.Helper()
+
+	if got == nil {
+		t.Fatal("row not found")
+	}
+	if got.ID != id || got.SerialID != serial {
+		t.Fatalf("got id %s serial %d, want %s %d", got.ID, got.SerialID, id, serial)
+	}
+	if !equalStr(got.Title, want.Title) || !equalStr(got.Description, want.Description) ||
+		!equalStr(got.MimeType, want.MimeType) || !equalStr(got.Owner, want.Owner) {
+		t.Fatalf("text fields differ: got %+v", got)
+	}
+	if !equalInt(got.WidthPx, want.WidthPx) || !equalInt(got.HeightPx, want.HeightPx) {
+		t.Fatalf("dimensions differ: got %+v", got)
+	}
+	if got.IsAIGenerated != want.IsAIGenerated {
+		t.Fatalf("IsAIGenerated = %v, want %v", got.IsAIGenerated, want.IsAIGenerated)
+	}
+	if got.Tags == nil || !slices.Equal(got.Tags, want.Tags) {
+		t.Fatalf("Tags = %#v, want %#v", got.Tags, want.Tags)
+	}
+	if (got.CapturedAt == nil) != (want.CapturedAt == nil) ||
+		(got.CapturedAt != nil && !got.CapturedAt.Equal(*want.CapturedAt)) {
+		t.Fatalf("CapturedAt = %v, want %v", got.CapturedAt, want.CapturedAt)
+	}
+	if got.CreatedAt.IsZero() || got.UpdatedAt.IsZero() {
+		t.Fatal("timestamps not set")
+	}
+}
+
+func equalStr(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func equalInt(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// testHashes builds a full set of hashes whose words are all w.
+func testHashes(w uint64) fingerprint.ImageHashes {
+	hashes := fingerprint.ImageHashes{}
+	for _, kind := range fingerprint.HashKinds {
+		h := make(fingerprint.Hash, kind.Bits()/64)
+		for i := range h {
+			h[i] = w
+		}
+		hashes[kind] = h
+	}
+	return hashes
+}
+
+func TestHashStore(t *testing.T) {
+	for _, sf := range stores() {
+		t.Run(sf.name, func(t *testing.T) {
+			store, cleanup := sf.open(t)
+			ctx := context.Background()
+
+			// Three images: the query itself, one 3 bits away, one far away.
+			words := []uint64{0xF0F0F0F0F0F0F0F0, 0xF0F0F0F0F0F0F0F7, 0x0F0F0F0F0F0F0F0F}
+			ids := make([]uuid.UUID, len(words))
+			for i, w := range words {
+				id, _, err := store.InsertImageMetadata(ctx, models.ImageMetadata{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				cleanup(id)
+				ids[i] = id
+				if err := store.InsertImageHashes(ctx, id, testHashes(w)); err != nil {
+					t.Fatalf("InsertImageHashes: %v", err)
+				}
+			}
+
+			got, err := store.GetImageHashes(ctx, ids[1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, kind := range fingerprint.HashKinds {
+				if got[kind].Distance(testHashes(words[1])[kind]) != 0 {
+					t.Fatalf("GetImageHashes %s = %v", kind, got[kind])
+				}
+			}
+			if none, err := store.GetImageHashes(ctx, uuid.New()); none != nil || err != nil {
+				t.Fatalf("GetImageHashes(unknown) = %v, %v; want nil, nil", none, err)
+			}
+
+			query := testHashes(words[0])
+			tests := []struct {
+				kind        fingerprint.HashKind
+				maxDistance int
+				limit       int
+				want        []HashMatch
+			}{
+				{fingerprint.PHash, 0, 10, []HashMatch{{ids[0], 0}}},
+				{fingerprint.DHash, 5, 10, []HashMatch{{ids[0], 0}, {ids[1], 3}}},
+				{fingerprint.DHash, 5, 1, []HashMatch{{ids[0], 0}}},
+				{fingerprint.PHash256, 12, 10, []HashMatch{{ids[0], 0}, {ids[1], 12}}},
+			}
+
+			for _, tt := range tests {
+				matches, err := store.FindByHash(ctx, tt.kind, query[tt.kind], tt.maxDistance, tt.limit)
+				if err != nil {
+					t.Fatalf("FindByHash(%s): %v", tt.kind, err)
+				}
+				// A shared Postgres may hold other rows; only ours matter.
+				var ours []HashMatch
+				for _, m := range matches {
+					for _, id := range ids {
+						if m.ImageID == id {
+							ours = append(ours, m)
+						}
+					}
+				}
+				if len(ours) != len(tt.want) {
+					t.Fatalf("FindByHash(%s, %d) = %v, want %v", tt.kind, tt.maxDistance, ours, tt.want)
+				}
+				for i := range ours {
+					if ours[i] != tt.want[i] {
+						t.Fatalf("FindByHash(%s, %d) = %v, want %v", tt.kind, tt.maxDistance, ours, tt.want)
+					}
+				}
+			}
+
+			if err := store.InsertImageHashes(ctx, ids[0], fingerprint.ImageHashes{}); err == nil {
+				t.Fatal("InsertImageHashes accepted an incomplete set")
+			}
+		})
+	}
+}
+
+// testFeatures builds a feature set of n keypoints with random descriptors.
+func testFeatures(rng *rand.Rand, n int) *features.Set {
+	set := &features.Set{Width: 640, Height: 480}
+	for i := 0; i < n; i++ {
+		kp := features.Keypoint{X: float32(rng.Intn(640)), Y: float32(rng.Intn(480)), Size: 31}
+		for w := range kp.Descriptor {
+			kp.Descriptor[w] = rng.Uint64()
+		}
+		set.Keypoints = append(set.Keypoints, kp)
+	}
+	return set
+}
+
+func TestFeatureStore(t *testing.T) {
+	for _, sf := range stores() {
+		t.Run(sf.name, func(t *testing.T) {
+			store, cleanup := sf.open(t)
+			ctx := context.Background()
+			rng := rand.New(rand.NewSource(7))
+
+			sets := []*features.Set{testFeatures(rng, 200), testFeatures(rng, 200)}
+			ids := make([]uuid.UUID, len(sets))
+			for i, set := range sets {
+				id, _, err := store.InsertImageMetadata(ctx, models.ImageMetadata{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				cleanup(id)
+				ids[i] = id
+				if err := store.InsertImageFeatures(ctx, id, set); err != nil {
+					t.Fatalf("InsertImageFeatures: %v", err)
+				}
+			}
+
+			got, err := store.GetImageFeaturesBatch(ctx, append([]uuid.UUID{uuid.New()}, ids...))
+			if err != nil {
+				t.Fatalf("GetImageFeaturesBatch: %v", err)
+			}
+			if len(got) != len(ids) {
+				t.Fatalf("batch returned %d sets, want %d", len(got), len(ids))
+			}
+			for i, id := range ids {
+				if got[id].Width != 640 || len(got[id].Keypoints) != 200 ||
+					got[id].Keypoints[17] != sets[i].Keypoints[17] {
+					t.Fatalf("features of image %d differ after storing", i)
+				}
+			}
+
+			// A quarter of the second image's keypoints, as a crop would have.
+			query := &features.Set{Keypoints: sets[1].Keypoints[:50]}
+			candidates, err := store.FindFeatureCandidates(ctx, query.BucketKeys(), 10)
+			if err != nil {
+				t.Fatalf("FindFeatureCandidates: %v", err)
+			}
+			if len(candidates) == 0 || candidates[0].ImageID != ids[1] || candidates[0].Votes < 50 {
+				t.Fatalf("FindFeatureCandidates = %v, want %s first", candidates, ids[1])
+			}
+
+			// Replacing the features drops the old buckets.
+			if err := store.InsertImageFeatures(ctx, ids[1], testFeatures(rng, 10)); err != nil {
+				t.Fatal(err)
+			}
+			candidates, err = store.FindFeatureCandidates(ctx, query.BucketKeys(), 10)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, c := range candidates {
+				if c.ImageID == ids[1] && c.Votes > 5 {
+					t.Fatalf("stale buckets after replace: %v", c)
+				}
+			}
+		})
+	}
+}
+
+func TestIndexStore(t *testing.T) {
+	for _, sf := range stores() {
+		t.Run(sf.name, func(t *testing.T) {
+			store, cleanup := sf.open(t)
+			ctx := context.Background()
+
+			before, err := store.GetIndexStats(ctx, fingerprint.V3)
+			if err != nil {
+				t.Fatalf("GetIndexStats: %v", err)
+			}
+			countsBefore, err := store.CountPendingIndex(ctx, fingerprint.V3)
+			if err != nil {
+				t.Fatalf("CountPendingIndex: %v", err)
+			}
+
+			// current, stale, failed, unindexed
+			ids := make([]uuid.UUID, 4)
+			for i := range ids {
+				id, _, err := store.InsertImageMetadata(ctx, models.ImageMetadata{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				cleanup(id)
+				ids[i] = id
+			}
+			if err := store.MarkIndexed(ctx, ids[0], fingerprint.V3); err != nil {
+				t.Fatalf("MarkIndexed: %v", err)
+			}
+			if err := store.MarkIndexed(ctx, ids[1], fingerprint.V1); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.MarkIndexFailed(ctx, ids[2], "no vector"); err != nil {
+				t.Fatalf("MarkIndexFailed: %v", err)
+			}
+
+			pending, err := store.ListPendingIndex(ctx, fingerprint.V3, 10000)
+			if err != nil {
+				t.Fatalf("ListPendingIndex: %v", err)
+			}
+			isPending := make(map[uuid.UUID]bool)
+			for _, id := range pending {
+				isPending[id] = true
+			}
+			for i, want := range []bool{false, true, false, true} {
+				if isPending[ids[i]] != want {
+					t.Errorf("row %d pending = %v, want %v", i, !want, want)
+				}
+			}
+
+			// The stale row counts under its version, the unindexed one under 0.
+			counts, err := store.CountPendingIndex(ctx, fingerprint.V3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if counts[fingerprint.V1]-countsBefore[fingerprint.V1] != 1 || counts[0]-countsBefore[0] != 1 || counts[fingerprint.V3] != 0 {
+				t.Fatalf("CountPendingIndex = %v (before %v)", counts, countsBefore)
+			}
+
+			after, err := store.GetIndexStats(ctx, fingerprint.V3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			delta := IndexStats{
+				Total:     after.Total - before.Total,
+				Current:   after.Current - before.Current,
+				Stale:     after.Stale - before.Stale,
+				Unindexed: after.Unindexed - before.Unindexed,
+				Failed:    after.Failed - before.Failed,
+			}
+			if delta != (IndexStats{Total: 4, Current: 1, Stale: 1, Unindexed: 1, Failed: 1}) {
+				t.Fatalf("stats changed by %+v", delta)
+			}
+
+			// Indexing again clears the failure.
+			if err := store.MarkIndexed(ctx, ids[2], fingerprint.V3); err != nil {
+				t.Fatal(err)
+			}
+			again, err := store.GetIndexStats(ctx, fingerprint.V3)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again.Failed != before.Failed || again.Current != before.Current+2 {
+				t.Fatalf("after re-indexing the failed row: %+v (before %+v)", again, before)
+			}
+		})
+	}
+}
+
+func TestOutboxStore(t *testing.T) {
+	for _, sf := range stores() {
+		t.Run(sf.name, func(t *testing.T) {
+			store, cleanup := sf.open(t)
+			ctx := context.Background()
+
+			serial, err := store.ReserveSerialID(ctx)
+			if err != nil {
+				t.Fatalf("ReserveSerialID: %v", err)
+			}
+			set := testFeatures(rand.New(rand.NewSource(4)), 10)
+			entry, err := store.InsertImageWithOutbox(ctx,
+				models.ImageMetadata{SerialID: serial, Title: strPtr("outbox")},
+				ImageLookups{Hashes: testHashes(1), Features: set},
+				OutboxEntry{
+					Version:   fingerprint.V3,
+					Structure: fingerprint.Vector{0.5, -1.25, 3},
+					Colour:    fingerprint.Vector{1, 2},
+				})
+			if err != nil {
+				t.Fatalf("InsertImageWithOutbox: %v", err)
+			}
+			cleanup(entry.ImageID)
+
+			m, err := store.GetImageMetadataBySerialID(ctx, serial)
+			if err != nil || m == nil || m.ID != entry.ImageID || !equalStr(m.Title, strPtr("outbox")) {
+				t.Fatalf("row under reserved serial = %+v, %v", m, err)
+			}
+			if h, err := store.GetImageHashes(ctx, entry.ImageID); err != nil || h[fingerprint.PHash].Distance(testHashes(1)[fingerprint.PHash]) != 0 {
+				t.Fatalf("hashes stored with the row = %v, %v", h, err)
+			}
+			if f, err := store.GetImageFeaturesBatch(ctx, []uuid.UUID{entry.ImageID}); err != nil || f[entry.ImageID] == nil || len(f[entry.ImageID].Keypoints) != len(set.Keypoints) {
+				t.Fatalf("features stored with the row = %v, %v", f, err)
+			}
+
+			// A row whose hashes cannot be stored is not inserted at all.
+			bad, err := store.ReserveSerialID(ctx)
+			if err != nil {
+				t.Fatalf("ReserveSerialID: %v", err)
+			}
+			if _, err := store.InsertImageWithOutbox(ctx,
+				models.ImageMetadata{SerialID: bad},
+				ImageLookups{Hashes: fingerprint.ImageHashes{}, Features: set},
+				OutboxEntry{Version: fingerprint.V3, Structure: fingerprint.Vector{1}},
+			); err == nil {
+				t.Fatal("InsertImageWithOutbox accepted an incomplete hash set")
+			}
+			if m, err := store.GetImageMetadataBySerialID(ctx, bad); err != nil || m != nil {
+				t.Fatalf("row without hashes = %+v, %v", m, err)
+			}
+
+			// claim returns the entry once, then leases it
+			claim := func() *OutboxEntry {
+				t.Helper()
+				entries, err := store.ClaimOutbox(ctx, 1000, time.Hour)
+				if err != nil {
+					t.Fatalf("ClaimOutbox: %v", err)
+				}
+				for i := range entries {
+					if entries[i].ID == entry.ID {
+						return &entries[i]
+					}
+				}
+				return nil
+			}
+
+			got := claim()
+			if got == nil {
+				t.Fatal("new entry not claimed")
+			}
+			if got.ImageID != entry.ImageID || got.Version != fingerprint.V3 ||
+				len(got.Structure) != 3 || got.Structure[1] != -1.25 || len(got.Colour) != 2 {
+				t.Fatalf("claimed entry = %+v", got)
+			}
+			if claim() != nil {
+				t.Fatal("leased entry claimed again")
+			}
+
+			if err := store.FailOutbox(ctx, entry.ID, "vector store down", time.Now().Add(-time.Second)); err != nil {
+				t.Fatalf("FailOutbox: %v", err)
+			}
+			got = claim()
+			if got == nil || got.Attempts != 1 || got.LastError != "vector store down" {
+				t.Fatalf("entry after a failure = %+v", got)
+			}
+
+			rows, err := store.ListIndexRows(ctx, serial-1, 1)
+			if err != nil || len(rows) != 1 || rows[0].ID != entry.ImageID || !rows[0].Pending || rows[0].Indexed {
+				t.Fatalf("ListIndexRows while pending = %+v, %v", rows, err)
+			}
+			pending, err := store.ListPendingIndex(ctx, fingerprint.V3, 10000)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, id := range pending {
+				if id == entry.ImageID {
+					t.Fatal("row with an outbox entry handed to the re-index worker")
+				}
+			}
+
+			if err := store.CompleteOutbox(ctx, entry); err != nil {
+				t.Fatalf("CompleteOutbox: %v", err)
+			}
+			rows, err = store.ListIndexRows(ctx, serial-1, 1)
+			if err != nil || len(rows) != 1 || rows[0].Pending || !rows[0].Indexed || rows[0].Version != fingerprint.V3 {
+				t.Fatalf("ListIndexRows after delivery = %+v, %v", rows, err)
+			}
+		})
+	}
+}
+
+func TestClusterStore(t *testing.T) {
+	for _, sf := range stores() {
+		t.Run(sf.name, func(t *testing.T) {
+			store, cleanup := sf.open(t)
+			ctx := context.Background()
+
+			ids := make([]uuid.UUID, 6)
+			for i := range ids {
+				id, _, err := store.InsertImageMetadata(ctx, models.ImageMetadata{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				cleanup(id)
+				ids[i] = id
+			}
+
+			// Members come back oldest first whatever order they were
+			// saved in; a cluster left with one known row is not listed.
+			err := store.ReplaceClusters(ctx, [][]uuid.UUID{
+				{ids[2], ids[0], ids[1]},
+				{ids[4], ids[3]},
+				{ids[5], uuid.New()},
+			})
+			if err != nil {
+				t.Fatalf("ReplaceClusters: %v", err)
+			}
+
+			clusters, total, err := store.ListClusters(ctx, 10, 0)
+			if err != nil {
+				t.Fatalf("ListClusters: %v", err)
+			}
+			if total != 2 || len(clusters) != 2 {
+				t.Fatalf("ListClusters = %+v, total %d; want 2 clusters", clusters, total)
+			}
+			if clusters[0].ID != 1 || !slices.Equal(clusters[0].Members, ids[:3]) ||
+				clusters[1].ID != 2 || !slices.Equal(clusters[1].Members, ids[3:5]) {
+				t.Fatalf("ListClusters = %+v", clusters)
+			}
+
+			page, total, err := store.ListClusters(ctx, 1, 1)
+			if err != nil || total != 2 || len(page) != 1 || page[0].ID != 2 {
+				t.Fatalf("second page = %+v, total %d, %v", page, total, err)
+			}
+
+			// A new run replaces the old clusters.
+			if err := store.ReplaceClusters(ctx, [][]uuid.UUID{{ids[1], ids[5]}}); err != nil {
+				t.Fatalf("ReplaceClusters: %v", err)
+			}
+			clusters, total, err = store.ListClusters(ctx, 10, 0)
+			if err != nil || total != 1 || len(clusters) != 1 || !slices.Equal(clusters[0].Members, []uuid.UUID{ids[1], ids[5]}) {
+				t.Fatalf("after second run = %+v, total %d, %v", clusters, total, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
+	"github.com/google/uuid"
+)
+
+// IndexStats counts image_metadata rows by vector-index state relative to
+// one fingerprint version.
+type IndexStats struct {
+	Total     int
+	Current   int // indexed at the version asked about
+	Stale     int // indexed at another (or an unrecorded) version
+	Unindexed int // never indexed, still to be tried
+	Pending   int // never indexed, waiting in the outbox
+	Failed    int // given up on; see index_error
+}
+
+// MarkIndexed records that the row's fingerprint is in the vector store
+// at version v and clears any earlier failure.
+func (db *DB) MarkIndexed(ctx context.Context, id uuid.UUID, v fingerprint.Version) error {
+	query := `
+    UPDATE image_metadata
+    SET is_indexed    = TRUE,
+        index_version = $2,
+        indexed_at    = NOW(),
+        index_error   = NULL
+    WHERE id = $1;
+    `
+	_, err := db.pool.ExecContext(ctx, query, id, int(v))
+	return err
+}
+
+// MarkIndexFailed takes the row out of the re-index queue with a reason.
+func (db *DB) MarkIndexFailed(ctx context.Context, id uuid.UUID, reason string) error {
+	query := `
+    UPDATE image_metadata
+    SET is_indexed  = FALSE,
+        index_error = $2
+    WHERE id = $1;
+    `
+	_, err := db.pool.ExecContext(ctx, query, id, reason)
+	return err
+}
+
+// ListPendingIndex returns up to limit rows that are unindexed or indexed
+// at a version other than v, oldest first. Failed rows and rows still
+// waiting in the outbox are skipped.
+func (db *DB) ListPendingIndex(ctx context.Context, v fingerprint.Version, limit int) ([]uuid.UUID, error) {
+	query := `
+    SELECT id
+    FROM image_metadata
+    WHERE index_error IS NULL
+      AND (NOT is_indexed OR index_version IS DISTINCT FROM $1)
+      AND NOT EXISTS (SELECT 1 FROM vector_outbox o WHERE o.image_id = image_metadata.id)
+    ORDER BY serial_id
+    LIMIT $2;
+    `
+
+	rows, err := db.pool.QueryContext(ctx, query, int(v), limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// CountPendingIndex counts the rows ListPendingIndex would return for v
+// by the version they are recorded at; unindexed or unrecorded rows count
+// under 0.
+func (db *DB) CountPendingIndex(ctx context.Context, v fingerprint.Version) (map[fingerprint.Version]int, error) {
+	query := `
+    SELECT CASE WHEN is_indexed THEN COALESCE(index_version, 0) ELSE 0 END, count(*)
+    FROM image_metadata
+    WHERE index_error IS NULL
+      AND (NOT is_indexed OR index_version IS DISTINCT FROM $1)
+      AND NOT EXISTS (SELECT 1 FROM vector_outbox o WHERE o.image_id = image_metadata.id)
+    GROUP BY 1;
+    `
+
+	rows, err := db.pool.QueryContext(ctx, query, int(v))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make(map[fingerprint.Version]int)
+	for rows.Next() {
+		var version, n int
+		if err := rows.Scan(&version, &n); err != nil {
+			return nil, err
+		}
+		counts[fingerprint.Version(version)] = n
+	}
+	return counts, rows.Err()
+}
+
+// GetIndexStats counts rows by index state relative to version v.
+func (db *DB) GetIndexStats(ctx context.Context, v fingerprint.Version) (IndexStats, error) {
+	query := `
+    SELECT
+        count(*),
+        count(*) FILTER (WHERE is_indexed AND index_version = $1),
+        count(*) FILTER (WHERE is_indexed AND index_version IS DISTINCT FROM $1),
+        count(*) FILTER (WHERE NOT is_indexed AND index_error IS NULL AND NOT pending),
+        count(*) FILTER (WHERE NOT is_indexed AND index_error IS NULL AND pending),
+        count(*) FILTER (WHERE NOT is_indexed AND index_error IS NOT NULL)
+    FROM (
+        SELECT m.is_indexed, m.index_version, m.index_error,
+               EXISTS (SELECT 1 FROM vector_outbox o WHERE o.image_id = m.id) AS pending
+        FROM image_metadata m
+    ) rows;
+    `
+
+	var s IndexStats
+	err := db.pool.QueryRowContext(ctx, query, int(v)).Scan(
+		&s.Total, &s.Current, &s.Stale, &s.Unindexed, &s.Pending, &s.Failed,
+	)
+	return s, err
+}
@@ -0,0 +1,582 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/models"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/features"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
+	"github.com/google/uuid"
+)
+
+// MemoryDB is an in-process ImageMetadataStore for tests and local runs
+// without Postgres. It hands out serial IDs the way BIGSERIAL does:
+// starting at 1 and never reused.
+type MemoryDB struct {
+	mu       sync.RWMutex
+	lastID   int64
+	byID     map[uuid.UUID]*models.ImageMetadata
+	bySerial map[int64]uuid.UUID
+	hashes   map[uuid.UUID]fingerprint.ImageHashes
+	features map[uuid.UUID]*features.Set
+	buckets  map[int64]map[uuid.UUID]bool
+	index    map[uuid.UUID]indexState
+
+	lastOutbox int64
+	outbox     map[int64]*outboxRow
+
+	clusters map[uuid.UUID]int64 // image → cluster ID
+}
+
+// outboxRow is an outbox entry plus when it is next due.
+type outboxRow struct {
+	entry OutboxEntry
+	due   time.Time
+}
+
+// indexState mirrors the index columns of image_metadata.
+type indexState struct {
+	indexed bool
+	version fingerprint.Version
+	err     string
+}
+
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{
+		byID:     make(map[uuid.UUID]*models.ImageMetadata),
+		bySerial: make(map[int64]uuid.UUID),
+		hashes:   make(map[uuid.UUID]fingerprint.ImageHashes),
+		features: make(map[uuid.UUID]*features.Set),
+		buckets:  make(map[int64]map[uuid.UUID]bool),
+		index:    make(map[uuid.UUID]indexState),
+		outbox:   make(map[int64]*outboxRow),
+		clusters: make(map[uuid.UUID]int64),
+	}
+}
+
+// InsertImageMetadata stores a copy of m and returns its new UUID and
+// serial_id. Like the Postgres version it ignores any ID, SerialID and
+// timestamps already set on m.
+func (db *MemoryDB) InsertImageMetadata(
+	ctx context.Context,
+	m models.ImageMetadata,
+) (uuid.UUID, int64, error) {
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.lastID++
+	now := time.Now().UTC()
+
+	m.ID = uuid.New()
+	m.SerialID = db.lastID
+	m.CreatedAt = now
+	m.UpdatedAt = now
+	m.Tags = append([]string{}, m.Tags...)
+
+	db.byID[m.ID] = &m
+	db.bySerial[m.SerialID] = m.ID
+
+	return m.ID, m.SerialID, nil
+}
+
+// GetImageMetadataBySerialID looks up a row using the watermark-embedded serial_id.
+func (db *MemoryDB) GetImageMetadataBySerialID(
+	ctx context.Context,
+	serialID int64,
+) (*models.ImageMetadata, error) {
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	id, ok := db.bySerial[serialID]
+	if !ok {
+		return nil, nil
+	}
+	return db.copyOf(id), nil
+}
+
+// GetImageMetadata fetches by UUID.
+func (db *MemoryDB) GetImageMetadata(
+	ctx context.Context,
+	id uuid.UUID,
+) (*models.ImageMetadata, error) {
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	return db.copyOf(id), nil
+}
+
+// GetImageMetadataBatch fetches multiple rows by UUID slice.
+func (db *MemoryDB) GetImageMetadataBatch(
+	ctx context.Context,
+	ids []uuid.UUID,
+) (map[uuid.UUID]*models.ImageMetadata, error) {
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	result := make(map[uuid.UUID]*models.ImageMetadata)
+	for _, id := range ids {
+		if m := db.copyOf(id); m != nil {
+			result[id] = m
+		}
+	}
+	return result, nil
+}
+
+// copyOf returns a copy of the stored row so callers can't modify it in
+// place. The pointer fields are never written after insert, so sharing
+// them is safe; the tags slice is copied. Callers hold db.mu.
+func (db *MemoryDB) copyOf(id uuid.UUID) *models.ImageMetadata {
+	stored, ok := db.byID[id]
+	if !ok {
+		return nil
+	}
+	m := *stored
+	m.Tags = append([]string{}, stored.Tags...)
+	return &m
+}
+
+// InsertImageHashes stores (or replaces) the perceptual hashes of an image.
+func (db *MemoryDB) InsertImageHashes(
+	ctx context.Context,
+	imageID uuid.UUID,
+	hashes fingerprint.ImageHashes,
+) error {
+
+	if err := checkHashes(hashes); err != nil {
+		return err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.putHashes(imageID, hashes)
+	return nil
+}
+
+// putHashes stores a copy of hashes; the caller holds db.mu.
+func (db *MemoryDB) putHashes(imageID uuid.UUID, hashes fingerprint.ImageHashes) {
+	stored := make(fingerprint.ImageHashes, len(hashes))
+	for kind, h := range hashes {
+		stored[kind] = append(fingerprint.Hash(nil), h...)
+	}
+	db.hashes[imageID] = stored
+}
+
+// GetImageHashes returns the stored hashes of an image, or nil if none.
+func (db *MemoryDB) GetImageHashes(
+	ctx context.Context,
+	imageID uuid.UUID,
+) (fingerprint.ImageHashes, error) {
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	stored, ok := db.hashes[imageID]
+	if !ok {
+		return nil, nil
+	}
+	hashes := make(fingerprint.ImageHashes, len(stored))
+	for kind, h := range stored {
+		hashes[kind] = append(fingerprint.Hash(nil), h...)
+	}
+	return hashes, nil
+}
+
+// FindByHash scans every stored hash of the given kind, closest first.
+func (db *MemoryDB) FindByHash(
+	ctx context.Context,
+	kind fingerprint.HashKind,
+	h fingerprint.Hash,
+	maxDistance int,
+	limit int,
+) ([]HashMatch, error) {
+
+	if err := checkHash(kind, h); err != nil {
+		return nil, err
+	}
+
+	db.mu.RLock()
+	var matches []HashMatch
+	for id, hashes := range db.hashes {
+		if d := h.Distance(hashes[kind]); d >= 0 && d <= maxDistance {
+			matches = append(matches, HashMatch{ImageID: id, Distance: d})
+		}
+	}
+	db.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Distance != matches[j].Distance {
+			return matches[i].Distance < matches[j].Distance
+		}
+		return matches[i].ImageID.String() < matches[j].ImageID.String()
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches, nil
+}
+
+// InsertImageFeatures stores (or replaces) the local features of an image
+// and indexes its buckets.
+func (db *MemoryDB) InsertImageFeatures(
+	ctx context.Context,
+	imageID uuid.UUID,
+	set *features.Set,
+) error {
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.putFeatures(imageID, set)
+	return nil
+}
+
+// putFeatures stores a copy of set and indexes its buckets; the caller
+// holds db.mu.
+func (db *MemoryDB) putFeatures(imageID uuid.UUID, set *features.Set) {
+	stored := &features.Set{
+		Width:     set.Width,
+		Height:    set.Height,
+		Keypoints: append([]features.Keypoint(nil), set.Keypoints...),
+	}
+
+	if old, ok := db.features[imageID]; ok {
+		for _, k := range old.BucketKeys() {
+			delete(db.buckets[k], imageID)
+		}
+	}
+	db.features[imageID] = stored
+	for _, k := range stored.BucketKeys() {
+		if db.buckets[k] == nil {
+			db.buckets[k] = make(map[uuid.UUID]bool)
+		}
+		db.buckets[k][imageID] = true
+	}
+}
+
+// GetImageFeaturesBatch returns copies of the stored feature sets.
+func (db *MemoryDB) GetImageFeaturesBatch(
+	ctx context.Context,
+	ids []uuid.UUID,
+) (map[uuid.UUID]*features.Set, error) {
+
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	result := make(map[uuid.UUID]*features.Set)
+	for _, id := range ids {
+		if set, ok := db.features[id]; ok {
+			result[id] = &features.Set{
+				Width:     set.Width,
+				Height:    set.Height,
+				Keypoints: append([]features.Keypoint(nil), set.Keypoints...),
+			}
+		}
+	}
+	return result, nil
+}
+
+// FindFeatureCandidates counts the distinct buckets each image shares
+// with keys, most votes first.
+func (db *MemoryDB) FindFeatureCandidates(
+	ctx context.Context,
+	keys []int64,
+	limit int,
+) ([]FeatureCandidate, error) {
+
+	db.mu.RLock()
+	votes := make(map[uuid.UUID]int)
+	for _, k := range distinctKeys(keys) {
+		for id := range db.buckets[k] {
+			votes[id]++
+		}
+	}
+	db.mu.RUnlock()
+
+	candidates := make([]FeatureCandidate, 0, len(votes))
+	for id, v := range votes {
+		candidates = append(candidates, FeatureCandidate{ImageID: id, Votes: v})
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].Votes != candidates[j].Votes {
+			return candidates[i].Votes > candidates[j].Votes
+		}
+		return candidates[i].ImageID.String() < candidates[j].ImageID.String()
+	})
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+	return candidates, nil
+}
+
+// MarkIndexed records the row as indexed at version v.
+func (db *MemoryDB) MarkIndexed(ctx context.Context, id uuid.UUID, v fingerprint.Version) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, ok := db.byID[id]; ok {
+		db.index[id] = indexState{indexed: true, version: v}
+	}
+	return nil
+}
+
+// MarkIndexFailed takes the row out of the re-index queue.
+func (db *MemoryDB) MarkIndexFailed(ctx context.Context, id uuid.UUID, reason string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if _, ok := db.byID[id]; ok {
+		db.index[id] = indexState{version: db.index[id].version, err: reason}
+	}
+	return nil
+}
+
+// ListPendingIndex returns unindexed or stale rows in serial order.
+func (db *MemoryDB) ListPendingIndex(ctx context.Context, v fingerprint.Version, limit int) ([]uuid.UUID, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var ids []uuid.UUID
+	for serial := int64(1); serial <= db.lastID && len(ids) < limit; serial++ {
+		id, ok := db.bySerial[serial]
+		if !ok {
+			continue
+		}
+		st := db.index[id]
+		if st.err == "" && (!st.indexed || st.version != v) && !db.pendingLocked(id) {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// CountPendingIndex counts the rows ListPendingIndex would return by their
+// recorded version, 0 for unindexed rows.
+func (db *MemoryDB) CountPendingIndex(ctx context.Context, v fingerprint.Version) (map[fingerprint.Version]int, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	counts := make(map[fingerprint.Version]int)
+	for id := range db.byID {
+		st := db.index[id]
+		if st.err != "" || (st.indexed && st.version == v) || db.pendingLocked(id) {
+			continue
+		}
+		var recorded fingerprint.Version
+		if st.indexed {
+			recorded = st.version
+		}
+		counts[recorded]++
+	}
+	return counts, nil
+}
+
+// GetIndexStats counts rows by index state relative to version v.
+func (db *MemoryDB) GetIndexStats(ctx context.Context, v fingerprint.Version) (IndexStats, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	s := IndexStats{Total: len(db.byID)}
+	for id := range db.byID {
+		st := db.index[id]
+		switch {
+		case st.indexed && st.version == v:
+			s.Current++
+		case st.indexed:
+			s.Stale++
+		case st.err == "" && db.pendingLocked(id):
+			s.Pending++
+		case st.err == "":
+			s.Unindexed++
+		default:
+			s.Failed++
+		}
+	}
+	return s, nil
+}
+
+// ReserveSerialID takes the next serial_id without inserting a row.
+func (db *MemoryDB) ReserveSerialID(ctx context.Context) (int64, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.lastID++
+	return db.lastID, nil
+}
+
+// InsertImageWithOutbox stores m under its reserved serial_id together
+// with its lookups and the outbox entry e.
+func (db *MemoryDB) InsertImageWithOutbox(
+	ctx context.Context,
+	m models.ImageMetadata,
+	lookups ImageLookups,
+	e OutboxEntry,
+) (OutboxEntry, error) {
+
+	if err := checkHashes(lookups.Hashes); err != nil {
+		return e, err
+	}
+
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if m.SerialID <= 0 || m.SerialID > db.lastID {
+		return e, fmt.Errorf("serial_id %d was not reserved", m.SerialID)
+	}
+	if _, taken := db.bySerial[m.SerialID]; taken {
+		return e, fmt.Errorf("serial_id %d already in use", m.SerialID)
+	}
+
+	now := time.Now().UTC()
+	m.ID = uuid.New()
+	m.CreatedAt = now
+	m.UpdatedAt = now
+	m.Tags = append([]string{}, m.Tags...)
+
+	db.byID[m.ID] = &m
+	db.bySerial[m.SerialID] = m.ID
+	db.putHashes(m.ID, lookups.Hashes)
+	db.putFeatures(m.ID, lookups.Features)
+
+	db.lastOutbox++
+	e.ID = db.lastOutbox
+	e.ImageID = m.ID
+	e.Attempts = 0
+	e.LastError = ""
+	db.outbox[e.ID] = &outboxRow{entry: e, due: now}
+
+	return e, nil
+}
+
+// ClaimOutbox returns due entries, oldest first, and leases them.
+func (db *MemoryDB) ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]OutboxEntry, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	now := time.Now()
+	var entries []OutboxEntry
+	for id := int64(1); id <= db.lastOutbox && len(entries) < limit; id++ {
+		row, ok := db.outbox[id]
+		if !ok || row.due.After(now) {
+			continue
+		}
+		row.due = now.Add(lease)
+		entries = append(entries, row.entry)
+	}
+	return entries, nil
+}
+
+// CompleteOutbox removes the entry and marks its row indexed.
+func (db *MemoryDB) CompleteOutbox(ctx context.Context, e OutboxEntry) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	delete(db.outbox, e.ID)
+	if _, ok := db.byID[e.ImageID]; ok {
+		db.index[e.ImageID] = indexState{indexed: true, version: e.Version}
+	}
+	return nil
+}
+
+// FailOutbox records a failed attempt and when to try again.
+func (db *MemoryDB) FailOutbox(ctx context.Context, id int64, reason string, retryAt time.Time) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	if row, ok := db.outbox[id]; ok {
+		row.entry.Attempts++
+		row.entry.LastError = reason
+		row.due = retryAt
+	}
+	return nil
+}
+
+// ListIndexRows pages through the rows in serial order.
+func (db *MemoryDB) ListIndexRows(ctx context.Context, afterSerial int64, limit int) ([]IndexRow, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	var out []IndexRow
+	for serial := afterSerial + 1; serial <= db.lastID && len(out) < limit; serial++ {
+		id, ok := db.bySerial[serial]
+		if !ok {
+			continue
+		}
+		st := db.index[id]
+		out = append(out, IndexRow{
+			SerialID: serial,
+			ID:       id,
+			Indexed:  st.indexed,
+			Version:  st.version,
+			Failed:   st.err != "",
+			Pending:  db.pendingLocked(id),
+		})
+	}
+	return out, nil
+}
+
+// pendingLocked reports whether id has an outbox entry. Callers hold db.mu.
+func (db *MemoryDB) pendingLocked(id uuid.UUID) bool {
+	for _, row := range db.outbox {
+		if row.entry.ImageID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// ReplaceClusters stores a clustering run in place of the previous one.
+func (db *MemoryDB) ReplaceClusters(ctx context.Context, clusters [][]uuid.UUID) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.clusters = make(map[uuid.UUID]int64)
+	for i, members := range clusters {
+		for _, id := range members {
+			if _, ok := db.byID[id]; ok {
+				db.clusters[id] = int64(i + 1)
+			}
+		}
+	}
+	return nil
+}
+
+// ListClusters returns a page of clusters with at least two members.
+func (db *MemoryDB) ListClusters(ctx context.Context, limit, offset int) ([]Cluster, int, error) {
+	db.mu.RLock()
+	defer db.mu.RUnlock()
+
+	byCluster := make(map[int64][]uuid.UUID)
+	for id, clusterID := range db.clusters {
+		if _, ok := db.byID[id]; ok {
+			byCluster[clusterID] = append(byCluster[clusterID], id)
+		}
+	}
+
+	var all []Cluster
+	for clusterID, members := range byCluster {
+		if len(members) < 2 {
+			continue
+		}
+		sort.Slice(members, func(i, j int) bool {
+			return db.byID[members[i]].SerialID < db.byID[members[j]].SerialID
+		})
+		all = append(all, Cluster{ID: clusterID, Members: members})
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].ID < all[j].ID })
+
+	total := len(all)
+	all = all[min(offset, total):]
+	if len(all) > limit {
+		all = all[:limit]
+	}
+	return all, total, nil
+}
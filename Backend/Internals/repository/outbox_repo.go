@@ -0,0 +1,284 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/models"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/features"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
+	"github.com/google/uuid"
+)
+
+// OutboxEntry is a pending vector-store write for one image. It is
+// inserted in the same transaction as the image_metadata row, so a row
+// never exists without either its vector or the means to write it.
+type OutboxEntry struct {
+	ID        int64
+	ImageID   uuid.UUID
+	Version   fingerprint.Version
+	Structure fingerprint.Vector
+	Colour    fingerprint.Vector // nil when no colour vector is stored
+	Attempts  int
+	LastError string
+}
+
+// IndexRow is the index bookkeeping of one image_metadata row, as the
+// reconciliation pass compares it against the vector store.
+type IndexRow struct {
+	SerialID int64
+	ID       uuid.UUID
+	Indexed  bool
+	Version  fingerprint.Version // 0 if never recorded
+	Failed   bool                // index_error is set
+	Pending  bool                // an outbox entry is waiting
+}
+
+// encodeVector packs a vector in its binary form (fingerprint/vector.go).
+func encodeVector(vec fingerprint.Vector) []byte {
+	if vec == nil {
+		return nil
+	}
+	buf, _ := vec.MarshalBinary()
+	return buf
+}
+
+// decodeVector unpacks a vector from its binary form.
+func decodeVector(buf []byte) (fingerprint.Vector, error) {
+	if len(buf) == 0 {
+		return nil, nil
+	}
+	var vec fingerprint.Vector
+	if err := vec.UnmarshalBinary(buf); err != nil {
+		return nil, err
+	}
+	return vec, nil
+}
+
+// ReserveSerialID takes the next serial_id without inserting a row, so the
+// watermark can be embedded before the row and its outbox entry are
+// written together. Unused reservations just leave a gap.
+func (db *DB) ReserveSerialID(ctx context.Context) (int64, error) {
+	query := `SELECT nextval(pg_get_serial_sequence('image_metadata', 'serial_id'));`
+
+	var serialID int64
+	err := db.pool.QueryRowContext(ctx, query).Scan(&serialID)
+	return serialID, err
+}
+
+// ImageLookups are the data written next to an image row that lookups
+// other than the vector store rely on. They go into the row's transaction,
+// so an image is never registered without them.
+type ImageLookups struct {
+	Hashes   fingerprint.ImageHashes
+	Features *features.Set
+}
+
+// InsertImageWithOutbox inserts m under the serial_id already set on it
+// (see ReserveSerialID) together with its lookups and the outbox entry e,
+// in one transaction. It returns e with ID and ImageID filled in.
+func (db *DB) InsertImageWithOutbox(
+	ctx context.Context,
+	m models.ImageMetadata,
+	lookups ImageLookups,
+	e OutboxEntry,
+) (OutboxEntry, error) {
+
+	if err := checkHashes(lookups.Hashes); err != nil {
+		return e, err
+	}
+	data, err := lookups.Features.MarshalBinary()
+	if err != nil {
+		return e, err
+	}
+
+	tx, err := db.pool.BeginTx(ctx, nil)
+	if err != nil {
+		return e, err
+	}
+	defer tx.Rollback()
+
+	query := `
+    INSERT INTO image_metadata (
+        serial_id,
+        title,
+        description,
+        mime_type,
+        width_px,
+        height_px,
+        is_ai_generated,
+        owner,
+        tags,
+        derived_from,
+        captured_at
+    )
+    VALUES ($1,$2,$3,$4,$5,$6,$7,$8,COALESCE($9::text[], '{}'),$10,$11)
+    RETURNING id;
+    `
+	err = tx.QueryRowContext(
+		ctx, query,
+		m.SerialID,
+		m.Title,
+		m.Description,
+		m.MimeType,
+		m.WidthPx,
+		m.HeightPx,
+		m.IsAIGenerated,
+		m.Owner,
+		m.Tags,
+		m.DerivedFrom,
+		m.CapturedAt,
+	).Scan(&e.ImageID)
+	if err != nil {
+		return e, err
+	}
+
+	if err := insertHashes(ctx, tx, e.ImageID, lookups.Hashes); err != nil {
+		return e, err
+	}
+	if err := insertFeatures(ctx, tx, e.ImageID, lookups.Features, data); err != nil {
+		return e, err
+	}
+
+	query = `
+    INSERT INTO vector_outbox (image_id, fingerprint_version, structure, colour)
+    VALUES ($1, $2, $3, $4)
+    RETURNING id;
+    `
+	err = tx.QueryRowContext(
+		ctx, query,
+		e.ImageID, int(e.Version), encodeVector(e.Structure), encodeVector(e.Colour),
+	).Scan(&e.ID)
+	if err != nil {
+		return e, err
+	}
+
+	return e, tx.Commit()
+}
+
+// ClaimOutbox returns up to limit entries that are due, oldest first, and
+// pushes their next attempt lease into the future so concurrent
+// dispatchers don't pick them up too.
+func (db *DB) ClaimOutbox(ctx context.Context, limit int, lease time.Duration) ([]OutboxEntry, error) {
+	query := `
+    UPDATE vector_outbox
+    SET next_attempt_at = NOW() + make_interval(secs => $2)
+    WHERE id IN (
+        SELECT id
+        FROM vector_outbox
+        WHERE next_attempt_at <= NOW()
+        ORDER BY id
+        LIMIT $1
+        FOR UPDATE SKIP LOCKED
+    )
+    RETURNING id, image_id, fingerprint_version, structure, colour,
+              attempts, COALESCE(last_error, '');
+    `
+
+	rows, err := db.pool.QueryContext(ctx, query, limit, lease.Seconds())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []OutboxEntry
+	for rows.Next() {
+		var (
+			e                 OutboxEntry
+			version           int
+			structure, colour []byte
+		)
+		if err := rows.Scan(&e.ID, &e.ImageID, &version, &structure, &colour, &e.Attempts, &e.LastError); err != nil {
+			return nil, err
+		}
+		e.Version = fingerprint.Version(version)
+		if e.Structure, err = decodeVector(structure); err != nil {
+			return nil, err
+		}
+		if e.Colour, err = decodeVector(colour); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].ID < entries[j].ID })
+	return entries, nil
+}
+
+// CompleteOutbox removes a written entry and marks its row indexed at the
+// entry's version.
+func (db *DB) CompleteOutbox(ctx context.Context, e OutboxEntry) error {
+	tx, err := db.pool.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM vector_outbox WHERE id = $1;`, e.ID); err != nil {
+		return err
+	}
+
+	query := `
+    UPDATE image_metadata
+    SET is_indexed    = TRUE,
+        index_version = $2,
+        indexed_at    = NOW(),
+        index_error   = NULL
+    WHERE id = $1;
+    `
+	if _, err := tx.ExecContext(ctx, query, e.ImageID, int(e.Version)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// FailOutbox records a failed attempt and when to try again.
+func (db *DB) FailOutbox(ctx context.Context, id int64, reason string, retryAt time.Time) error {
+	query := `
+    UPDATE vector_outbox
+    SET attempts        = attempts + 1,
+        last_error      = $2,
+        next_attempt_at = $3
+    WHERE id = $1;
+    `
+	_, err := db.pool.ExecContext(ctx, query, id, reason, retryAt)
+	return err
+}
+
+// ListIndexRows returns up to limit rows with serial_id > afterSerial, in
+// serial order, for paging through the whole table.
+func (db *DB) ListIndexRows(ctx context.Context, afterSerial int64, limit int) ([]IndexRow, error) {
+	query := `
+    SELECT m.serial_id, m.id, m.is_indexed, COALESCE(m.index_version, 0),
+           m.index_error IS NOT NULL,
+           EXISTS (SELECT 1 FROM vector_outbox o WHERE o.image_id = m.id)
+    FROM image_metadata m
+    WHERE m.serial_id > $1
+    ORDER BY m.serial_id
+    LIMIT $2;
+    `
+
+	rows, err := db.pool.QueryContext(ctx, query, afterSerial, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []IndexRow
+	for rows.Next() {
+		var (
+			r       IndexRow
+			version int
+		)
+		if err := rows.Scan(&r.SerialID, &r.ID, &r.Indexed, &version, &r.Failed, &r.Pending); err != nil {
+			return nil, err
+		}
+		r.Version = fingerprint.Version(version)
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,68 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/models"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
+	"github.com/google/uuid"
+)
+
+// attributes.go — Keeping the vector store's copy of image attributes
+//
+// Filtered similarity searches run inside the vector store, so the
+// attributes they filter on are copied there whenever an image's vectors
+// are written (writeVectors). SyncAttributes backfills images indexed
+// before attributes were mirrored.
+
+// imageAttributes picks the filterable attributes out of a metadata row.
+func imageAttributes(m *models.ImageMetadata) fingerprint.Attributes {
+	attrs := fingerprint.Attributes{
+		IsAIGenerated: m.IsAIGenerated,
+		Tags:          m.Tags,
+		CreatedAt:     m.CreatedAt,
+	}
+	if m.Owner != nil {
+		attrs.Owner = *m.Owner
+	}
+	if m.MimeType != nil {
+		attrs.MimeType = *m.MimeType
+	}
+	return attrs
+}
+
+// writeAttributes mirrors the attributes of imageID's row into the vector
+// store. A row deleted meanwhile has nothing to mirror.
+func (s *ImageService) writeAttributes(ctx context.Context, imageID uuid.UUID) error {
+	meta, err := s.repo.GetImageMetadata(ctx, imageID)
+	if err != nil {
+		return err
+	}
+	if meta == nil {
+		return nil
+	}
+	return s.vectorDB.SetAttributes(ctx, imageID, imageAttributes(meta))
+}
+
+// SyncAttributes rewrites the attributes of every stored fingerprint from
+// its metadata row and returns how many it wrote. Vectors without a row
+// are skipped; Reconcile deals with those.
+func (s *ImageService) SyncAttributes(ctx context.Context) (int, error) {
+	synced := 0
+	err := s.vectorDB.Scroll(ctx, func(imageID uuid.UUID, _ fingerprint.Vector, _ fingerprint.Version) error {
+		meta, err := s.repo.GetImageMetadata(ctx, imageID)
+		if err != nil {
+			return err
+		}
+		if meta == nil {
+			return nil
+		}
+		if err := s.vectorDB.SetAttributes(ctx, imageID, imageAttributes(meta)); err != nil {
+			return fmt.Errorf("image %s: %w", imageID, err)
+		}
+		synced++
+		return nil
+	})
+	return synced, err
+}
@@ -0,0 +1,235 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/models"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
+	"github.com/google/uuid"
+)
+
+// cluster.go — Grouping the catalogue's near-identical records
+//
+// The clustering job asks the vector store for the nearest neighbours of
+// every stored fingerprint and joins each image with the neighbours that
+// score at least the threshold (union-find), so chains of near-duplicates
+// end up in one cluster even where the ends of the chain aren't close. The
+// clusters of two or more images are saved in place of the previous run's
+// and listed, largest first, by ClusterReport.
+
+const (
+	// DefaultClusterNeighbours is how many neighbours each image is
+	// compared with when the request doesn't say.
+	DefaultClusterNeighbours = 10
+
+	// MaxClusterPage caps the page size of the cluster report.
+	MaxClusterPage = 100
+
+	// clusterMetadataBatch is how many rows are fetched per query when the
+	// clusters are checked against image_metadata.
+	clusterMetadataBatch = 500
+)
+
+// ClusterRequest tunes a clustering run. Zero values take the defaults:
+// the near-duplicate threshold and DefaultClusterNeighbours.
+type ClusterRequest struct {
+	Threshold  float32
+	Neighbours int
+}
+
+// ClusterRunReport sums up a clustering run.
+type ClusterRunReport struct {
+	Images    int // fingerprints visited
+	Clusters  int // clusters of two or more images saved
+	Clustered int // images in those clusters
+}
+
+// ClusterCatalogue groups every stored fingerprint with its near
+// neighbours and saves the resulting clusters.
+func (s *ImageService) ClusterCatalogue(ctx context.Context, req ClusterRequest) (*ClusterRunReport, error) {
+	if req.Threshold == 0 {
+		req.Threshold = s.cfg.Thresholds.NearDuplicate
+	}
+	if req.Neighbours == 0 {
+		req.Neighbours = DefaultClusterNeighbours
+	}
+	if req.Threshold < 0 || req.Threshold > 1 || req.Neighbours < 1 {
+		return nil, errors.New("threshold must be 0..1 and neighbours positive")
+	}
+
+	report := &ClusterRunReport{}
+	sets := newUnionFind()
+
+	////////////////////////////////////////////////////////////
+	// 1️⃣ Join every image with its close neighbours
+	////////////////////////////////////////////////////////////
+
+	err := s.vectorDB.Scroll(ctx, func(id uuid.UUID, vec fingerprint.Vector, _ fingerprint.Version) error {
+		report.Images++
+		ids, _, err := s.vectorDB.QueryNamed(ctx, fingerprint.VectorStructure, vec, req.Neighbours, fingerprint.QueryOptions{
+			MinScore: req.Threshold,
+			Exclude:  []uuid.UUID{id},
+		})
+		if err != nil {
+			return fmt.Errorf("neighbours of %s: %w", id, err)
+		}
+		for _, other := range ids {
+			sets.union(id, other)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	////////////////////////////////////////////////////////////
+	// 2️⃣ Drop vectors without a row and order each cluster by
+	//    serial_id, oldest record first
+	////////////////////////////////////////////////////////////
+
+	var candidates [][]uuid.UUID
+	var ids []uuid.UUID
+	for _, members := range sets.groups() {
+		if len(members) > 1 {
+			candidates = append(candidates, members)
+			ids = append(ids, members...)
+		}
+	}
+
+	metas := make(map[uuid.UUID]*models.ImageMetadata, len(ids))
+	for start := 0; start < len(ids); start += clusterMetadataBatch {
+		batch, err := s.repo.GetImageMetadataBatch(ctx, ids[start:min(start+clusterMetadataBatch, len(ids))])
+		if err != nil {
+			return nil, err
+		}
+		for id, m := range batch {
+			metas[id] = m
+		}
+	}
+
+	var clusters [][]uuid.UUID
+	for _, members := range candidates {
+		kept := members[:0]
+		for _, id := range members {
+			if metas[id] != nil {
+				kept = append(kept, id)
+			}
+		}
+		if len(kept) < 2 {
+			continue
+		}
+		sort.Slice(kept, func(i, j int) bool { return metas[kept[i]].SerialID < metas[kept[j]].SerialID })
+		clusters = append(clusters, kept)
+		report.Clustered += len(kept)
+	}
+
+	////////////////////////////////////////////////////////////
+	// 3️⃣ Save them largest first, replacing the previous run
+	////////////////////////////////////////////////////////////
+
+	sort.SliceStable(clusters, func(i, j int) bool {
+		if len(clusters[i]) != len(clusters[j]) {
+			return len(clusters[i]) > len(clusters[j])
+		}
+		return metas[clusters[i][0]].SerialID < metas[clusters[j][0]].SerialID
+	})
+	if err := s.repo.ReplaceClusters(ctx, clusters); err != nil {
+		return nil, err
+	}
+	report.Clusters = len(clusters)
+	return report, nil
+}
+
+// ClusterEntry is one cluster of the report with its members' records.
+type ClusterEntry struct {
+	ID      int64
+	Size    int
+	Members []*models.ImageMetadata
+}
+
+// ClusterPage is one page of the cluster report.
+type ClusterPage struct {
+	Total    int
+	Limit    int
+	Offset   int
+	Clusters []ClusterEntry
+}
+
+// ClusterReport lists the saved clusters, largest first.
+func (s *ImageService) ClusterReport(ctx context.Context, limit, offset int) (*ClusterPage, error) {
+	if limit < 1 || limit > MaxClusterPage || offset < 0 {
+		return nil, fmt.Errorf("limit must be 1..%d and offset non-negative", MaxClusterPage)
+	}
+
+	clusters, total, err := s.repo.ListClusters(ctx, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []uuid.UUID
+	for _, c := range clusters {
+		ids = append(ids, c.Members...)
+	}
+	metas, err := s.repo.GetImageMetadataBatch(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &ClusterPage{Total: total, Limit: limit, Offset: offset, Clusters: []ClusterEntry{}}
+	for _, c := range clusters {
+		entry := ClusterEntry{ID: c.ID}
+		for _, id := range c.Members {
+			if m, ok := metas[id]; ok {
+				entry.Members = append(entry.Members, m)
+			}
+		}
+		entry.Size = len(entry.Members)
+		page.Clusters = append(page.Clusters, entry)
+	}
+	return page, nil
+}
+
+// unionFind joins image IDs into disjoint sets.
+type unionFind struct {
+	parent map[uuid.UUID]uuid.UUID
+}
+
+func newUnionFind() *unionFind {
+	return &unionFind{parent: make(map[uuid.UUID]uuid.UUID)}
+}
+
+// find returns the root of id's set, halving the path on the way.
+func (u *unionFind) find(id uuid.UUID) uuid.UUID {
+	if _, ok := u.parent[id]; !ok {
+		u.parent[id] = id
+	}
+	for u.parent[id] != id {
+		u.parent[id] = u.parent[u.parent[id]]
+		id = u.parent[id]
+	}
+	return id
+}
+
+func (u *unionFind) union(a, b uuid.UUID) {
+	ra, rb := u.find(a), u.find(b)
+	if ra != rb {
+		u.parent[ra] = rb
+	}
+}
+
+// groups returns every set, including single images.
+func (u *unionFind) groups() [][]uuid.UUID {
+	byRoot := make(map[uuid.UUID][]uuid.UUID)
+	for id := range u.parent {
+		root := u.find(id)
+		byRoot[root] = append(byRoot[root], id)
+	}
+	groups := make([][]uuid.UUID, 0, len(byRoot))
+	for _, members := range byRoot {
+		groups = append(groups, members)
+	}
+	return groups
+}
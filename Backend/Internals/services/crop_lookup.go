@@ -0,0 +1,126 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"image"
+	"sort"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/models"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/features"
+	"github.com/google/uuid"
+)
+
+// cropCandidates is how many bucket-index candidates get the full
+// descriptor match and geometry check.
+const cropCandidates = 20
+
+// CropLookupRequest asks which stored originals contain Image.
+type CropLookupRequest struct {
+	Image image.Image
+	Limit int
+}
+
+// CropRect is a rectangle in the original's pixels.
+type CropRect struct {
+	X      int `json:"x"`
+	Y      int `json:"y"`
+	Width  int `json:"width"`
+	Height int `json:"height"`
+}
+
+// CropLookupMatch is one original containing the query. Scale is original
+// pixels per query pixel; Inliers is how many feature matches agree on
+// the placement.
+type CropLookupMatch struct {
+	Rect     CropRect
+	Scale    float64
+	Inliers  int
+	Matches  int
+	Metadata *models.ImageMetadata
+}
+
+type CropLookupResult struct {
+	Keypoints int // detected in the query
+	Matches   []CropLookupMatch
+}
+
+// FindCrops looks for stored images the query was cropped (and possibly
+// rescaled) from, and where in them it sits.
+func (s *ImageService) FindCrops(
+	ctx context.Context,
+	req CropLookupRequest,
+) (*CropLookupResult, error) {
+
+	if req.Image == nil {
+		return nil, errors.New("an image is required")
+	}
+
+	query := features.Extract(req.Image)
+	result := &CropLookupResult{
+		Keypoints: len(query.Keypoints),
+		Matches:   []CropLookupMatch{},
+	}
+	if len(query.Keypoints) < features.MinInliers {
+		return result, nil
+	}
+
+	candidates, err := s.repo.FindFeatureCandidates(ctx, query.BucketKeys(), cropCandidates)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, len(candidates))
+	for i, c := range candidates {
+		ids[i] = c.ImageID
+	}
+	sets, err := s.repo.GetImageFeaturesBatch(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	type located struct {
+		id uuid.UUID
+		m  features.CropMatch
+	}
+	var found []located
+	for _, id := range ids {
+		set, ok := sets[id]
+		if !ok {
+			continue
+		}
+		if m, ok := features.LocateCrop(query, set); ok {
+			found = append(found, located{id: id, m: m})
+		}
+	}
+
+	sort.SliceStable(found, func(i, j int) bool { return found[i].m.Inliers > found[j].m.Inliers })
+	if len(found) > req.Limit {
+		found = found[:req.Limit]
+	}
+
+	ids = ids[:0]
+	for _, f := range found {
+		ids = append(ids, f.id)
+	}
+	metaMap, err := s.repo.GetImageMetadataBatch(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, f := range found {
+		meta, ok := metaMap[f.id]
+		if !ok {
+			continue
+		}
+		r := f.m.Rect
+		result.Matches = append(result.Matches, CropLookupMatch{
+			Rect:     CropRect{X: r.Min.X, Y: r.Min.Y, Width: r.Dx(), Height: r.Dy()},
+			Scale:    f.m.Transform.Scale,
+			Inliers:  f.m.Inliers,
+			Matches:  f.m.Matches,
+			Metadata: meta,
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,81 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"image"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
+	"github.com/google/uuid"
+)
+
+// duplicate.go — Is this image already in the catalogue?
+//
+// The watermark check only catches copies that still carry a mark. A copy
+// of a registered photo taken before it was marked (or after the mark was
+// stripped) embeds happily and becomes a second, competing record. With a
+// duplicate action configured, EmbedWatermarkInImage first looks the image
+// up by fingerprint and, when the best match scores at least the
+// threshold, rejects the upload, flags it, or links the new record to the
+// existing one as a derivative.
+
+// DuplicateAction is what embedding does with a near-duplicate upload.
+type DuplicateAction string
+
+const (
+	DuplicateOff    DuplicateAction = "off"    // no check
+	DuplicateReject DuplicateAction = "reject" // refuse the upload
+	DuplicateWarn   DuplicateAction = "warn"   // embed, report the match
+	DuplicateLink   DuplicateAction = "link"   // embed, record derived_from
+)
+
+// ParseDuplicateAction validates a configured action; empty means off.
+func ParseDuplicateAction(s string) (DuplicateAction, error) {
+	switch a := DuplicateAction(s); a {
+	case "":
+		return DuplicateOff, nil
+	case DuplicateOff, DuplicateReject, DuplicateWarn, DuplicateLink:
+		return a, nil
+	}
+	return "", fmt.Errorf("unknown duplicate action %q, expected off, reject, warn or link", s)
+}
+
+// DuplicateMatch is the catalogued image an upload duplicates.
+type DuplicateMatch struct {
+	ImageID uuid.UUID
+	Score   float32
+	Action  DuplicateAction // what was done about it
+}
+
+// duplicateCandidates is how many hits findDuplicate reads, so a few
+// vectors without a row (see Reconcile) don't hide a real match.
+const duplicateCandidates = 5
+
+// findDuplicate returns the closest catalogued image if it scores at least
+// the duplicate threshold, or nil.
+func (s *ImageService) findDuplicate(ctx context.Context, img image.Image) (*DuplicateMatch, error) {
+	vec, err := fingerprint.CreateFingerprintVersion(img, s.cfg.FingerprintVersion)
+	if err != nil {
+		return nil, err
+	}
+	ids, scores, err := s.vectorDB.QueryNamed(ctx, fingerprint.VectorStructure, vec, duplicateCandidates, fingerprint.QueryOptions{
+		MinScore: s.cfg.DuplicateThreshold,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	metaMap, err := s.repo.GetImageMetadataBatch(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for i, id := range ids {
+		if _, ok := metaMap[id]; ok {
+			return &DuplicateMatch{ImageID: id, Score: scores[i], Action: s.cfg.DuplicateAction}, nil
+		}
+	}
+	return nil, nil
+}
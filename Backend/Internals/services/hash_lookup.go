@@ -0,0 +1,91 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"image"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/models"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
+	"github.com/google/uuid"
+)
+
+// HashLookupRequest asks for images whose perceptual hash is close to a
+// query. Either Image or Hash must be set; Hash skips hashing entirely.
+type HashLookupRequest struct {
+	Image image.Image
+	Hash  fingerprint.Hash
+
+	Kind        fingerprint.HashKind
+	MaxDistance int
+	Limit       int
+}
+
+type HashLookupResult struct {
+	Kind  fingerprint.HashKind
+	Query string // hex of the hash that was searched for
+
+	Matches []HashLookupMatch
+}
+
+// HashLookupMatch is one stored image and its Hamming distance (in bits)
+// from the query.
+type HashLookupMatch struct {
+	Distance int
+	Metadata *models.ImageMetadata
+}
+
+// DefaultMaxDistance is the near-duplicate cut-off used when a lookup
+// doesn't give one: about 15% of the hash's bits.
+func DefaultMaxDistance(kind fingerprint.HashKind) int {
+	return kind.Bits() * 10 / 64
+}
+
+// FindByHash looks up near-duplicates by Hamming distance in the
+// metadata store, without touching the vector store.
+func (s *ImageService) FindByHash(
+	ctx context.Context,
+	req HashLookupRequest,
+) (*HashLookupResult, error) {
+
+	if req.Kind.Bits() == 0 {
+		return nil, errors.New("unknown hash kind")
+	}
+
+	query := req.Hash
+	if query == nil {
+		if req.Image == nil {
+			return nil, errors.New("an image or a hash is required")
+		}
+		query = fingerprint.ComputeHashes(req.Image)[req.Kind]
+	}
+	if len(query)*64 != req.Kind.Bits() {
+		return nil, errors.New("hash length does not match its kind")
+	}
+
+	matches, err := s.repo.FindByHash(ctx, req.Kind, query, req.MaxDistance, req.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]uuid.UUID, len(matches))
+	for i, m := range matches {
+		ids[i] = m.ImageID
+	}
+	metaMap, err := s.repo.GetImageMetadataBatch(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &HashLookupResult{
+		Kind:    req.Kind,
+		Query:   query.String(),
+		Matches: []HashLookupMatch{},
+	}
+	for _, m := range matches {
+		if meta, ok := metaMap[m.ImageID]; ok {
+			result.Matches = append(result.Matches, HashLookupMatch{Distance: m.Distance, Metadata: meta})
+		}
+	}
+	return result, nil
+}
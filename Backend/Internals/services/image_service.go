@@ -7,24 +7,84 @@ import (
 	"image"
 	"time"
 
-	//"github.com/google/uuid"
+	"github.com/google/uuid"
 
 	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/models"
 	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/repository"
 	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/engine"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/features"
 	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
 	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/payload"
 )
 
 type ImageService struct {
-	repo     *repository.DB
-	vectorDB *fingerprint.QdrantDB
+	repo     repository.Store
+	vectorDB fingerprint.VectorStore
+	cfg      ImageServiceConfig
+
+	reindex reindexState
+}
+
+// ImageServiceConfig carries the settings the service takes from config.
+type ImageServiceConfig struct {
+	// WatermarkKey orders the reversible watermark; without it reversible
+	// embedding and removal are refused.
+	WatermarkKey []byte
+
+	// Engine bounds the memory the tile pipeline may use per request.
+	Engine engine.Options
+
+	// FingerprintVersion is the variant stored and queried; it must match
+	// the vectors already in the store (see MigrateIndex). Zero means
+	// fingerprint.DefaultVersion.
+	FingerprintVersion fingerprint.Version
+
+	// ColourFingerprint stores a colour vector next to each fingerprint.
+	ColourFingerprint bool
+
+	// UnversionedAs is the version the re-index worker assumes for vectors
+	// stored before versions were recorded. Zero means v1.
+	UnversionedAs fingerprint.Version
+
+	// OutboxRetryDelay is the wait before retrying a failed vector write;
+	// it doubles with every further failure. Zero means 5s.
+	OutboxRetryDelay time.Duration
+
+	// Thresholds classify similarity scores into verdicts. The zero value
+	// means fingerprint.DefaultThresholds.
+	Thresholds fingerprint.Thresholds
+
+	// DuplicateAction is what embedding does with an image scoring at
+	// least DuplicateThreshold against a catalogued one (duplicate.go).
+	// Empty means DuplicateOff; a zero threshold means the near-duplicate
+	// threshold.
+	DuplicateAction    DuplicateAction
+	DuplicateThreshold float32
 }
 
-func NewImageService(repo *repository.DB, vectorDB *fingerprint.QdrantDB) *ImageService {
+func NewImageService(repo repository.Store, vectorDB fingerprint.VectorStore, cfg ImageServiceConfig) *ImageService {
+	if cfg.FingerprintVersion == 0 {
+		cfg.FingerprintVersion = fingerprint.DefaultVersion
+	}
+	if cfg.UnversionedAs == 0 {
+		cfg.UnversionedAs = fingerprint.V1
+	}
+	if cfg.Thresholds == (fingerprint.Thresholds{}) {
+		cfg.Thresholds = fingerprint.DefaultThresholds
+	}
+	if cfg.OutboxRetryDelay <= 0 {
+		cfg.OutboxRetryDelay = 5 * time.Second
+	}
+	if cfg.DuplicateAction == "" {
+		cfg.DuplicateAction = DuplicateOff
+	}
+	if cfg.DuplicateThreshold == 0 {
+		cfg.DuplicateThreshold = cfg.Thresholds.NearDuplicate
+	}
 	return &ImageService{
 		repo:     repo,
 		vectorDB: vectorDB,
+		cfg:      cfg,
 	}
 }
 
@@ -34,6 +94,41 @@ type EmbedRequest struct {
 	MimeType      *string
 	IsAIGenerated bool
 	CapturedAt    *time.Time
+
+	// Owner and Tags are stored on the row and mirrored into the vector
+	// store, where similarity searches can filter on them.
+	Owner *string
+	Tags  []string
+
+	// Reversible selects the removable watermark used for archival
+	// masters instead of the robust frequency-domain one.
+	Reversible bool
+
+	// AddLayer lets an already watermarked image take one more owner's
+	// mark on the next free layer instead of being refused.
+	AddLayer bool
+}
+
+type AuthRequest struct {
+	// K is the number of similar images to return.
+	K int
+
+	// MatchOrientations also searches the mirrored and rotated versions of
+	// the image, catching flipped/rotated reposts.
+	MatchOrientations bool
+
+	// ColourWeight (0..1) is the share of colour similarity in the
+	// ranking; the rest is structure. Zero ranks by structure only.
+	ColourWeight float64
+
+	// MinScore leaves out similar images scoring below it; zero means no
+	// limit. It is passed to the vector query, so fewer than K images may
+	// come back.
+	MinScore float32
+
+	// Fallback answers an image whose watermark can't be read with the
+	// similarity search and a provenance guess instead of an error.
+	Fallback bool
 }
 
 type AuthResult struct {
@@ -41,8 +136,43 @@ type AuthResult struct {
 
 	ExtractedMetadata *models.ImageMetadata
 
+	// Layers is the chain of owners' marks, from the original (layer 0)
+	// to the most recently added one.
+	Layers []WatermarkLayer
+
 	SimilarImages    []*models.ImageMetadata
 	SimilarityScores []float32
+
+	// SimilarTransforms says, for each similar image, how the original was
+	// rotated or flipped to give the queried image ("identity",
+	// "flip_horizontal", "rotate_90", ...). Only set for orientation
+	// matching queries.
+	SimilarTransforms []string
+
+	// StructureScores and ColourScores break SimilarityScores down when
+	// the query weighted colour in.
+	StructureScores []float32
+	ColourScores    []float32
+
+	// SimilarVerdicts classifies each similar image by its score
+	// (exact-copy, near-duplicate, related, unrelated); Verdict is the
+	// closest of them, or unrelated when nothing was found.
+	SimilarVerdicts []fingerprint.Verdict
+	Verdict         fingerprint.Verdict
+
+	// Integrity compares the image with the fingerprint stored for the
+	// record its watermark names.
+	Integrity *IntegrityCheck
+
+	// Provenance is set instead of Layers and Integrity when the
+	// watermark couldn't be read and the request asked for the fallback.
+	Provenance *Provenance
+}
+
+// WatermarkLayer is one owner's mark found in a layered image.
+type WatermarkLayer struct {
+	Layer    int
+	Metadata *models.ImageMetadata
 }
 
 // func UUIDToUint64(id uuid.UUID) uint64 {
@@ -68,20 +198,68 @@ func (s *ImageService) EmbedWatermarkInImage(
 	ctx context.Context,
 	img image.Image,
 	req EmbedRequest,
-) (*image.YCbCr, []float64, error) {
+) (image.Image, fingerprint.Vector, *DuplicateMatch, error) {
+
+	if req.Reversible && len(s.cfg.WatermarkKey) == 0 {
+		return nil, nil, nil, errors.New("reversible watermarking is not configured")
+	}
 
 	////////////////////////////////////////////////////////////
-	// 1️⃣ Convert image to Y matrix (required for Identify)
+	// 1️⃣ Convert image to YCbCr once — the robust mark is written
+	//    into this copy, and Identify reads its Y plane tile by tile
 	////////////////////////////////////////////////////////////
 
-	coeff_matrices := make([]engine.Constants, 0)
-	coeff_matrices = append(coeff_matrices, *engine.CreateConstant(2, 3))
-	coeff_matrices = append(coeff_matrices, *engine.CreateConstant(3, 2))
+	if req.AddLayer && req.Reversible {
+		return nil, nil, nil, errors.New("layered watermarks require robust mode")
+	}
+
+	coeff_matrices := engine.LayerConstants(0)
+
+	var ycb *image.YCbCr
+	source := img
+	if !req.Reversible {
+		ycb = engine.ToYCbCr(img)
+		source = ycb
+	}
+	tiles := engine.NewTileSource(source, s.cfg.Engine)
+	_, _, alreadyWatermarked := engine.IdentifyTiles(tiles, coeff_matrices)
+
+	// In layered mode an existing mark is expected; take the next layer.
+	// A layered mark is meant for an image already on record.
+	layered := alreadyWatermarked && req.AddLayer
+
+	if layered {
+		layer := engine.NextFreeLayer(tiles)
+		if layer < 0 {
+			return nil, nil, nil, errors.New("image has no free watermark layer")
+		}
+		coeff_matrices = engine.LayerConstants(layer)
+		alreadyWatermarked = false
+	}
 
-	_, _, alreadyWatermarked := engine.Identify(img, coeff_matrices)
+	// A reversibly marked master carries no robust mark; whatever mode is
+	// asked for, it is already on record.
+	if !alreadyWatermarked && len(s.cfg.WatermarkKey) > 0 {
+		_, _, err := engine.ExtractReversible(img, s.cfg.WatermarkKey)
+		alreadyWatermarked = err == nil
+	}
 
 	if alreadyWatermarked {
-		return nil, nil, errors.New("image is already watermarked")
+		return nil, nil, nil, errors.New("image is already watermarked")
+	}
+
+	// An unmarked copy of a catalogued image is handled per the configured
+	// duplicate action.
+	var duplicate *DuplicateMatch
+	if s.cfg.DuplicateAction != DuplicateOff && !layered {
+		match, err := s.findDuplicate(ctx, img)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		if match != nil && match.Action == DuplicateReject {
+			return nil, nil, match, errors.New("image is a near-duplicate of an existing image")
+		}
+		duplicate = match
 	}
 
 	////////////////////////////////////////////////////////////
@@ -106,18 +284,24 @@ func (s *ImageService) EmbedWatermarkInImage(
 		WidthPx:       widthPtr,
 		HeightPx:      heightPtr,
 		IsAIGenerated: req.IsAIGenerated,
+		Owner:         req.Owner,
+		Tags:          req.Tags,
 		CapturedAt:    req.CapturedAt,
 	}
+	if duplicate != nil && duplicate.Action == DuplicateLink {
+		meta.DerivedFrom = &duplicate.ImageID
+	}
 
 	////////////////////////////////////////////////////////////
-	// 4️⃣ Insert metadata into PostgreSQL
+	// 4️⃣ Reserve serial_id — the row itself is written together
+	//    with its vector outbox entry once the fingerprint exists
 	////////////////////////////////////////////////////////////
 
-	// 4️⃣ Insert metadata — now returns serial_id too
-	imageUUID, serialID, err := s.repo.InsertImageMetadata(ctx, meta)
+	serialID, err := s.repo.ReserveSerialID(ctx)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
+	meta.SerialID = serialID
 
 	////////////////////////////////////////////////////////////
 	// 5️⃣ Convert UUID → uint64 (lower 64 bits)
@@ -139,138 +323,372 @@ func (s *ImageService) EmbedWatermarkInImage(
 	}
 	payloadBits, err := payload.PayloadGenerate(payloadFields)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	////////////////////////////////////////////////////////////
 	// 7️⃣ Embed watermark in frequency domain
 	////////////////////////////////////////////////////////////
 
-	watermarkedImg, success := engine.EmbedWatermark(img, payloadBits, coeff_matrices)
-	if !success {
-		return nil, nil, errors.New("failed to embed watermark")
+	var watermarkedImg image.Image
+	if req.Reversible {
+		watermarkedImg, err = engine.EmbedReversible(img, payloadBits, s.cfg.WatermarkKey)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	} else {
+		// The layer is known to be free and its tiles are already cached.
+		if !engine.EmbedTiles(tiles, payloadBits, coeff_matrices) {
+			return nil, nil, nil, errors.New("failed to embed watermark")
+		}
+		watermarkedImg = ycb
 	}
 
 	////////////////////////////////////////////////////////////
-	// 8️⃣ Generate fingerprint (1024-D vector)
+	// 8️⃣ Generate fingerprint (1024-D vector), perceptual hashes and
+	//    local features
 	////////////////////////////////////////////////////////////
 
-	fingerprint := fingerprint.Createfingerprint(watermarkedImg)
+	hashes := fingerprint.ComputeHashes(watermarkedImg)
+	localFeatures := features.Extract(watermarkedImg)
+	colourFingerprint := fingerprint.CreateColourFingerprint(watermarkedImg)
+	fingerprint, err := fingerprint.CreateFingerprintVersion(watermarkedImg, s.cfg.FingerprintVersion)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 
 	////////////////////////////////////////////////////////////
-	// 9️⃣ Store fingerprint in Qdrant
+	// 9️⃣ Insert metadata, its lookups and its pending vector write in one
+	//    transaction, then try the vector store right away
 	////////////////////////////////////////////////////////////
 
-	// (store fingerprint still uses imageUUID for Qdrant)
-	err = s.vectorDB.StoreFingerprint(ctx, imageUUID, fingerprint)
+	entry := repository.OutboxEntry{
+		Version:   s.cfg.FingerprintVersion,
+		Structure: fingerprint,
+	}
+	if s.cfg.ColourFingerprint {
+		entry.Colour = colourFingerprint
+	}
+
+	// The hashes and features go in with the row so near-duplicate and
+	// crop checks work without the vector store.
+	lookups := repository.ImageLookups{Hashes: hashes, Features: localFeatures}
+
+	entry, err = s.repo.InsertImageWithOutbox(ctx, meta, lookups, entry)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	// On failure the entry stays in the outbox and the dispatcher retries.
+	if err := s.dispatchEntry(ctx, entry); err != nil {
+		fmt.Println("Vector store write deferred to the outbox:", err)
+	}
 
 	////////////////////////////////////////////////////////////
 	// 🔟 Return result
 	////////////////////////////////////////////////////////////
 
-	return watermarkedImg, fingerprint, nil
+	return watermarkedImg, fingerprint, duplicate, nil
 }
 
 func (s *ImageService) ImageAuth(
 	ctx context.Context,
 	img image.Image,
-	k int, // number of similar images
+	req AuthRequest,
 ) (*AuthResult, error) {
 
 	result := &AuthResult{}
 
+	// One tile source for every layer: each tile is converted at most once.
+	tiles := engine.NewTileSource(img, s.cfg.Engine)
+
 	////////////////////////////////////////////////////////////
-	// 1️⃣ Identify watermark
+	// 1️⃣ Read the base watermark (layer 0)
 	////////////////////////////////////////////////////////////
 
-	coeff_matrices := make([]engine.Constants, 0)
-	coeff_matrices = append(coeff_matrices, *engine.CreateConstant(2, 3))
-	coeff_matrices = append(coeff_matrices, *engine.CreateConstant(3, 2))
+	meta, err := s.readLayer(ctx, tiles, 0)
 
-	_, _, exists := engine.Identify(img, coeff_matrices)
-	if !exists {
-		return nil, errors.New("no watermark detected in image")
+	// Archival masters carry the reversible mark instead of a robust one.
+	reversible := false
+	if err != nil && err.Error() == "no watermark detected in image" && len(s.cfg.WatermarkKey) > 0 {
+		revMeta, _, revErr := s.readReversible(ctx, img)
+		switch {
+		case revErr == nil:
+			meta, err, reversible = revMeta, nil, true
+		case !errors.Is(revErr, engine.ErrNoReversibleMark):
+			err = revErr
+		}
+	}
+
+	if err != nil && !(req.Fallback && watermarkUnreadable(err)) {
+		return nil, err
 	}
+	readErr := err
+
+	// Without a readable mark there are no layers to follow and no record
+	// to check the content against; the similarity search still runs.
+	if meta != nil {
+		result.WatermarkValid = true
+		result.Layers = append(result.Layers, WatermarkLayer{Layer: 0, Metadata: meta})
+
+		// result.ExtractedMetadata = meta
+
+		////////////////////////////////////////////////////////////
+		// 2️⃣ Follow the chain of added layers (robust marks only;
+		//    reversible masters can't take layers)
+		////////////////////////////////////////////////////////////
+
+		for layer := 1; layer < engine.MaxLayers && !reversible; layer++ {
+			layerMeta, err := s.readLayer(ctx, tiles, layer)
+			if err != nil {
+				if err.Error() != "no watermark detected in image" {
+					fmt.Println("Stopping at layer", layer, ":", err)
+				}
+				break
+			}
+			result.Layers = append(result.Layers, WatermarkLayer{Layer: layer, Metadata: layerMeta})
+		}
 
-	result.WatermarkValid = true
+		////////////////////////////////////////////////////////////
+		// 3️⃣ Compare the content with the fingerprint on record for
+		//    the decoded ID — catches transplanted marks and edits
+		////////////////////////////////////////////////////////////
+
+		result.Integrity, err = s.checkIntegrity(ctx, img, meta.ID, req.MatchOrientations)
+		if err != nil {
+			return nil, err
+		}
+	}
 
 	////////////////////////////////////////////////////////////
-	// 2️⃣ Extract watermark bits from all tiles
+	// 4️⃣ Find similar images via the vector store
 	////////////////////////////////////////////////////////////
 
-	payloadCopies, ok := engine.ExtractWatermark(img, coeff_matrices)
-	fmt.Println("Length : ", len(payloadCopies))
-	if !ok {
+	var similarIDs []uuid.UUID
+	var scores []float32
+	var transforms []string
 
-		return nil, errors.New("failed to extract watermark")
+	// With colour weighted in, fetch a wider pool of structure hits so
+	// the blend can reorder them.
+	k := req.K
+	if req.ColourWeight > 0 {
+		k = fingerprint.CandidatePool(req.K)
+	}
+
+	// MinScore goes to the structure query on its own; blended scores are
+	// cut after blending, since colour alone can't decide a match.
+	opts := fingerprint.QueryOptions{MinScore: req.MinScore}
+
+	if req.MatchOrientations {
+		matches, err := fingerprint.FindSimilarOriented(ctx, s.vectorDB, s.cfg.FingerprintVersion, img, k, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range matches {
+			similarIDs = append(similarIDs, m.ImageID)
+			scores = append(scores, m.Score)
+			transforms = append(transforms, m.Transform.String())
+		}
+	} else {
+		similarIDs, scores, err = fingerprint.FindSimilar(ctx, s.vectorDB, s.cfg.FingerprintVersion, img, k, opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var structureScores, colourScores []float32
+
+	if req.ColourWeight > 0 {
+		// The colour vector is taken of the query as given; only its
+		// histogram half is orientation-independent.
+		colourIDs, colourHits, err := fingerprint.FindSimilarColour(ctx, s.vectorDB, img, k, fingerprint.QueryOptions{})
+		if errors.Is(err, fingerprint.ErrUnknownVector) {
+			return nil, errors.New("colour fingerprints are not available")
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		transformOf := make(map[uuid.UUID]string, len(transforms))
+		for i, t := range transforms {
+			transformOf[similarIDs[i]] = t
+		}
+
+		blended := fingerprint.CombineScores(similarIDs, scores, colourIDs, colourHits, fingerprint.Weights{
+			Structure: 1 - req.ColourWeight,
+			Colour:    req.ColourWeight,
+		})
+		for i, m := range blended {
+			if req.MinScore != 0 && m.Score < req.MinScore {
+				blended = blended[:i]
+				break
+			}
+		}
+		if len(blended) > req.K {
+			blended = blended[:req.K]
+		}
+
+		similarIDs, scores, transforms = nil, nil, nil
+		for _, m := range blended {
+			similarIDs = append(similarIDs, m.ImageID)
+			scores = append(scores, m.Score)
+			structureScores = append(structureScores, m.StructureScore)
+			colourScores = append(colourScores, m.ColourScore)
+			if req.MatchOrientations {
+				t, ok := transformOf[m.ImageID]
+				if !ok {
+					t = fingerprint.Identity.String() // found by colour alone
+				}
+				transforms = append(transforms, t)
+			}
+		}
 	}
 
 	////////////////////////////////////////////////////////////
-	// 3️⃣ Verify payload (majority vote + CRC check)
+	// 5️⃣ Fetch metadata batch for similar images
 	////////////////////////////////////////////////////////////
 
-	fields, err := payload.PayloadVerify(payloadCopies)
+	metaMap, err := s.repo.GetImageMetadataBatch(ctx, similarIDs)
 	if err != nil {
-		fmt.Println("Error in Payload Verify")
+		fmt.Println("Error in Postgres 2")
 		return nil, err
 	}
 
+	// Keep scores (and transforms) aligned with the images that still
+	// have metadata.
+	var similarMetas []*models.ImageMetadata
+	var similarScores []float32
+	var similarTransforms []string
+	var similarStructure, similarColour []float32
+
+	for i, id := range similarIDs {
+		if m, ok := metaMap[id]; ok {
+			similarMetas = append(similarMetas, m)
+			similarScores = append(similarScores, scores[i])
+			if transforms != nil {
+				similarTransforms = append(similarTransforms, transforms[i])
+			}
+			if structureScores != nil {
+				similarStructure = append(similarStructure, structureScores[i])
+				similarColour = append(similarColour, colourScores[i])
+			}
+		}
+	}
+
+	result.SimilarImages = similarMetas
+	result.SimilarityScores = similarScores
+	result.SimilarTransforms = similarTransforms
+	result.StructureScores = similarStructure
+	result.ColourScores = similarColour
+
 	////////////////////////////////////////////////////////////
-	// 4️⃣ Convert uint64 → UUID
+	// 6️⃣ Classify the similar images
 	////////////////////////////////////////////////////////////
 
-	// 4️⃣ fields.MetadataID is directly the serial_id — no UUID conversion
-	serialID := int64(fields.MetadataID)
+	result.Verdict = fingerprint.VerdictUnrelated
+	for i, score := range similarScores {
+		v := s.cfg.Thresholds.Classify(score)
+		result.SimilarVerdicts = append(result.SimilarVerdicts, v)
+		if i == 0 {
+			result.Verdict = v
+		}
+	}
 
 	////////////////////////////////////////////////////////////
-	// 5️⃣ Fetch metadata from PostgreSQL
+	// 7️⃣ Without a watermark, name the probable original
 	////////////////////////////////////////////////////////////
 
-	// 5️⃣ Fetch by serial_id
-	meta, err := s.repo.GetImageMetadataBySerialID(ctx, serialID)
-	// if err != nil || meta == nil {
-	//     return nil, errors.New("metadata not found for extracted watermark ID")
-	// }
-	if err != nil {
-		fmt.Println("Error in Postgres 1")
-		return nil, err
-	}
 	if meta == nil {
-		println("Error in metadata ")
-		return nil, errors.New("metadata not found for extracted watermark ID")
+		result.Provenance = s.traceProvenance(result, readErr)
 	}
 
-	// result.ExtractedMetadata = meta
+	return result, nil
+}
 
-	////////////////////////////////////////////////////////////
-	// 6️⃣ Find similar images via Qdrant
-	////////////////////////////////////////////////////////////
+// RemoveWatermark restores the bit-exact original of an image that was
+// watermarked in reversible mode, returning it with the record it names.
+func (s *ImageService) RemoveWatermark(
+	ctx context.Context,
+	img image.Image,
+) (image.Image, *models.ImageMetadata, error) {
 
-	similarIDs, scores, err := s.vectorDB.FindSimilar(ctx, img, k)
+	if len(s.cfg.WatermarkKey) == 0 {
+		return nil, nil, errors.New("reversible watermarking is not configured")
+	}
+
+	meta, original, err := s.readReversible(ctx, img)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
+	return original, meta, nil
+}
 
-	////////////////////////////////////////////////////////////
-	// 7️⃣ Fetch metadata batch for similar images
-	////////////////////////////////////////////////////////////
+// readReversible reads the reversible watermark with the configured key and
+// returns the record it names and the restored original.
+func (s *ImageService) readReversible(
+	ctx context.Context,
+	img image.Image,
+) (*models.ImageMetadata, image.Image, error) {
 
-	metaMap, err := s.repo.GetImageMetadataBatch(ctx, similarIDs)
+	bits, original, err := engine.ExtractReversible(img, s.cfg.WatermarkKey)
 	if err != nil {
-		fmt.Println("Error in Postgres 2")
-		return nil, err
+		return nil, nil, err
 	}
 
-	var similarMetas []*models.ImageMetadata
+	fields, err := payload.PayloadVerify([][]int{bits})
+	if err != nil {
+		return nil, nil, err
+	}
 
-	for _, id := range similarIDs {
-		if m, ok := metaMap[id]; ok {
-			similarMetas = append(similarMetas, m)
-		}
+	meta, err := s.repo.GetImageMetadataBySerialID(ctx, int64(fields.MetadataID))
+	if err != nil {
+		return nil, nil, err
+	}
+	if meta == nil {
+		return nil, nil, errors.New("metadata not found for extracted watermark ID")
 	}
 
-	result.SimilarImages = similarMetas
-	result.SimilarityScores = scores
+	return meta, original, nil
+}
 
-	return result, nil
+// readLayer identifies, extracts and verifies one watermark layer and
+// returns the metadata record its payload points to.
+func (s *ImageService) readLayer(
+	ctx context.Context,
+	tiles *engine.TileSource,
+	layer int,
+) (*models.ImageMetadata, error) {
+
+	coeff_matrices := engine.LayerConstants(layer)
+
+	_, _, exists := engine.IdentifyTiles(tiles, coeff_matrices)
+	if !exists {
+		return nil, errors.New("no watermark detected in image")
+	}
+
+	// Extract watermark bits from all tiles
+	payloadCopies, ok := engine.ExtractTiles(tiles, coeff_matrices)
+	fmt.Println("Length : ", len(payloadCopies))
+	if !ok {
+		return nil, errors.New("failed to extract watermark")
+	}
+
+	// Verify payload (majority vote + CRC check)
+	fields, err := payload.PayloadVerify(payloadCopies)
+	if err != nil {
+		fmt.Println("Error in Payload Verify")
+		return nil, err
+	}
+
+	// fields.MetadataID is directly the serial_id — no UUID conversion
+	meta, err := s.repo.GetImageMetadataBySerialID(ctx, int64(fields.MetadataID))
+	if err != nil {
+		fmt.Println("Error in Postgres 1")
+		return nil, err
+	}
+	if meta == nil {
+		return nil, errors.New("metadata not found for extracted watermark ID")
+	}
+
+	return meta, nil
 }
@@ -0,0 +1,92 @@
+package services
+
+import (
+	"context"
+	"image"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
+	"github.com/google/uuid"
+)
+
+// integrity.go — Does the watermark belong to this content?
+//
+// A watermark says which record an image came from, but it can be lifted
+// onto another picture, or the picture edited after marking. The
+// fingerprint stored for that record describes the content as it was
+// marked, so comparing it with a fresh fingerprint of the queried image
+// tells the two cases apart: a copy scores near-duplicate or better
+// (same thresholds as the similarity verdicts), anything lower means the
+// mark was transplanted or the content altered.
+
+// Integrity verdicts.
+const (
+	IntegrityMatch    = "watermark matches content"
+	IntegrityMismatch = "watermark transplanted or content altered"
+	IntegrityUnknown  = "no fingerprint on record"
+)
+
+// IntegrityCheck compares the queried image with the fingerprint stored
+// for the record its watermark names.
+type IntegrityCheck struct {
+	ImageID uuid.UUID
+	Verdict string
+
+	// Score is the cosine similarity of the two fingerprints; zero when
+	// there is no fingerprint on record.
+	Score float32
+
+	// Transform is how the recorded image was turned to give the queried
+	// one, when the query matched orientations.
+	Transform string `json:",omitempty"`
+}
+
+// checkIntegrity fingerprints img at the version of the vector stored for
+// id and compares the two. With orientations it takes the best of the
+// eight turns of img.
+func (s *ImageService) checkIntegrity(
+	ctx context.Context,
+	img image.Image,
+	id uuid.UUID,
+	orientations bool,
+) (*IntegrityCheck, error) {
+
+	check := &IntegrityCheck{ImageID: id, Verdict: IntegrityUnknown}
+
+	stored, v, err := s.vectorDB.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		// Not written yet (outbox) or lost; nothing to compare against.
+		return check, nil
+	}
+	if v == 0 {
+		v = s.cfg.UnversionedAs
+	}
+
+	if orientations {
+		vecs, err := fingerprint.OrientedFingerprints(img, v)
+		if err != nil {
+			return nil, err
+		}
+		check.Score = -1
+		for _, o := range fingerprint.Orientations {
+			if score := fingerprint.Similarity(vecs[o], stored); score > check.Score {
+				check.Score = score
+				check.Transform = o.Inverse().String()
+			}
+		}
+	} else {
+		fresh, err := fingerprint.CreateFingerprintVersion(img, v)
+		if err != nil {
+			return nil, err
+		}
+		check.Score = fingerprint.Similarity(fresh, stored)
+	}
+
+	check.Verdict = IntegrityMismatch
+	if check.Score >= s.cfg.Thresholds.NearDuplicate {
+		check.Verdict = IntegrityMatch
+	}
+	return check, nil
+}
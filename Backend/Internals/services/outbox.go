@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/repository"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
+)
+
+// outbox.go — Delivering vector writes recorded in the outbox
+//
+// EmbedWatermarkInImage writes the metadata row and an outbox entry
+// holding its vectors in one transaction, then tries the vector store
+// straight away. Whatever doesn't get through is retried here with an
+// exponential backoff until it does; only then is the row marked indexed.
+// Vector writes are upserts, so delivering an entry twice is harmless.
+
+const (
+	// OutboxBatchSize is how many entries one dispatcher pass claims.
+	OutboxBatchSize = 100
+
+	// outboxLease keeps a claimed entry from being claimed again while it
+	// is being written.
+	outboxLease = time.Minute
+
+	outboxMaxDelay = 10 * time.Minute
+)
+
+// outboxDelay is the wait before attempt number attempts+1: the configured
+// first delay, doubled per earlier failure up to outboxMaxDelay.
+func (s *ImageService) outboxDelay(attempts int) time.Duration {
+	d := s.cfg.OutboxRetryDelay
+	for i := 1; i < attempts && d < outboxMaxDelay; i++ {
+		d *= 2
+	}
+	if d > outboxMaxDelay {
+		d = outboxMaxDelay
+	}
+	return d
+}
+
+// dispatchEntry writes the vectors of e and marks its row indexed. A
+// failure is recorded on the entry, with the time of the next attempt.
+func (s *ImageService) dispatchEntry(ctx context.Context, e repository.OutboxEntry) error {
+	err := s.writeVectors(ctx, e)
+	if err == nil {
+		return s.repo.CompleteOutbox(ctx, e)
+	}
+
+	retryAt := time.Now().Add(s.outboxDelay(e.Attempts + 1))
+	if ferr := s.repo.FailOutbox(ctx, e.ID, err.Error(), retryAt); ferr != nil {
+		return fmt.Errorf("%v (and recording the failure: %v)", err, ferr)
+	}
+	return err
+}
+
+func (s *ImageService) writeVectors(ctx context.Context, e repository.OutboxEntry) error {
+	if err := s.vectorDB.Store(ctx, e.ImageID, e.Structure, e.Version); err != nil {
+		return err
+	}
+	if err := s.writeAttributes(ctx, e.ImageID); err != nil {
+		return err
+	}
+	if e.Colour == nil {
+		return nil
+	}
+
+	// A collection without room for colour vectors will never take one;
+	// retrying wouldn't help, so the structure vector alone has to do.
+	err := s.vectorDB.StoreNamed(ctx, e.ImageID, fingerprint.VectorColour, e.Colour)
+	if errors.Is(err, fingerprint.ErrUnknownVector) {
+		fmt.Println("Colour fingerprint dropped:", err)
+		return nil
+	}
+	return err
+}
+
+// OutboxReport counts what a dispatcher pass did.
+type OutboxReport struct {
+	Delivered int
+	Failed    int // left in the outbox for a later attempt
+}
+
+// DispatchOutbox delivers up to limit due outbox entries.
+func (s *ImageService) DispatchOutbox(ctx context.Context, limit int) (OutboxReport, error) {
+	var report OutboxReport
+
+	entries, err := s.repo.ClaimOutbox(ctx, limit, outboxLease)
+	if err != nil {
+		return report, err
+	}
+
+	for _, e := range entries {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		if err := s.dispatchEntry(ctx, e); err != nil {
+			fmt.Printf("Outbox entry %d (image %s, attempt %d) failed: %v\n", e.ID, e.ImageID, e.Attempts+1, err)
+			report.Failed++
+			continue
+		}
+		report.Delivered++
+	}
+	return report, nil
+}
+
+// RunOutboxDispatcher calls DispatchOutbox every interval until ctx is
+// cancelled, draining full batches without waiting.
+func (s *ImageService) RunOutboxDispatcher(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			report, err := s.DispatchOutbox(ctx, OutboxBatchSize)
+			if err != nil {
+				fmt.Println("Outbox dispatch failed:", err)
+				break
+			}
+			if report.Failed > 0 || report.Delivered < OutboxBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
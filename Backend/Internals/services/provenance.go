@@ -0,0 +1,65 @@
+package services
+
+import (
+	"fmt"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/models"
+)
+
+// provenance.go — Where did an image without a readable watermark come from?
+//
+// Heavy recompression, resizing or deliberate stripping can leave a copy
+// whose watermark no longer decodes, yet whose content still fingerprints
+// close to the catalogued original. In fallback mode ImageAuth then skips
+// the watermark steps, runs the usual similarity search and names the best
+// match scoring at least "related" as the probable original.
+
+// ProvenanceNoOriginal is the verdict when no catalogued image comes close.
+const ProvenanceNoOriginal = "watermark missing, no likely original found"
+
+// Provenance explains an image whose watermark could not be read.
+type Provenance struct {
+	// Reason is why the watermark could not be read.
+	Reason string
+
+	// Verdict is "watermark missing, likely derived from <image ID>" or
+	// ProvenanceNoOriginal.
+	Verdict string
+
+	// Original is the probable source, nil when none was found; Score
+	// and Transform are those of its similarity match.
+	Original  *models.ImageMetadata
+	Score     float32
+	Transform string `json:",omitempty"`
+}
+
+// watermarkUnreadable reports whether a base-layer error means the mark is
+// missing or damaged, as opposed to a lookup failing.
+func watermarkUnreadable(err error) bool {
+	switch err.Error() {
+	case "no watermark detected in image",
+		"failed to extract watermark",
+		"no payloads provided",
+		"all payloads failed CRC / flag validation":
+		return true
+	}
+	return false
+}
+
+// traceProvenance picks the probable original out of the ranked similar
+// images of result.
+func (s *ImageService) traceProvenance(result *AuthResult, reason error) *Provenance {
+	p := &Provenance{Reason: reason.Error(), Verdict: ProvenanceNoOriginal}
+
+	if len(result.SimilarImages) == 0 || result.SimilarityScores[0] < s.cfg.Thresholds.Related {
+		return p
+	}
+
+	p.Original = result.SimilarImages[0]
+	p.Score = result.SimilarityScores[0]
+	if result.SimilarTransforms != nil {
+		p.Transform = result.SimilarTransforms[0]
+	}
+	p.Verdict = fmt.Sprintf("watermark missing, likely derived from %s", p.Original.ID)
+	return p
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
+	"github.com/google/uuid"
+)
+
+// reconcile.go — Finding rows and vectors that lost their counterpart
+//
+// Before the outbox a failed vector write left an image_metadata row with
+// no fingerprint, and nothing ever removed the vectors of deleted rows.
+// Reconcile compares both stores:
+//
+//   - rows without a vector (and no outbox entry that would write it) can't
+//     be repaired, since originals aren't kept; fixing marks them failed so
+//     the re-index worker stops trying
+//   - vectors without a row are deleted when fixing
+//
+// Rows with a vector that just isn't recorded are left to the re-index
+// worker, which backfills them.
+
+// reconcilePage is how many rows are read per query.
+const reconcilePage = 1000
+
+// ReconcileReport lists what Reconcile found (and fixed, if asked to).
+type ReconcileReport struct {
+	Rows    int
+	Vectors int
+
+	RowsWithoutVector []uuid.UUID
+	VectorsWithoutRow []uuid.UUID
+	Unrecorded        int // vector present, row not marked indexed
+}
+
+// Reconcile compares the metadata rows with the vector store. With fix it
+// marks rows without a vector as failed and deletes vectors without a row.
+func (s *ImageService) Reconcile(ctx context.Context, fix bool) (*ReconcileReport, error) {
+	report := &ReconcileReport{}
+
+	////////////////////////////////////////////////////////////
+	// 1️⃣ Collect every vector ID. Vectors are written after their
+	//    row commits, so one seen here without a row is an orphan.
+	////////////////////////////////////////////////////////////
+
+	vectors := make(map[uuid.UUID]bool)
+	err := s.vectorDB.Scroll(ctx, func(id uuid.UUID, _ fingerprint.Vector, _ fingerprint.Version) error {
+		vectors[id] = true
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	report.Vectors = len(vectors)
+
+	////////////////////////////////////////////////////////////
+	// 2️⃣ Page through the rows
+	////////////////////////////////////////////////////////////
+
+	var after int64
+	for {
+		rows, err := s.repo.ListIndexRows(ctx, after, reconcilePage)
+		if err != nil {
+			return nil, err
+		}
+		if len(rows) == 0 {
+			break
+		}
+		after = rows[len(rows)-1].SerialID
+
+		for _, r := range rows {
+			report.Rows++
+			switch {
+			case vectors[r.ID]:
+				delete(vectors, r.ID)
+				if !r.Indexed && !r.Pending {
+					report.Unrecorded++
+				}
+			case r.Pending || r.Failed:
+				// still to be written, or already given up on
+			default:
+				// The outbox may have written the vector since the scroll.
+				vec, _, err := s.vectorDB.Get(ctx, r.ID)
+				if err != nil {
+					return nil, err
+				}
+				if vec == nil {
+					report.RowsWithoutVector = append(report.RowsWithoutVector, r.ID)
+				}
+			}
+		}
+	}
+
+	for id := range vectors {
+		report.VectorsWithoutRow = append(report.VectorsWithoutRow, id)
+	}
+
+	////////////////////////////////////////////////////////////
+	// 3️⃣ Fix
+	////////////////////////////////////////////////////////////
+
+	if !fix {
+		return report, nil
+	}
+	for _, id := range report.RowsWithoutVector {
+		if err := s.repo.MarkIndexFailed(ctx, id, "no fingerprint in the vector store; the image must be watermarked again"); err != nil {
+			return nil, err
+		}
+	}
+	for _, id := range report.VectorsWithoutRow {
+		if err := s.vectorDB.Delete(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+	return report, nil
+}
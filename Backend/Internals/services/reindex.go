@@ -0,0 +1,272 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
+	"github.com/google/uuid"
+)
+
+// reindex.go — Background re-indexing of fingerprint vectors
+//
+// Every image_metadata row records whether its fingerprint is in the
+// vector store and at which version. The worker picks up rows that are
+// unindexed or at another version than the configured one and brings
+// them up to date from the stored vector:
+//
+//   - same version      → just record it (backfill of is_indexed)
+//   - derivable version → MigrateVector and store the result
+//   - anything else     → mark the row failed; the image has to be
+//                         uploaded again, since originals aren't kept
+//
+// Vectors written before versions were recorded are taken to be
+// ImageServiceConfig.UnversionedAs. For v1 that is safe even if they were
+// really v2/v3: deriving v3 from a v3 vector leaves it unchanged.
+
+// ReindexBatchSize is how many rows one worker pass handles.
+const ReindexBatchSize = 200
+
+// ReindexReport counts what one or more passes did.
+type ReindexReport struct {
+	Processed  int
+	Backfilled int // already at the target version, only recorded
+	Migrated   int // converted from an older version
+	Failed     int // marked failed
+}
+
+func (r *ReindexReport) add(o ReindexReport) {
+	r.Processed += o.Processed
+	r.Backfilled += o.Backfilled
+	r.Migrated += o.Migrated
+	r.Failed += o.Failed
+}
+
+// reindexState is what the admin endpoint reports about the worker.
+type reindexState struct {
+	mu        sync.Mutex
+	running   bool
+	lastRun   time.Time
+	lastError string
+	totals    ReindexReport
+}
+
+// IndexProgress is the admin view of the vector index.
+type IndexProgress struct {
+	TargetVersion int     `json:"target_version"`
+	Total         int     `json:"total"`
+	Current       int     `json:"current"`
+	Stale         int     `json:"stale"`
+	Unindexed     int     `json:"unindexed"`
+	Pending       int     `json:"pending"`
+	Failed        int     `json:"failed"`
+	Percent       float64 `json:"percent"`
+
+	Worker IndexWorkerStatus `json:"worker"`
+}
+
+type IndexWorkerStatus struct {
+	Running    bool       `json:"running"`
+	LastRun    *time.Time `json:"last_run,omitempty"`
+	LastError  string     `json:"last_error,omitempty"`
+	Processed  int        `json:"processed"`
+	Backfilled int        `json:"backfilled"`
+	Migrated   int        `json:"migrated"`
+	Failed     int        `json:"failed"`
+}
+
+// ReindexBatch brings up to limit pending rows to the configured
+// fingerprint version. Rows that can't be fixed are marked failed so they
+// don't come back; any other error stops the pass.
+func (s *ImageService) ReindexBatch(ctx context.Context, limit int) (ReindexReport, error) {
+	var report ReindexReport
+
+	ids, err := s.repo.ListPendingIndex(ctx, s.cfg.FingerprintVersion, limit)
+	if err != nil {
+		return report, err
+	}
+
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return report, err
+		}
+		outcome, err := s.reindexOne(ctx, id)
+		if err != nil {
+			return report, fmt.Errorf("reindex %s: %w", id, err)
+		}
+		report.Processed++
+		switch outcome {
+		case "backfilled":
+			report.Backfilled++
+		case "migrated":
+			report.Migrated++
+		case "failed":
+			report.Failed++
+		}
+	}
+	return report, nil
+}
+
+// reindexOne updates a single row and says what it did.
+func (s *ImageService) reindexOne(ctx context.Context, id uuid.UUID) (string, error) {
+	target := s.cfg.FingerprintVersion
+
+	vec, v, err := s.vectorDB.Get(ctx, id)
+	if err != nil {
+		return "", err
+	}
+	if vec == nil {
+		return "failed", s.repo.MarkIndexFailed(ctx, id, "no fingerprint in the vector store; the image must be watermarked again")
+	}
+
+	outcome := "migrated"
+	if v == 0 {
+		v = s.cfg.UnversionedAs
+	}
+	if v == target {
+		outcome = "backfilled"
+	}
+
+	out, err := fingerprint.MigrateVector(vec, v, target)
+	if errors.Is(err, fingerprint.ErrNotDerivable) {
+		reason := fmt.Sprintf("fingerprint v%d cannot be converted to v%d; the image must be watermarked again", v, target)
+		return "failed", s.repo.MarkIndexFailed(ctx, id, reason)
+	}
+	if err != nil {
+		return "", err
+	}
+
+	// Storing again also records the version on vectors that lack it.
+	if err := s.vectorDB.Store(ctx, id, out, target); err != nil {
+		return "", err
+	}
+	return outcome, s.repo.MarkIndexed(ctx, id, target)
+}
+
+// MigrateIndex brings every pending row to the configured fingerprint
+// version. The server runs it before taking requests, so a query vector is
+// never compared with stored vectors of another version. If any pending
+// row is recorded at a version the target can't be derived from, it fails
+// without touching anything rather than marking those images failed.
+func (s *ImageService) MigrateIndex(ctx context.Context) (ReindexReport, error) {
+	var total ReindexReport
+
+	target := s.cfg.FingerprintVersion
+	counts, err := s.repo.CountPendingIndex(ctx, target)
+	if err != nil {
+		return total, err
+	}
+
+	behind := 0
+	for v, n := range counts {
+		behind += n
+		if v == 0 {
+			v = s.cfg.UnversionedAs
+		}
+		if !fingerprint.Derivable(v, target) {
+			return total, fmt.Errorf("%d images are indexed at fingerprint v%d, which cannot be converted to v%d", n, v, target)
+		}
+	}
+	if behind == 0 {
+		return total, nil
+	}
+
+	fmt.Printf("Bringing %d images to fingerprint v%d before serving\n", behind, target)
+	for {
+		report, err := s.reindexPass(ctx)
+		total.add(report)
+		if err != nil {
+			return total, err
+		}
+		if report.Processed < ReindexBatchSize {
+			return total, nil
+		}
+	}
+}
+
+// RunReindexer runs ReindexBatch until nothing is pending, then again
+// every interval, until ctx is cancelled.
+func (s *ImageService) RunReindexer(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		for {
+			report, err := s.reindexPass(ctx)
+			if err != nil || report.Processed < ReindexBatchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// reindexPass runs one batch and records it for IndexProgress.
+func (s *ImageService) reindexPass(ctx context.Context) (ReindexReport, error) {
+	s.reindex.mu.Lock()
+	s.reindex.running = true
+	s.reindex.mu.Unlock()
+
+	report, err := s.ReindexBatch(ctx, ReindexBatchSize)
+
+	s.reindex.mu.Lock()
+	defer s.reindex.mu.Unlock()
+
+	s.reindex.running = false
+	s.reindex.lastRun = time.Now().UTC()
+	s.reindex.totals.add(report)
+	s.reindex.lastError = ""
+	if err != nil {
+		s.reindex.lastError = err.Error()
+		fmt.Println("Re-index pass failed:", err)
+	}
+	return report, err
+}
+
+// IndexProgress reports how much of the metadata is indexed at the
+// configured fingerprint version, and what the worker has done so far.
+func (s *ImageService) IndexProgress(ctx context.Context) (*IndexProgress, error) {
+	stats, err := s.repo.GetIndexStats(ctx, s.cfg.FingerprintVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &IndexProgress{
+		TargetVersion: int(s.cfg.FingerprintVersion),
+		Total:         stats.Total,
+		Current:       stats.Current,
+		Stale:         stats.Stale,
+		Unindexed:     stats.Unindexed,
+		Pending:       stats.Pending,
+		Failed:        stats.Failed,
+		Percent:       100,
+	}
+	if stats.Total > 0 {
+		p.Percent = 100 * float64(stats.Current) / float64(stats.Total)
+	}
+
+	s.reindex.mu.Lock()
+	defer s.reindex.mu.Unlock()
+
+	p.Worker = IndexWorkerStatus{
+		Running:    s.reindex.running,
+		LastError:  s.reindex.lastError,
+		Processed:  s.reindex.totals.Processed,
+		Backfilled: s.reindex.totals.Backfilled,
+		Migrated:   s.reindex.totals.Migrated,
+		Failed:     s.reindex.totals.Failed,
+	}
+	if !s.reindex.lastRun.IsZero() {
+		lastRun := s.reindex.lastRun
+		p.Worker.LastRun = &lastRun
+	}
+	return p, nil
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/models"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
+	"github.com/google/uuid"
+)
+
+// MaxSimilarLimit caps the page size of the similarity endpoints.
+const MaxSimilarLimit = 100
+
+// SimilarRequest pages through the neighbours of a query, best first.
+type SimilarRequest struct {
+	Limit    int
+	Offset   int
+	MinScore float32 // zero: no limit
+
+	// Filter keeps only images with matching attributes; nil keeps all.
+	Filter *fingerprint.Filter
+}
+
+// Neighbour is one similar image.
+type Neighbour struct {
+	ImageID  uuid.UUID
+	Score    float32
+	Verdict  fingerprint.Verdict
+	Metadata *models.ImageMetadata
+}
+
+// SimilarPage is one page of neighbours. NextOffset is set when there may
+// be more.
+type SimilarPage struct {
+	Limit      int
+	Offset     int
+	NextOffset *int
+	Neighbours []Neighbour
+}
+
+// SimilarToImage returns the neighbours of an image already in the
+// catalogue, leaving the image itself out.
+func (s *ImageService) SimilarToImage(ctx context.Context, id uuid.UUID, req SimilarRequest) (*SimilarPage, error) {
+	meta, err := s.repo.GetImageMetadata(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if meta == nil {
+		return nil, errors.New("image not found")
+	}
+
+	vec, _, err := s.vectorDB.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if vec == nil {
+		return nil, errors.New("no fingerprint stored for image")
+	}
+
+	return s.similarPage(ctx, vec, req, id)
+}
+
+// SimilarToFingerprint returns the neighbours of a fingerprint in the
+// format of the X-Fingerprint header: the configured version's vector.
+func (s *ImageService) SimilarToFingerprint(ctx context.Context, vec fingerprint.Vector, req SimilarRequest) (*SimilarPage, error) {
+	if len(vec) != fingerprint.Dimension {
+		return nil, errors.New("fingerprint has the wrong dimension")
+	}
+	return s.similarPage(ctx, vec, req)
+}
+
+// SimilarToImageContent fingerprints img and returns its neighbours.
+func (s *ImageService) SimilarToImageContent(ctx context.Context, img image.Image, req SimilarRequest) (*SimilarPage, error) {
+	vec, err := fingerprint.CreateFingerprintVersion(img, s.cfg.FingerprintVersion)
+	if err != nil {
+		return nil, err
+	}
+	return s.similarPage(ctx, vec, req)
+}
+
+// similarPage runs the paged query and attaches metadata and verdicts.
+func (s *ImageService) similarPage(ctx context.Context, vec fingerprint.Vector, req SimilarRequest, exclude ...uuid.UUID) (*SimilarPage, error) {
+	if req.Limit < 1 || req.Limit > MaxSimilarLimit || req.Offset < 0 {
+		return nil, fmt.Errorf("limit must be 1..%d and offset non-negative", MaxSimilarLimit)
+	}
+
+	// One extra hit tells whether there is a next page.
+	ids, scores, err := s.vectorDB.QueryNamed(ctx, fingerprint.VectorStructure, vec, req.Limit+1, fingerprint.QueryOptions{
+		MinScore: req.MinScore,
+		Offset:   req.Offset,
+		Exclude:  exclude,
+		Filter:   req.Filter,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	page := &SimilarPage{Limit: req.Limit, Offset: req.Offset, Neighbours: []Neighbour{}}
+	if len(ids) > req.Limit {
+		ids, scores = ids[:req.Limit], scores[:req.Limit]
+		next := req.Offset + req.Limit
+		page.NextOffset = &next
+	}
+
+	metaMap, err := s.repo.GetImageMetadataBatch(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// Vectors without a row (see Reconcile) are left out; the page is
+	// then shorter than Limit.
+	for i, id := range ids {
+		m, ok := metaMap[id]
+		if !ok {
+			continue
+		}
+		page.Neighbours = append(page.Neighbours, Neighbour{
+			ImageID:  id,
+			Score:    scores[i],
+			Verdict:  s.cfg.Thresholds.Classify(scores[i]),
+			Metadata: m,
+		})
+	}
+	return page, nil
+}
@@ -0,0 +1,201 @@
+package engine
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"math/rand"
+	"os"
+	"runtime"
+	"testing"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/payload"
+)
+
+// benchImage returns a deterministic, photo-like RGBA image.
+func benchImage(w, h int) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	r := rand.New(rand.NewSource(1))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := 120 + 50*math.Sin(float64(x)/23) + 35*math.Cos(float64(y)/17) + float64(r.Intn(10))
+			img.SetRGBA(x, y, color.RGBA{uint8(v), uint8(v*0.9 + 8), uint8(v*0.8 + 20), 255})
+		}
+	}
+	return img
+}
+
+func benchPayload(b *testing.B) []int {
+	bits, err := payload.PayloadGenerate(payload.PayloadFields{Version: 1, MetadataID: 42})
+	if err != nil {
+		b.Fatal(err)
+	}
+	return bits
+}
+
+// quiet silences the engine's progress output for the rest of the test.
+func quiet(tb testing.TB) {
+	null, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = null
+	tb.Cleanup(func() {
+		os.Stdout = stdout
+		null.Close()
+	})
+}
+
+// workerCounts compares the serial pipeline with the full worker pool.
+func workerCounts() []int {
+	if n := runtime.GOMAXPROCS(0); n > 1 {
+		return []int{1, n}
+	}
+	return []int{1}
+}
+
+func BenchmarkEmbedWatermark(b *testing.B) {
+	img := benchImage(2048, 1536)
+	bits := benchPayload(b)
+	c := LayerConstants(0)
+	quiet(b)
+
+	for _, workers := range workerCounts() {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			opts := Options{Workers: workers}
+			b.SetBytes(int64(img.Rect.Dx() * img.Rect.Dy()))
+			for i := 0; i < b.N; i++ {
+				if _, ok := EmbedWatermarkWithOptions(img, bits, c, opts); !ok {
+					b.Fatal("embed failed")
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkExtractTiles(b *testing.B) {
+	bits := benchPayload(b)
+	c := LayerConstants(0)
+	quiet(b)
+
+	marked, ok := EmbedWatermark(benchImage(2048, 1536), bits, c)
+	if !ok {
+		b.Fatal("embed failed")
+	}
+
+	for _, workers := range workerCounts() {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			opts := Options{Workers: workers}
+			b.SetBytes(int64(marked.Rect.Dx() * marked.Rect.Dy()))
+			for i := 0; i < b.N; i++ {
+				if _, ok := ExtractTiles(NewTileSource(marked, opts), c); !ok {
+					b.Fatal("extract failed")
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkIdentifyUnmarked(b *testing.B) {
+	img := benchImage(2048, 1536)
+	c := LayerConstants(0)
+
+	for _, workers := range workerCounts() {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			opts := Options{Workers: workers}
+			for i := 0; i < b.N; i++ {
+				if _, _, found := IdentifyTiles(NewTileSource(img, opts), c); found {
+					b.Fatal("unmarked image identified as watermarked")
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkPerformCompleteDWT(b *testing.B) {
+	block := make([][]float64, 16)
+	for i := range block {
+		block[i] = make([]float64, 16)
+		for j := range block[i] {
+			block[i][j] = float64(i*16 + j)
+		}
+	}
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		d := PerformCompleteDWT(block)
+		PerformCompleteIDWT(d.LL, d.LH, d.HL, d.HH)
+	}
+}
+
+// benchInputs re-encodes the benchmark image as the types decoders return.
+func benchInputs(w, h int) []struct {
+	name string
+	img  image.Image
+} {
+	src := benchImage(w, h)
+	nrgba := image.NewNRGBA(src.Rect)
+	gray := image.NewGray(src.Rect)
+	rgba64 := image.NewRGBA64(src.Rect)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := src.At(x, y)
+			nrgba.Set(x, y, c)
+			gray.Set(x, y, c)
+			rgba64.Set(x, y, c)
+		}
+	}
+	return []struct {
+		name string
+		img  image.Image
+	}{
+		{"rgba", src},
+		{"nrgba", nrgba},
+		{"gray", gray},
+		{"ycbcr", ToYCbCr(src)},
+		{"generic", rgba64},
+	}
+}
+
+func BenchmarkToYCbCr(b *testing.B) {
+	for _, in := range benchInputs(2048, 1536) {
+		b.Run(in.name, func(b *testing.B) {
+			b.SetBytes(int64(in.img.Bounds().Dx() * in.img.Bounds().Dy()))
+			for i := 0; i < b.N; i++ {
+				ToYCbCr(in.img)
+			}
+		})
+	}
+}
+
+func BenchmarkConvertToYC(b *testing.B) {
+	for _, in := range benchInputs(2048, 1536) {
+		b.Run(in.name, func(b *testing.B) {
+			b.SetBytes(int64(in.img.Bounds().Dx() * in.img.Bounds().Dy()))
+			for i := 0; i < b.N; i++ {
+				ConvertToYC(in.img)
+			}
+		})
+	}
+}
+
+// BenchmarkEmbedPipeline runs the whole embed path (conversion, tiling,
+// DWT/DCT, write-back) for each input type.
+func BenchmarkEmbedPipeline(b *testing.B) {
+	bits := benchPayload(b)
+	c := LayerConstants(0)
+	quiet(b)
+
+	for _, in := range benchInputs(2048, 1536) {
+		b.Run(in.name, func(b *testing.B) {
+			b.SetBytes(int64(in.img.Bounds().Dx() * in.img.Bounds().Dy()))
+			for i := 0; i < b.N; i++ {
+				if _, ok := EmbedWatermark(in.img, bits, c); !ok {
+					b.Fatal("embed failed")
+				}
+			}
+		})
+	}
+}
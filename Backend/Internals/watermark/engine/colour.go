@@ -0,0 +1,74 @@
+package engine
+
+import "image"
+
+// ycbcrRow converts pixels [x0, x0+len(Y)) of row y to full-precision Y, Cb
+// and Cr. Cb and Cr may be nil when only luminance is needed.
+//
+// img.At(x, y).RGBA() boxes a colour value for every pixel, so the common
+// concrete image types read their Pix slices directly. The results match
+// the generic path bit for bit, except for *image.YCbCr whose own planes
+// are used as-is rather than round-tripping through RGB; for in-gamut
+// colours the two agree to within one level.
+func ycbcrRow(img image.Image, x0, y int, Y, Cb, Cr []float64) {
+	switch src := img.(type) {
+	case *image.YCbCr:
+		yo := src.YOffset(x0, y)
+		for i := range Y {
+			Y[i] = float64(src.Y[yo+i])
+			if Cb != nil {
+				co := src.COffset(x0+i, y)
+				Cb[i] = float64(src.Cb[co])
+				Cr[i] = float64(src.Cr[co])
+			}
+		}
+
+	case *image.RGBA:
+		o := src.PixOffset(x0, y)
+		for i := range Y {
+			p := src.Pix[o+4*i : o+4*i+3 : o+4*i+3]
+			storeYCbCr(Y, Cb, Cr, i, float64(p[0]), float64(p[1]), float64(p[2]))
+		}
+
+	case *image.NRGBA:
+		o := src.PixOffset(x0, y)
+		for i := range Y {
+			p := src.Pix[o+4*i : o+4*i+4 : o+4*i+4]
+			a := uint32(p[3])
+			storeYCbCr(Y, Cb, Cr, i,
+				float64(premultiply(p[0], a)),
+				float64(premultiply(p[1], a)),
+				float64(premultiply(p[2], a)))
+		}
+
+	case *image.Gray:
+		o := src.PixOffset(x0, y)
+		for i := range Y {
+			v := float64(src.Pix[o+i])
+			storeYCbCr(Y, Cb, Cr, i, v, v, v)
+		}
+
+	default:
+		for i := range Y {
+			r, g, b, _ := img.At(x0+i, y).RGBA()
+			storeYCbCr(Y, Cb, Cr, i, float64(r>>8), float64(g>>8), float64(b>>8))
+		}
+	}
+}
+
+// premultiply reproduces color.NRGBA.RGBA() >> 8 for one channel.
+func premultiply(c uint8, a uint32) uint32 {
+	v := uint32(c)
+	v |= v << 8
+	v *= a
+	v /= 0xff
+	return v >> 8
+}
+
+func storeYCbCr(Y, Cb, Cr []float64, i int, rr, gg, bb float64) {
+	Y[i] = 0.299*rr + 0.587*gg + 0.114*bb
+	if Cb != nil {
+		Cb[i] = -0.1687*rr - 0.3313*gg + 0.5*bb + 128
+		Cr[i] = 0.5*rr - 0.4187*gg - 0.0813*bb + 128
+	}
+}
@@ -0,0 +1,102 @@
+package engine
+
+import (
+	"image"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// generic hides the concrete type of an image so ycbcrRow takes its
+// At().RGBA() path.
+type generic struct{ image.Image }
+
+// colourInputs returns the types with a fast path, filled with random
+// pixels (any alpha for NRGBA) and cut to a sub-image so row offsets and
+// strides are exercised.
+func colourInputs() map[string]image.Image {
+	r := rand.New(rand.NewSource(5))
+	rect := image.Rect(0, 0, 67, 41)
+	sub := image.Rect(3, 5, 61, 40)
+
+	rgba := image.NewRGBA(rect)
+	for i := 0; i < len(rgba.Pix); i += 4 {
+		a := uint8(r.Intn(256))
+		rgba.Pix[i+3] = a
+		for c := 0; c < 3; c++ {
+			rgba.Pix[i+c] = uint8(r.Intn(int(a) + 1)) // premultiplied
+		}
+	}
+	nrgba := image.NewNRGBA(rect)
+	r.Read(nrgba.Pix)
+	gray := image.NewGray(rect)
+	r.Read(gray.Pix)
+
+	inputs := map[string]image.Image{
+		"rgba":  rgba.SubImage(sub),
+		"nrgba": nrgba.SubImage(sub),
+		"gray":  gray.SubImage(sub),
+	}
+	for _, ratio := range []image.YCbCrSubsampleRatio{image.YCbCrSubsampleRatio444, image.YCbCrSubsampleRatio420} {
+		// Planes stay inside the RGB gamut, so the generic path's round
+		// trip through RGB is lossless up to rounding.
+		ycb := image.NewYCbCr(rect, ratio)
+		for i := range ycb.Y {
+			ycb.Y[i] = uint8(60 + r.Intn(136))
+		}
+		for i := range ycb.Cb {
+			ycb.Cb[i] = uint8(98 + r.Intn(61))
+			ycb.Cr[i] = uint8(98 + r.Intn(61))
+		}
+		inputs["ycbcr "+ratio.String()] = ycb.SubImage(sub)
+	}
+	return inputs
+}
+
+func TestColourFastPathsMatchGeneric(t *testing.T) {
+	for name, img := range colourInputs() {
+		t.Run(name, func(t *testing.T) {
+			b := img.Bounds()
+			n := b.Dx()
+			Y, Cb, Cr := make([]float64, n), make([]float64, n), make([]float64, n)
+			gY, gCb, gCr := make([]float64, n), make([]float64, n), make([]float64, n)
+			_, isYCbCr := img.(*image.YCbCr)
+
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				ycbcrRow(img, b.Min.X, y, Y, Cb, Cr)
+				ycbcrRow(generic{img}, b.Min.X, y, gY, gCb, gCr)
+
+				for i := range Y {
+					if !isYCbCr {
+						if Y[i] != gY[i] || Cb[i] != gCb[i] || Cr[i] != gCr[i] {
+							t.Fatalf("(%d,%d): fast %v/%v/%v, generic %v/%v/%v",
+								b.Min.X+i, y, Y[i], Cb[i], Cr[i], gY[i], gCb[i], gCr[i])
+						}
+						continue
+					}
+
+					// YCbCr reads its own planes, which the generic path
+					// only reaches through a rounded RGB round trip.
+					src := img.(*image.YCbCr)
+					c := src.YCbCrAt(b.Min.X+i, y)
+					if Y[i] != float64(c.Y) || Cb[i] != float64(c.Cb) || Cr[i] != float64(c.Cr) {
+						t.Fatalf("(%d,%d): fast %v/%v/%v, planes %v", b.Min.X+i, y, Y[i], Cb[i], Cr[i], c)
+					}
+					if d := math.Abs(Y[i] - gY[i]); d > 1 {
+						t.Fatalf("(%d,%d): Y %v differs from generic %v by %v", b.Min.X+i, y, Y[i], gY[i], d)
+					}
+				}
+			}
+
+			// The luminance-only form (nil Cb and Cr) gives the same Y.
+			ycbcrRow(img, b.Min.X, b.Min.Y, Y, nil, nil)
+			ycbcrRow(generic{img}, b.Min.X, b.Min.Y, gY, nil, nil)
+			for i := range Y {
+				if !isYCbCr && Y[i] != gY[i] {
+					t.Fatalf("luminance-only row differs at %d: %v vs %v", i, Y[i], gY[i])
+				}
+			}
+		})
+	}
+}
+
@@ -3,7 +3,6 @@ package engine
 import (
 	"fmt"
 	"math"
-	"sync"
 	//"time"
 )
 
@@ -48,18 +47,12 @@ func PerformCompleteDWT(Ymatrix [][]float64) *DWTResult {
 	tempL := make([][]float64, h) // Low-pass on rows
 	tempH := make([][]float64, h) // High-pass on rows
 
-	var wg sync.WaitGroup
-
-	// Process each row in parallel
-	for i := 0; i < h; i++ {
-		wg.Add(1)
-		go func(row int) {
-			defer wg.Done()
-			tempL[row] = haarL(Ymatrix[row])
-			tempH[row] = haarH(Ymatrix[row])
-		}(i)
+	// Blocks are only 16x16 and tiles already run on the worker pool, so
+	// the rows and columns are transformed sequentially.
+	for row := 0; row < h; row++ {
+		tempL[row] = haarL(Ymatrix[row])
+		tempH[row] = haarH(Ymatrix[row])
 	}
-	wg.Wait()
 
 	// Step 2: Column-wise transform on tempL to get LL and LH
 	LL := make([][]float64, h/2)
@@ -69,29 +62,23 @@ func PerformCompleteDWT(Ymatrix [][]float64) *DWTResult {
 		LH[i] = make([]float64, w/2)
 	}
 
-	for col := 0; col < w/2; col++ {
-		wg.Add(1)
-		go func(c int) {
-			defer wg.Done()
-
-			// Extract column from tempL
-			column := make([]float64, h)
-			for row := 0; row < h; row++ {
-				column[row] = tempL[row][c]
-			}
+	column := make([]float64, h)
+	for c := 0; c < w/2; c++ {
+		// Extract column from tempL
+		for row := 0; row < h; row++ {
+			column[row] = tempL[row][c]
+		}
 
-			// Apply L and H transforms
-			columnL := haarL(column)
-			columnH := haarH(column)
+		// Apply L and H transforms
+		columnL := haarL(column)
+		columnH := haarH(column)
 
-			// Store results
-			for row := 0; row < h/2; row++ {
-				LL[row][c] = columnL[row]
-				LH[row][c] = columnH[row]
-			}
-		}(col)
+		// Store results
+		for row := 0; row < h/2; row++ {
+			LL[row][c] = columnL[row]
+			LH[row][c] = columnH[row]
+		}
 	}
-	wg.Wait()
 
 	// Step 3: Column-wise transform on tempH to get HL and HH
 	HL := make([][]float64, h/2)
@@ -101,29 +88,22 @@ func PerformCompleteDWT(Ymatrix [][]float64) *DWTResult {
 		HH[i] = make([]float64, w/2)
 	}
 
-	for col := 0; col < w/2; col++ {
-		wg.Add(1)
-		go func(c int) {
-			defer wg.Done()
-
-			// Extract column from tempH
-			column := make([]float64, h)
-			for row := 0; row < h; row++ {
-				column[row] = tempH[row][c]
-			}
+	for c := 0; c < w/2; c++ {
+		// Extract column from tempH
+		for row := 0; row < h; row++ {
+			column[row] = tempH[row][c]
+		}
 
-			// Apply L and H transforms
-			columnL := haarL(column)
-			columnH := haarH(column)
+		// Apply L and H transforms
+		columnL := haarL(column)
+		columnH := haarH(column)
 
-			// Store results
-			for row := 0; row < h/2; row++ {
-				HL[row][c] = columnL[row]
-				HH[row][c] = columnH[row]
-			}
-		}(col)
+		// Store results
+		for row := 0; row < h/2; row++ {
+			HL[row][c] = columnL[row]
+			HH[row][c] = columnH[row]
+		}
 	}
-	wg.Wait()
 
 	//t2 := time.Now()
 	//fmt.Printf("DWT completed in %v\n", t2.Sub(t1))
@@ -3,7 +3,6 @@ package engine
 import (
 	"fmt"
 	"image"
-	"math"
 )
 
 func GetBlock(matrix [][]float64, x, y, B int) [][]float64 {
@@ -22,68 +21,74 @@ func PutBlock(matrix [][]float64, block [][]float64, x, y int) {
 }
 
 func EmbedWatermark(img image.Image, payload []int, c []Constants) (*image.YCbCr, bool) {
-	stream := payload
+	return EmbedWatermarkWithOptions(img, payload, c, Options{})
+}
 
-	//fmt.Printf("payload: \"%s\" -> %d bits (including flags)\n", payload, len(stream))
+// EmbedWatermarkWithOptions converts img to YCbCr once and embeds payload
+// tile by tile, so no full-size float matrix is ever allocated. It refuses
+// images that already carry this watermark.
+func EmbedWatermarkWithOptions(img image.Image, payload []int, c []Constants, opts Options) (*image.YCbCr, bool) {
+	ycb := ToYCbCr(img)
+	src := NewTileSource(ycb, opts)
 
-	ycb, Ymatrix := ConvertToYC(img)
+	fmt.Printf("Image converted to YCbCr, size: %dx%d\n", ycb.Rect.Dx(), ycb.Rect.Dy())
 
-	fmt.Printf("Image converted to YCbCr, Y matrix size: %dx%d\n", len(Ymatrix[0]), len(Ymatrix))
-	_, _, flag := Identify(img, c)
-	if flag {
+	if _, _, found := IdentifyTiles(src, c); found {
 		return ycb, false
 	}
-	h := len(Ymatrix)
-	w := len(Ymatrix[0])
+	return ycb, EmbedTiles(src, payload, c)
+}
+
+// EmbedTiles embeds payload into every tile of src and writes each marked
+// tile straight back into the Y plane of the *image.YCbCr src was built
+// from (see ToYCbCr). It does not look for an existing mark: callers check
+// with IdentifyTiles on the same source first, whose cached tiles are then
+// reused here instead of being converted again.
+func EmbedTiles(src *TileSource, payload []int, c []Constants) bool {
+	stream := payload
+
+	ycb, ok := src.Image().(*image.YCbCr)
+	if !ok {
+		return false
+	}
 
 	// Calculate number of tiles
-	numTilesY := int(math.Floor(float64(h) / 256))
-	numTilesX := int(math.Floor(float64(w) / 256))
+	numTilesY := src.TilesY
+	numTilesX := src.TilesX
 
 	fmt.Printf("Processing %d x %d = %d tiles\n", numTilesY, numTilesX, numTilesY*numTilesX)
 
 	// Calculate capacity per tile
-	// Each tile is 256x256, divided into 16x16 blocks = 8x8 = 64 blocks
-	// First row (8 blocks) + first column (7 blocks, excluding corner) = 15 blocks for verification
-	// Remaining: 64 - 15 = 49 blocks for data
-	blocksPerTile := 49
+	// Each tile is 256x256, divided into 16x16 blocks = 16x16 = 256 blocks
+	// First row (16 blocks) + first column (15 blocks, excluding corner) = 31 blocks for verification
+	// Remaining: 225 blocks for data, 2 bits each
+	blocksPerTile := 225
 	bitsPerTile := blocksPerTile * 2
 
 	fmt.Printf("Capacity per tile: %d bits\n", bitsPerTile)
 	fmt.Printf("Total capacity: %d bits\n", bitsPerTile*numTilesY*numTilesX)
 
-	// if len(stream) > bitsPerTile {
-	// 	fmt.Printf("⚠ Warning: payload (%d bits) exceeds single tile capacity (%d bits)\n",
-	// 		len(stream), bitsPerTile)
-	// 	fmt.Printf("   payload will be replicated across multiple tiles\n")
-	// }
-
-	// Process tiles
-	tileCount := 0
-	for i := 0; i < numTilesY; i++ {
-		for j := 0; j < numTilesX; j++ {
-			tileCount++
-
-			// Get the tile from the Y matrix (spatial domain, not DWT yet)
-			tile := GetBlock(Ymatrix, j*256, i*256, 256)
+	// Process tiles on the worker pool; each tile only touches its own
+	// region of the Y plane, so they can be written back concurrently.
+	forEach(numTilesY*numTilesX, src.workers, func(n int) {
+		i := n / numTilesX
+		j := n % numTilesX
 
-			// Embed watermark in this tile
-			// DWT will be performed inside EmbedinaTile on 16x16 blocks
-			modifiedTile := EmbedinaTile(tile, stream, c)
+		// Get the tile luminance (spatial domain, not DWT yet)
+		tile := src.Tile(j, i)
 
-			// Put the modified tile back
-			PutBlock(Ymatrix, modifiedTile, j*256, i*256)
-
-			fmt.Printf("✓ Tile [%d,%d] (tile #%d): Watermark embedded\n", i, j, tileCount)
-		}
-	}
+		// Embed watermark in this tile
+		// DWT will be performed inside EmbedinaTile on 16x16 blocks
+		modifiedTile := EmbedinaTile(tile, stream, c)
 
-	// Update the Y component with the modified matrix
-	Modify_YComponent(ycb, Ymatrix)
+		// Write the modified tile back into the Y plane
+		writeTile(ycb, j, i, modifiedTile)
+		src.drop(j, i)
 
-	
+		fmt.Printf("✓ Tile [%d,%d] (tile #%d): Watermark embedded\n", i, j, n+1)
+	})
 
 	fmt.Println("Watermark embedding completed successfully")
 
-	return ycb, true
+	return true
 }
@@ -0,0 +1,27 @@
+package engine
+
+import "testing"
+
+func TestEmbedTilesOnSharedSource(t *testing.T) {
+	quiet(t)
+
+	// The service's flow: one conversion, identify, then embed into the
+	// same source.
+	ycb := ToYCbCr(benchImage(512, 512))
+	src := NewTileSource(ycb, Options{TileCacheBytes: 2 * tileBytes})
+	if _, _, found := IdentifyTiles(src, LayerConstants(0)); found {
+		t.Fatal("unmarked image identified")
+	}
+	if !EmbedTiles(src, testPayload(t, 9), LayerConstants(0)) {
+		t.Fatal("EmbedTiles failed")
+	}
+
+	if id := readID(t, ycb, 0); id != 9 {
+		t.Fatalf("marked image carries %d, want 9", id)
+	}
+
+	// Only a YCbCr source can be written back to.
+	if EmbedTiles(NewTileSource(benchImage(256, 256), Options{}), testPayload(t, 9), LayerConstants(0)) {
+		t.Fatal("EmbedTiles accepted a source it cannot write to")
+	}
+}
@@ -71,48 +71,45 @@ import (
 // }
 
 func ExtractWatermark(img image.Image, c []Constants) ([][]int, bool) {
-	// Convert image to YCbCr and get Y matrix
-	_, Ymatrix := ConvertToYC(img)
+	return ExtractTiles(NewTileSource(img, Options{}), c)
+}
 
+// ExtractTiles reads the payload bits of every marked tile of src, one tile
+// at a time. Pass the same source used for IdentifyTiles to avoid
+// converting the image again.
+func ExtractTiles(src *TileSource, c []Constants) ([][]int, bool) {
 	fmt.Println("Extraction started")
-	fmt.Printf("Image Y matrix dimensions: %dx%d\n", len(Ymatrix[0]), len(Ymatrix))
+	fmt.Printf("Image tile grid: %dx%d\n", src.TilesX, src.TilesY)
 
-	x_index, y_index, _ := Identify(img, c)
+	x_index, y_index, flag := IdentifyTiles(src, c)
 
 	fmt.Println("X_index : ", x_index, "Y_index : ", y_index)
 
-	h := len(Ymatrix)
-	w := len(Ymatrix[0])
+	if !flag {
+		return nil, false
+	}
 
 	//var messages []string
-	tileCount := 0
 	// validTileCount := 0
 
-	// if !flag {
-	// 	return messages, false
-	// }
+	// Process each 256x256 tile from the first marked one onwards
+	rows := src.TilesY - x_index
+	cols := src.TilesX - y_index
 
-	// Process each 256x256 tile
-	numTilesY := h / 256
-	numTilesX := w / 256
-
-	fmt.Printf("Processing %d x %d = %d tiles\n", numTilesY, numTilesX, numTilesY*numTilesX)
+	fmt.Printf("Processing %d x %d = %d tiles\n", rows, cols, rows*cols)
 	fmt.Println("----------------------------------------")
-	extractedBits := make([][]int, numTilesX*numTilesY)
-	for i := x_index; i < numTilesY; i++ {
-		for j := y_index; j < numTilesX; j++ {
-
-			// Get the tile from the Y matrix (not DWT transformed)
-			tile := GetBlock(Ymatrix, j*256, i*256, 256)
-
-			// Extract bits from this tile (DWT happens inside ExtractfromaTile)
-			extractedBits[tileCount] = ExtractfromaTile(tile, c)
-
-			fmt.Println("Length of one payload : ", len(extractedBits[tileCount]))
-			
-			tileCount++
-		}
-	}
+	extractedBits := make([][]int, rows*cols)
+	forEach(rows*cols, src.workers, func(n int) {
+		i := x_index + n/cols
+		j := y_index + n%cols
+
+		// Get the tile luminance (not DWT transformed)
+		tile := src.Tile(j, i)
+
+		// Extract bits from this tile (DWT happens inside ExtractfromaTile)
+		extractedBits[n] = ExtractfromaTile(tile, c)
+	})
+	fmt.Println("Length of one payload : ", len(extractedBits[0]))
 
 	// fmt.Println("----------------------------------------")
 	// fmt.Printf("Summary: %d/%d tiles verified successfully (%.1f%%)\n",
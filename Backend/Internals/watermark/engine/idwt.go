@@ -4,7 +4,6 @@ package engine
 import (
 	"fmt"
 	"math"
-	"sync"
 	//"time"
 )
 
@@ -16,11 +15,11 @@ func invHaarCombine(low []float64, high []float64) []float64 {
 
 	for i := 0; i < n; i++ {
 		// Haar forward: L = (x + y)/√2, H = (x - y)/√2
-		// Inverse: x = (L + H), y = (L - H)
-		// Since L and H are already divided by √2, we need:
-		// x = L + H, y = L - H
-		output[2*i] = low[i] + high[i]
-		output[2*i+1] = low[i] - high[i]
+		// Inverse: x = (L + H)/√2, y = (L - H)/√2
+		// Without the √2 every IDWT doubled the block, which only looked
+		// right because Modify_YComponent rescaled the whole image back.
+		output[2*i] = (low[i] + high[i]) / math.Sqrt2
+		output[2*i+1] = (low[i] - high[i]) / math.Sqrt2
 	}
 	return output
 }
@@ -39,8 +38,6 @@ func PerformCompleteIDWT(LL, LH, HL, HH [][]float64) [][]float64 {
 		panic("All DWT components must have the same dimensions")
 	}
 
-	var wg sync.WaitGroup
-
 	// Step 1: Inverse column-wise transform
 	// Combine LL with LH to get tempL (low-pass rows)
 	tempL := make([][]float64, h*2)
@@ -54,62 +51,42 @@ func PerformCompleteIDWT(LL, LH, HL, HH [][]float64) [][]float64 {
 		tempH[i] = make([]float64, w)
 	}
 
-	// Process each column in parallel
-	for col := 0; col < w; col++ {
-		wg.Add(1)
-		go func(c int) {
-			defer wg.Done()
-
-			// Extract columns from LL and LH
-			lowColumn := make([]float64, h)
-			highColumn := make([]float64, h)
-			for row := 0; row < h; row++ {
-				lowColumn[row] = LL[row][c]
-				highColumn[row] = LH[row][c]
-			}
+	// Process each column in turn
+	lowColumn := make([]float64, h)
+	highColumn := make([]float64, h)
+	for c := 0; c < w; c++ {
+		// Extract columns from LL and LH
+		for row := 0; row < h; row++ {
+			lowColumn[row] = LL[row][c]
+			highColumn[row] = LH[row][c]
+		}
 
-			// Inverse transform to get tempL column
-			reconstructedL := invHaarCombine(lowColumn, highColumn)
-			for row := 0; row < h*2; row++ {
-				tempL[row][c] = reconstructedL[row]
-			}
+		// Inverse transform to get tempL column
+		reconstructedL := invHaarCombine(lowColumn, highColumn)
+		for row := 0; row < h*2; row++ {
+			tempL[row][c] = reconstructedL[row]
+		}
 
-			// Extract columns from HL and HH
-			for row := 0; row < h; row++ {
-				lowColumn[row] = HL[row][c]
-				highColumn[row] = HH[row][c]
-			}
+		// Extract columns from HL and HH
+		for row := 0; row < h; row++ {
+			lowColumn[row] = HL[row][c]
+			highColumn[row] = HH[row][c]
+		}
 
-			// Inverse transform to get tempH column
-			reconstructedH := invHaarCombine(lowColumn, highColumn)
-			for row := 0; row < h*2; row++ {
-				tempH[row][c] = reconstructedH[row]
-			}
-		}(col)
+		// Inverse transform to get tempH column
+		reconstructedH := invHaarCombine(lowColumn, highColumn)
+		for row := 0; row < h*2; row++ {
+			tempH[row][c] = reconstructedH[row]
+		}
 	}
-	wg.Wait()
 
 	// Step 2: Inverse row-wise transform
 	// Combine tempL and tempH to get final result
 	result := make([][]float64, h*2)
-	for i := range result {
-		result[i] = make([]float64, w*2)
-	}
-
-	for row := 0; row < h*2; row++ {
-		wg.Add(1)
-		go func(r int) {
-			defer wg.Done()
-
-			// Combine tempL and tempH rows
-			reconstructed := invHaarCombine(tempL[r], tempH[r])
-
-			for col := 0; col < w*2; col++ {
-				result[r][col] = reconstructed[col]
-			}
-		}(row)
+	for r := 0; r < h*2; r++ {
+		// Combine tempL and tempH rows
+		result[r] = invHaarCombine(tempL[r], tempH[r])
 	}
-	wg.Wait()
 
 	//t2 := time.Now()
 	//fmt.Printf("Inverse DWT completed in %v\n", t2.Sub(t1))
@@ -0,0 +1,35 @@
+package engine
+
+// MaxLayers is the number of independent watermarks one image can carry.
+const MaxLayers = 3
+
+// layerCoefficients lists the (u, v) DCT coefficient pairs of the HL band
+// used by each layer. The sets are disjoint, and because the DCT basis is
+// orthogonal, quantising one layer's coefficients leaves the others intact.
+// Layer 0 is the original single-owner watermark.
+var layerCoefficients = [MaxLayers][2][2]int{
+	{{2, 3}, {3, 2}},
+	{{1, 4}, {4, 1}},
+	{{3, 4}, {4, 3}},
+}
+
+// LayerConstants returns the coefficient constants that carry layer n.
+func LayerConstants(n int) []Constants {
+	pair := layerCoefficients[n]
+	return []Constants{
+		*CreateConstant(pair[0][0], pair[0][1]),
+		*CreateConstant(pair[1][0], pair[1][1]),
+	}
+}
+
+// NextFreeLayer returns the lowest layer that src does not carry yet, or -1
+// when every layer is taken. Layers are filled in order, so the scan stops
+// at the first gap.
+func NextFreeLayer(src *TileSource) int {
+	for n := 0; n < MaxLayers; n++ {
+		if _, _, found := IdentifyTiles(src, LayerConstants(n)); !found {
+			return n
+		}
+	}
+	return -1
+}
@@ -0,0 +1,133 @@
+package engine
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/payload"
+)
+
+func testPayload(t *testing.T, id uint64) []int {
+	t.Helper()
+	bits, err := payload.PayloadGenerate(payload.PayloadFields{Version: 1, MetadataID: id})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return bits
+}
+
+// readID extracts layer n of img and returns the metadata ID it carries.
+func readID(t *testing.T, img image.Image, n int) uint64 {
+	t.Helper()
+	copies, ok := ExtractWatermark(img, LayerConstants(n))
+	if !ok {
+		t.Fatalf("layer %d not identified", n)
+	}
+	fields, err := payload.PayloadVerify(copies)
+	if err != nil {
+		t.Fatalf("layer %d: %v", n, err)
+	}
+	return fields.MetadataID
+}
+
+func TestSecondLayerKeepsFirstReadable(t *testing.T) {
+	quiet(t)
+
+	base, ok := EmbedWatermark(benchImage(512, 512), testPayload(t, 41), LayerConstants(0))
+	if !ok {
+		t.Fatal("embedding layer 0 failed")
+	}
+	if n := NextFreeLayer(NewTileSource(base, Options{})); n != 1 {
+		t.Fatalf("NextFreeLayer = %d after layer 0, want 1", n)
+	}
+
+	layered, ok := EmbedWatermark(base, testPayload(t, 42), LayerConstants(1))
+	if !ok {
+		t.Fatal("embedding layer 1 failed")
+	}
+	if n := NextFreeLayer(NewTileSource(layered, Options{})); n != 2 {
+		t.Fatalf("NextFreeLayer = %d after layer 1, want 2", n)
+	}
+
+	if id := readID(t, layered, 0); id != 41 {
+		t.Fatalf("layer 0 carries %d after adding layer 1, want 41", id)
+	}
+	if id := readID(t, layered, 1); id != 42 {
+		t.Fatalf("layer 1 carries %d, want 42", id)
+	}
+
+	// The same layer cannot be embedded twice.
+	if _, ok := EmbedWatermark(layered, testPayload(t, 43), LayerConstants(1)); ok {
+		t.Fatal("layer 1 embedded over itself")
+	}
+}
+
+// legacyEmbed marks img the way the engine did before layers existed: the
+// IDWT left every block doubled and the whole Y plane was then rescaled
+// into range (only the tiled area was written back).
+func legacyEmbed(img image.Image, bits []int) *image.YCbCr {
+	ycb, Y := ConvertToYC(img)
+	for ty := 0; ty < len(Y)/TileSize; ty++ {
+		for tx := 0; tx < len(Y[0])/TileSize; tx++ {
+			tile := make([][]float64, TileSize)
+			for i := range tile {
+				tile[i] = append([]float64(nil), Y[ty*TileSize+i][tx*TileSize:(tx+1)*TileSize]...)
+			}
+			for i, row := range EmbedinaTile(tile, bits, LayerConstants(0)) {
+				for j, v := range row {
+					Y[ty*TileSize+i][tx*TileSize+j] = 2 * v
+				}
+			}
+		}
+	}
+
+	minValue, maxValue := Y[0][0], Y[0][0]
+	for _, row := range Y {
+		for _, v := range row {
+			minValue = math.Min(minValue, v)
+			maxValue = math.Max(maxValue, v)
+		}
+	}
+	if minValue >= -128 && maxValue <= 127 {
+		Modify_YComponent(ycb, Y)
+		return ycb
+	}
+	h := len(Y) / TileSize * TileSize
+	w := len(Y[0]) / TileSize * TileSize
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			value := (Y[y][x] - minValue) / (maxValue - minValue) * 255
+			ycb.Y[ycb.YOffset(x, y)] = uint8(math.Min(math.Max(value, 0), 255))
+		}
+	}
+	return ycb
+}
+
+// Images marked before the IDWT fix must still be found. The old rescale
+// only left the marks intact on images using most of the tonal range, so
+// those are the ones that were readable then and must stay readable.
+func TestLegacyMarksStillIdentified(t *testing.T) {
+	quiet(t)
+
+	img := image.NewRGBA(image.Rect(0, 0, 512, 512))
+	for y := 0; y < 512; y++ {
+		for x := 0; x < 512; x++ {
+			v := 127.5 + 118*math.Sin(float64(x)/37+float64(y)/53) + 10*math.Cos(float64(x*y)/900)
+			v = math.Min(math.Max(v, 0), 255)
+			img.SetRGBA(x, y, color.RGBA{uint8(v), uint8(v), uint8(v), 255})
+		}
+	}
+
+	marked := legacyEmbed(img, testPayload(t, 7))
+	if _, _, found := Identify(marked, LayerConstants(0)); !found {
+		t.Fatal("legacy mark not identified")
+	}
+	if id := readID(t, marked, 0); id != 7 {
+		t.Fatalf("legacy mark carries %d, want 7", id)
+	}
+	if n := NextFreeLayer(NewTileSource(marked, Options{})); n != 1 {
+		t.Fatalf("NextFreeLayer = %d on a legacy mark, want 1", n)
+	}
+}
@@ -0,0 +1,90 @@
+package engine
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+func (o Options) workers() int {
+	if o.Workers > 0 {
+		return o.Workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
+
+// forEach runs fn for every index in [0, n) on at most workers goroutines.
+func forEach(n, workers int, fn func(i int)) {
+	if workers > n {
+		workers = n
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			fn(i)
+		}
+		return
+	}
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := int(next.Add(1) - 1)
+				if i >= n {
+					return
+				}
+				fn(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// firstMatch returns the lowest index in [0, n) for which pred holds, or -1.
+// Indices are handed out in order, and once a match is found no worker
+// starts on a higher index, so little work is wasted when the match is
+// near the front (the usual case for Identify).
+func firstMatch(n, workers int, pred func(i int) bool) int {
+	var best atomic.Int64
+	best.Store(int64(n))
+
+	var next atomic.Int64
+	var wg sync.WaitGroup
+
+	if workers > n {
+		workers = n
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				i := next.Add(1) - 1
+				if i >= best.Load() {
+					return
+				}
+				if !pred(int(i)) {
+					continue
+				}
+				for {
+					cur := best.Load()
+					if i >= cur || best.CompareAndSwap(cur, i) {
+						break
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if found := int(best.Load()); found < n {
+		return found
+	}
+	return -1
+}
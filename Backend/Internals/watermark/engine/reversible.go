@@ -0,0 +1,287 @@
+package engine
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"image"
+	"image/draw"
+	"io"
+	"math/bits"
+	"math/rand/v2"
+)
+
+// ---------------------------------------------------------------------------
+// Reversible (removable) watermarking
+//
+// The robust DWT/DCT/QIM watermark permanently alters the image. Archival
+// masters instead use difference histogram shifting on pixel pairs, which
+// can be undone bit-exactly:
+//
+//	pairs     : horizontally adjacent pixels (a, b) of the R, G and B planes,
+//	            visited in an order derived from the server key
+//	d = b - a : d == 0 carries one bit (b += bit), d >= 1 is shifted (b += 1),
+//	            d < 0 is left alone
+//
+// A shifted b of 255 would overflow, so every b == 255 is first lowered to
+// 254 and a location map recording which 254s were originally 255 travels
+// inside the embedded stream. Stream layout:
+//
+//	MAGIC(16) | MAP_BYTES(32) | PAYLOAD_BITS(16) | MAP(8*MAP_BYTES) | PAYLOAD
+//
+// Restoration is bit-exact for opaque images and for *image.NRGBA sources.
+// The watermarked image must be stored losslessly (PNG).
+// ---------------------------------------------------------------------------
+
+const reversibleMagic uint16 = 0xA55A
+
+var (
+	ErrReversibleCapacity = errors.New("image has too little capacity for a reversible watermark")
+	ErrNoReversibleMark   = errors.New("no reversible watermark found in image")
+)
+
+// pairOrder returns the key-dependent visiting order of all pixel pairs.
+//
+// Archived masters can only be restored with the exact same order, so the
+// shuffle is done here rather than with rand.Perm, whose output Go does not
+// promise to keep across releases. Only the ChaCha8 stream itself is relied
+// on; it is fixed by the chacha8rand specification. The index arithmetic is
+// that of Perm on 64-bit platforms, so masters marked before keep restoring.
+func pairOrder(key []byte, n int) []int {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("reversible-watermark-pairs"))
+	var seed [32]byte
+	copy(seed[:], mac.Sum(nil))
+	src := rand.NewChaCha8(seed)
+
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+	}
+	// Fisher–Yates, drawing j uniformly from [0, i].
+	for i := n - 1; i > 0; i-- {
+		j := uniform(src, uint64(i+1))
+		order[i], order[j] = order[j], order[i]
+	}
+	return order
+}
+
+// uniform returns a value in [0, n) from src without modulo bias, using
+// Lemire's multiply-and-reject method.
+func uniform(src *rand.ChaCha8, n uint64) uint64 {
+	if n&(n-1) == 0 {
+		return src.Uint64() & (n - 1)
+	}
+	hi, lo := bits.Mul64(src.Uint64(), n)
+	if lo < n {
+		thresh := -n % n
+		for lo < thresh {
+			hi, lo = bits.Mul64(src.Uint64(), n)
+		}
+	}
+	return hi
+}
+
+// pairOffsets maps pair index i to the Pix offsets of its (a, b) samples.
+func pairOffsets(img *image.NRGBA, i int) (int, int) {
+	w := img.Rect.Dx()
+	perRow := w / 2
+	perPlane := perRow * img.Rect.Dy()
+
+	plane := i / perPlane
+	rest := i % perPlane
+	y := rest / perRow
+	x := (rest % perRow) * 2
+
+	a := y*img.Stride + x*4 + plane
+	return a, a + 4
+}
+
+func pairCount(img *image.NRGBA) int {
+	return 3 * (img.Rect.Dx() / 2) * img.Rect.Dy()
+}
+
+// toNRGBA returns a private NRGBA copy of img with its origin at (0, 0).
+func toNRGBA(img image.Image) *image.NRGBA {
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	if src, ok := img.(*image.NRGBA); ok {
+		for y := 0; y < b.Dy(); y++ {
+			i := src.PixOffset(b.Min.X, b.Min.Y+y)
+			copy(dst.Pix[y*dst.Stride:], src.Pix[i:i+b.Dx()*4])
+		}
+		return dst
+	}
+	draw.Draw(dst, dst.Rect, img, b.Min, draw.Src)
+	return dst
+}
+
+func appendUint(bits []int, v uint64, n int) []int {
+	for i := n - 1; i >= 0; i-- {
+		bits = append(bits, int((v>>i)&1))
+	}
+	return bits
+}
+
+func readUint(bits []int, n int) uint64 {
+	var v uint64
+	for _, b := range bits[:n] {
+		v = (v << 1) | uint64(b)
+	}
+	return v
+}
+
+func packBits(bits []int) []byte {
+	out := make([]byte, (len(bits)+7)/8)
+	for i, b := range bits {
+		if b == 1 {
+			out[i/8] |= 0x80 >> (i % 8)
+		}
+	}
+	return out
+}
+
+func unpackBits(data []byte) []int {
+	bits := make([]int, len(data)*8)
+	for i := range bits {
+		if data[i/8]&(0x80>>(i%8)) != 0 {
+			bits[i] = 1
+		}
+	}
+	return bits
+}
+
+// EmbedReversible hides payload in img so that ExtractReversible, given the
+// same key, can return both the payload and the untouched original pixels.
+func EmbedReversible(img image.Image, payload []int, key []byte) (*image.NRGBA, error) {
+	out := toNRGBA(img)
+	n := pairCount(out)
+	order := pairOrder(key, n)
+
+	// Pre-process overflow candidates and build the location map.
+	var locationMap []int
+	for _, p := range order {
+		_, b := pairOffsets(out, p)
+		switch out.Pix[b] {
+		case 255:
+			out.Pix[b] = 254
+			locationMap = append(locationMap, 1)
+		case 254:
+			locationMap = append(locationMap, 0)
+		}
+	}
+
+	var compressed bytes.Buffer
+	fw, err := flate.NewWriter(&compressed, flate.BestCompression)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fw.Write(packBits(locationMap)); err != nil {
+		return nil, err
+	}
+	if err := fw.Close(); err != nil {
+		return nil, err
+	}
+
+	stream := appendUint(nil, uint64(reversibleMagic), 16)
+	stream = appendUint(stream, uint64(compressed.Len()), 32)
+	stream = appendUint(stream, uint64(len(payload)), 16)
+	stream = append(stream, unpackBits(compressed.Bytes())...)
+	stream = append(stream, payload...)
+
+	capacity := 0
+	for _, p := range order {
+		a, b := pairOffsets(out, p)
+		if out.Pix[a] == out.Pix[b] {
+			capacity++
+		}
+	}
+	if capacity < len(stream) {
+		return nil, ErrReversibleCapacity
+	}
+
+	// Histogram shift: open the d == 1 bin and fill d == 0 with the stream.
+	bitIndex := 0
+	for _, p := range order {
+		a, b := pairOffsets(out, p)
+		d := int(out.Pix[b]) - int(out.Pix[a])
+		switch {
+		case d == 0:
+			if bitIndex < len(stream) {
+				out.Pix[b] += uint8(stream[bitIndex])
+				bitIndex++
+			}
+		case d > 0:
+			out.Pix[b]++
+		}
+	}
+
+	return out, nil
+}
+
+// ExtractReversible recovers the payload embedded by EmbedReversible and
+// restores the original image. It returns ErrNoReversibleMark when the
+// image carries no reversible watermark for this key.
+func ExtractReversible(img image.Image, key []byte) ([]int, *image.NRGBA, error) {
+	restored := toNRGBA(img)
+	n := pairCount(restored)
+	order := pairOrder(key, n)
+
+	// Undo the histogram shift, collecting the stream as we go.
+	stream := make([]int, 0, 1024)
+	for _, p := range order {
+		a, b := pairOffsets(restored, p)
+		d := int(restored.Pix[b]) - int(restored.Pix[a])
+		switch {
+		case d == 0:
+			stream = append(stream, 0)
+		case d == 1:
+			stream = append(stream, 1)
+			restored.Pix[b]--
+		case d > 1:
+			restored.Pix[b]--
+		}
+	}
+
+	const headerBits = 16 + 32 + 16
+	if len(stream) < headerBits || uint16(readUint(stream, 16)) != reversibleMagic {
+		return nil, nil, ErrNoReversibleMark
+	}
+	mapBytes := int(readUint(stream[16:], 32))
+	payloadBits := int(readUint(stream[48:], 16))
+	if headerBits+mapBytes*8+payloadBits > len(stream) {
+		return nil, nil, ErrNoReversibleMark
+	}
+
+	mapStart := headerBits
+	payloadStart := mapStart + mapBytes*8
+	packed := packBits(stream[mapStart:payloadStart])
+	raw, err := io.ReadAll(flate.NewReader(bytes.NewReader(packed)))
+	if err != nil {
+		return nil, nil, ErrNoReversibleMark
+	}
+	locationMap := unpackBits(raw)
+
+	// Put the overflow pixels back.
+	next := 0
+	for _, p := range order {
+		_, b := pairOffsets(restored, p)
+		if restored.Pix[b] != 254 {
+			continue
+		}
+		if next >= len(locationMap) {
+			return nil, nil, ErrNoReversibleMark
+		}
+		if locationMap[next] == 1 {
+			restored.Pix[b] = 255
+		}
+		next++
+	}
+
+	payload := make([]int, payloadBits)
+	copy(payload, stream[payloadStart:payloadStart+payloadBits])
+
+	return payload, restored, nil
+}
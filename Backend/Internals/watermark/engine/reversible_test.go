@@ -0,0 +1,121 @@
+package engine
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// archiveImage is an opaque picture with flat areas, for capacity, and
+// saturated ones: a white band fills the overflow location map and black
+// and 254 pixels sit next to it.
+func archiveImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	r := rand.New(rand.NewSource(7))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var v uint8
+			switch {
+			case y < h/4:
+				v = 255
+			case y < h/3:
+				v = uint8(254 + x%2)
+			case y < h/2:
+				v = 0
+			case x < w/2:
+				v = uint8(100 + (x/16)%3)
+			default:
+				v = uint8(r.Intn(256))
+			}
+			img.SetNRGBA(x, y, color.NRGBA{v, 255 - v, uint8(r.Intn(2)) * v, 255})
+		}
+	}
+	return img
+}
+
+func testBits(n int) []int {
+	r := rand.New(rand.NewSource(3))
+	bits := make([]int, n)
+	for i := range bits {
+		bits[i] = r.Intn(2)
+	}
+	return bits
+}
+
+func TestReversibleRoundTrip(t *testing.T) {
+	key := []byte("archive-key")
+	bits := testBits(464)
+
+	for _, size := range []image.Point{{256, 256}, {257, 131}} {
+		original := archiveImage(size.X, size.Y)
+
+		marked, err := EmbedReversible(original, bits, key)
+		if err != nil {
+			t.Fatalf("%v: %v", size, err)
+		}
+		if bytes.Equal(marked.Pix, original.Pix) {
+			t.Fatalf("%v: embedding left the pixels unchanged", size)
+		}
+
+		got, restored, err := ExtractReversible(marked, key)
+		if err != nil {
+			t.Fatalf("%v: %v", size, err)
+		}
+		if !slices.Equal(got, bits) {
+			t.Fatalf("%v: payload differs after the round trip", size)
+		}
+		if !bytes.Equal(restored.Pix, original.Pix) {
+			t.Fatalf("%v: restored pixels differ from the original", size)
+		}
+	}
+}
+
+func TestReversibleRejectsWrongKey(t *testing.T) {
+	marked, err := EmbedReversible(archiveImage(256, 256), testBits(464), []byte("archive-key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ExtractReversible(marked, []byte("other-key")); !errors.Is(err, ErrNoReversibleMark) {
+		t.Fatalf("wrong key: err = %v, want ErrNoReversibleMark", err)
+	}
+}
+
+func TestReversibleUnmarkedImages(t *testing.T) {
+	flat := image.NewGray(image.Rect(0, 0, 64, 64))
+	for i := range flat.Pix {
+		flat.Pix[i] = 255
+	}
+	for name, img := range map[string]image.Image{
+		"photo-like": archiveImage(256, 256),
+		"all white":  flat,
+		"one pixel":  image.NewRGBA(image.Rect(0, 0, 1, 1)),
+		"empty":      image.NewRGBA(image.Rectangle{}),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := ExtractReversible(img, []byte("archive-key")); !errors.Is(err, ErrNoReversibleMark) {
+				t.Fatalf("err = %v, want ErrNoReversibleMark", err)
+			}
+		})
+	}
+}
+
+func TestReversibleCapacity(t *testing.T) {
+	noise := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	rand.New(rand.NewSource(1)).Read(noise.Pix)
+	if _, err := EmbedReversible(noise, testBits(464), []byte("archive-key")); !errors.Is(err, ErrReversibleCapacity) {
+		t.Fatalf("err = %v, want ErrReversibleCapacity", err)
+	}
+}
+
+// The pair order decides where every bit of an archived master sits, so it
+// must never change. These values were produced by the original release.
+func TestPairOrderIsStable(t *testing.T) {
+	want := []int{931, 295, 54, 679, 517, 438, 73, 746}
+	if got := pairOrder([]byte("archive-key"), 1000); !slices.Equal(got[:len(want)], want) {
+		t.Fatalf("pairOrder starts %v, want %v", got[:len(want)], want)
+	}
+}
@@ -0,0 +1,204 @@
+package engine
+
+import (
+	"image"
+	"sync"
+)
+
+// TileSize is the edge length of the square tiles the watermark is
+// replicated over.
+const TileSize = 256
+
+// DefaultTileCacheBytes is the luminance cache budget used when Options
+// leaves TileCacheBytes at zero.
+const DefaultTileCacheBytes = 64 << 20
+
+// tileBytes is the approximate footprint of one cached [][]float64 tile.
+const tileBytes = TileSize * (TileSize*8 + 24)
+
+// Options bounds the resources the tile pipeline may use.
+type Options struct {
+	// TileCacheBytes caps the converted tiles a TileSource keeps between
+	// passes, so Identify followed by Extract converts each tile once while
+	// very large images never hold a full-size luminance matrix.
+	TileCacheBytes int
+
+	// Workers is the number of tiles processed concurrently by the embed,
+	// extract and identify paths. Zero means GOMAXPROCS.
+	Workers int
+}
+
+func (o Options) maxCachedTiles() int {
+	budget := o.TileCacheBytes
+	if budget <= 0 {
+		budget = DefaultTileCacheBytes
+	}
+	if n := budget / tileBytes; n > 0 {
+		return n
+	}
+	return 1
+}
+
+// TileSource hands out 256x256 luminance tiles (Y - 128) of an image,
+// converting each one from the source colour space only when it is first
+// asked for and keeping recently used tiles within the memory budget.
+// Tiles of a *image.YCbCr are read straight from its Y plane.
+type TileSource struct {
+	img    image.Image
+	bounds image.Rectangle
+
+	TilesX int
+	TilesY int
+
+	maxTiles int
+	workers  int
+
+	mu    sync.Mutex
+	cache map[image.Point][][]float64
+	lru   []image.Point // least recently used first
+}
+
+// NewTileSource prepares img for tile-at-a-time processing. Nothing is
+// converted until Tile is called.
+func NewTileSource(img image.Image, opts Options) *TileSource {
+	b := img.Bounds()
+	return &TileSource{
+		img:      img,
+		bounds:   b,
+		TilesX:   b.Dx() / TileSize,
+		TilesY:   b.Dy() / TileSize,
+		maxTiles: opts.maxCachedTiles(),
+		workers:  opts.workers(),
+		cache:    make(map[image.Point][][]float64),
+	}
+}
+
+// Image returns the image the tiles are taken from.
+func (s *TileSource) Image() image.Image { return s.img }
+
+// Tile returns a copy of tile (tx, ty) that the caller may modify.
+func (s *TileSource) Tile(tx, ty int) [][]float64 {
+	key := image.Point{X: tx, Y: ty}
+
+	s.mu.Lock()
+	cached, ok := s.cache[key]
+	if ok {
+		s.touch(key)
+	}
+	s.mu.Unlock()
+
+	if !ok {
+		cached = s.convert(tx, ty)
+
+		s.mu.Lock()
+		if _, raced := s.cache[key]; !raced {
+			s.cache[key] = cached
+			s.lru = append(s.lru, key)
+			s.evict()
+		}
+		s.mu.Unlock()
+	}
+
+	tile := make([][]float64, TileSize)
+	for i := range tile {
+		tile[i] = make([]float64, TileSize)
+		copy(tile[i], cached[i])
+	}
+	return tile
+}
+
+// drop forgets a cached tile after its pixels have been rewritten.
+func (s *TileSource) drop(tx, ty int) {
+	key := image.Point{X: tx, Y: ty}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.cache[key]; !ok {
+		return
+	}
+	delete(s.cache, key)
+	for i, k := range s.lru {
+		if k == key {
+			s.lru = append(s.lru[:i], s.lru[i+1:]...)
+			break
+		}
+	}
+}
+
+func (s *TileSource) touch(key image.Point) {
+	for i, k := range s.lru {
+		if k == key {
+			s.lru = append(append(s.lru[:i], s.lru[i+1:]...), key)
+			return
+		}
+	}
+}
+
+func (s *TileSource) evict() {
+	for len(s.lru) > s.maxTiles {
+		delete(s.cache, s.lru[0])
+		s.lru = s.lru[1:]
+	}
+}
+
+// convert computes the luminance of one tile from the source image.
+func (s *TileSource) convert(tx, ty int) [][]float64 {
+	x0 := s.bounds.Min.X + tx*TileSize
+	y0 := s.bounds.Min.Y + ty*TileSize
+
+	tile := make([][]float64, TileSize)
+	for i := range tile {
+		tile[i] = make([]float64, TileSize)
+		ycbcrRow(s.img, x0, y0+i, tile[i], nil, nil)
+		for j := range tile[i] {
+			tile[i][j] -= 128.0
+		}
+	}
+	return tile
+}
+
+// ToYCbCr converts img to a full-resolution (4:4:4) YCbCr copy using the
+// same formula as ConvertToYC, without building the float Y matrix.
+func ToYCbCr(img image.Image) *image.YCbCr {
+	bounds := img.Bounds()
+	ycb := image.NewYCbCr(bounds, image.YCbCrSubsampleRatio444)
+
+	Y := make([]float64, bounds.Dx())
+	Cb := make([]float64, bounds.Dx())
+	Cr := make([]float64, bounds.Dx())
+
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		ycbcrRow(img, bounds.Min.X, y, Y, Cb, Cr)
+
+		yo := ycb.YOffset(bounds.Min.X, y)
+		co := ycb.COffset(bounds.Min.X, y)
+		for i := range Y {
+			ycb.Y[yo+i] = uint8(Y[i])
+			ycb.Cb[co+i] = uint8(Cb[i])
+			ycb.Cr[co+i] = uint8(Cr[i])
+		}
+	}
+	return ycb
+}
+
+// writeTile stores a processed tile back into the Y plane of ycb, clamping
+// each pixel to the valid range.
+func writeTile(ycb *image.YCbCr, tx, ty int, tile [][]float64) {
+	x0 := ycb.Rect.Min.X + tx*TileSize
+	y0 := ycb.Rect.Min.Y + ty*TileSize
+
+	for i, row := range tile {
+		off := ycb.YOffset(x0, y0+i)
+		for j, v := range row {
+			value := v + 128.0
+			switch {
+			case value < 0:
+				value = 0
+			case value > 255:
+				value = 255
+			}
+			ycb.Y[off+j] = uint8(value)
+		}
+	}
+}
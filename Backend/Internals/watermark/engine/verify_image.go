@@ -3,48 +3,33 @@ package engine
 import "image"
 
 func Identify(img image.Image, c []Constants) (x int, y int, flag bool) {
+	return IdentifyTiles(NewTileSource(img, Options{}), c)
+}
 
-	_, Ymatrix := ConvertToYC(img)
-
+// IdentifyTiles locates the first marked tile row and column of src. Tiles
+// converted here stay cached for a following ExtractTiles on the same source.
+func IdentifyTiles(src *TileSource, c []Constants) (x int, y int, flag bool) {
 
-	h := len(Ymatrix)
-	w := len(Ymatrix[0])
+	if src.TilesX == 0 || src.TilesY == 0 {
+		return -1, -1, false
+	}
 
 	// Find x_index: scan candidate tile row-starts (0, 256, 512, ...)
-	// For each candidate, take the first 16 rows of that tile and check
+	// For each candidate, take the tile in the first tile column and check
 	// that the first column (bx=0) verification pattern holds (flag=false).
-	x_index := -1
-	for i := 0; i+256 <= h; i += 256 {
-		// Build a 256x256 block starting at row i, col 0
-		block := make([][]float64, 256)
-		for row := 0; row < 256; row++ {
-			block[row] = make([]float64, 256)
-			copy(block[row], Ymatrix[i+row][:256])
-		}
-		if Verifytile(block, c, false) {
-			x_index = i / 256
-			break
-		}
-	}
+	x_index := firstMatch(src.TilesY, src.workers, func(i int) bool {
+		return Verifytile(src.Tile(0, i), c, false)
+	})
 	if x_index == -1 {
 		return -1, -1, false
 	}
 
 	// Find y_index: scan candidate tile column-starts (0, 256, 512, ...)
-	// For each candidate, take the 256x256 block at (x_index*256, j) and
-	// check that the first row verification pattern holds (flag=true).
-	y_index := -1
-	for j := 0; j+256 <= w; j += 256 {
-		block := make([][]float64, 256)
-		for row := 0; row < 256; row++ {
-			block[row] = make([]float64, 256)
-			copy(block[row], Ymatrix[x_index*256+row][j:j+256])
-		}
-		if Verifytile(block, c, true) {
-			y_index = j / 256
-			break
-		}
-	}
+	// For each candidate, take the tile at (j, x_index) and check that the
+	// first row verification pattern holds (flag=true).
+	y_index := firstMatch(src.TilesX, src.workers, func(j int) bool {
+		return Verifytile(src.Tile(j, x_index), c, true)
+	})
 	if y_index == -1 {
 		return -1, -1, false
 	}
@@ -0,0 +1,69 @@
+package features
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Binary layout: magic, width, height, count (uint32 each, big endian),
+// then per keypoint X, Y, Size, Angle, Response as float32 bits followed
+// by the four descriptor words.
+const (
+	encodingMagic = 0x46454131 // "FEA1"
+	headerSize    = 16
+	keypointSize  = 5*4 + len(Descriptor{})*8
+)
+
+// MarshalBinary encodes s for storage.
+func (s *Set) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, headerSize+len(s.Keypoints)*keypointSize)
+	binary.BigEndian.PutUint32(buf[0:], encodingMagic)
+	binary.BigEndian.PutUint32(buf[4:], uint32(s.Width))
+	binary.BigEndian.PutUint32(buf[8:], uint32(s.Height))
+	binary.BigEndian.PutUint32(buf[12:], uint32(len(s.Keypoints)))
+
+	off := headerSize
+	for _, kp := range s.Keypoints {
+		for _, f := range []float32{kp.X, kp.Y, kp.Size, kp.Angle, kp.Response} {
+			binary.BigEndian.PutUint32(buf[off:], math.Float32bits(f))
+			off += 4
+		}
+		for _, w := range kp.Descriptor {
+			binary.BigEndian.PutUint64(buf[off:], w)
+			off += 8
+		}
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary decodes a Set written by MarshalBinary.
+func (s *Set) UnmarshalBinary(data []byte) error {
+	if len(data) < headerSize || binary.BigEndian.Uint32(data) != encodingMagic {
+		return errors.New("not an encoded feature set")
+	}
+	n := int(binary.BigEndian.Uint32(data[12:]))
+	if want := headerSize + n*keypointSize; len(data) != want {
+		return fmt.Errorf("feature set is %d bytes, want %d", len(data), want)
+	}
+
+	s.Width = int(binary.BigEndian.Uint32(data[4:]))
+	s.Height = int(binary.BigEndian.Uint32(data[8:]))
+	s.Keypoints = make([]Keypoint, n)
+
+	r := bytes.NewReader(data[headerSize:])
+	for i := range s.Keypoints {
+		kp := &s.Keypoints[i]
+		var f [5]uint32
+		binary.Read(r, binary.BigEndian, &f)
+		kp.X = math.Float32frombits(f[0])
+		kp.Y = math.Float32frombits(f[1])
+		kp.Size = math.Float32frombits(f[2])
+		kp.Angle = math.Float32frombits(f[3])
+		kp.Response = math.Float32frombits(f[4])
+		binary.Read(r, binary.BigEndian, &kp.Descriptor)
+	}
+	return nil
+}
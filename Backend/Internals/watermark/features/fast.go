@@ -0,0 +1,140 @@
+package features
+
+import "sort"
+
+// fastThreshold is the brightness difference a circle pixel needs to count
+// as brighter/darker than the centre.
+const fastThreshold = 20
+
+// fastCircle is the Bresenham circle of radius 3 FAST tests, clockwise
+// from the top.
+var fastCircle = [16][2]int{
+	{0, -3}, {1, -3}, {2, -2}, {3, -1}, {3, 0}, {3, 1}, {2, 2}, {1, 3},
+	{0, 3}, {-1, 3}, {-2, 2}, {-3, 1}, {-3, 0}, {-3, -1}, {-2, -2}, {-1, -3},
+}
+
+// fastScore is the FAST-9 test: the pixel is a corner when 9 contiguous
+// circle pixels are all brighter or all darker than it by the threshold.
+// The score is the summed excess difference, or 0 for non-corners.
+func fastScore(g *grey, x, y int) float32 {
+	p := g.at(x, y)
+
+	var diff [16]float32
+	for i, c := range fastCircle {
+		diff[i] = g.at(x+c[0], y+c[1]) - p
+	}
+
+	// Any 9-arc covers at least two of the four compass points.
+	bright, dark := 0, 0
+	for i := 0; i < 16; i += 4 {
+		if diff[i] > fastThreshold {
+			bright++
+		} else if diff[i] < -fastThreshold {
+			dark++
+		}
+	}
+	if bright < 2 && dark < 2 {
+		return 0
+	}
+
+	for _, sign := range []float32{1, -1} {
+		run, longest := 0, 0
+		for i := 0; i < 32; i++ {
+			if sign*diff[i%16] > fastThreshold {
+				run++
+				longest = max(longest, run)
+			} else {
+				run = 0
+			}
+		}
+		if longest >= 9 {
+			var score float32
+			for _, d := range diff {
+				if e := sign*d - fastThreshold; e > 0 {
+					score += e
+				}
+			}
+			return score
+		}
+	}
+	return 0
+}
+
+// harris returns the Harris corner response over a 7x7 window, which ORB
+// uses to rank FAST corners because FAST also fires along edges.
+func harris(g *grey, x, y int) float32 {
+	var a, b, c float32
+	for dy := -3; dy <= 3; dy++ {
+		for dx := -3; dx <= 3; dx++ {
+			px, py := x+dx, y+dy
+			ix := (g.at(px+1, py) - g.at(px-1, py)) / 2
+			iy := (g.at(px, py+1) - g.at(px, py-1)) / 2
+			a += ix * ix
+			b += iy * iy
+			c += ix * iy
+		}
+	}
+	return a*b - c*c - 0.04*(a+b)*(a+b)
+}
+
+type corner struct {
+	x, y     int
+	response float32
+}
+
+// detect finds up to n FAST corners at least border pixels from the edge,
+// non-maximum suppressed and ranked by Harris response.
+func detect(g *grey, border, n int) []corner {
+	if g.w <= 2*border || g.h <= 2*border || n <= 0 {
+		return nil
+	}
+
+	scores := make([]float32, g.w*g.h)
+	for y := border; y < g.h-border; y++ {
+		for x := border; x < g.w-border; x++ {
+			scores[y*g.w+x] = fastScore(g, x, y)
+		}
+	}
+
+	var corners []corner
+	for y := border; y < g.h-border; y++ {
+		for x := border; x < g.w-border; x++ {
+			s := scores[y*g.w+x]
+			if s == 0 || !localMax(scores, g.w, x, y, s) {
+				continue
+			}
+			corners = append(corners, corner{x: x, y: y, response: harris(g, x, y)})
+		}
+	}
+
+	sort.Slice(corners, func(i, j int) bool {
+		if corners[i].response != corners[j].response {
+			return corners[i].response > corners[j].response
+		}
+		if corners[i].y != corners[j].y {
+			return corners[i].y < corners[j].y
+		}
+		return corners[i].x < corners[j].x
+	})
+	if len(corners) > n {
+		corners = corners[:n]
+	}
+	return corners
+}
+
+// localMax reports whether s is the largest score in its 3x3
+// neighbourhood; ties go to the first pixel in scan order.
+func localMax(scores []float32, w, x, y int, s float32) bool {
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			if dx == 0 && dy == 0 {
+				continue
+			}
+			o := scores[(y+dy)*w+x+dx]
+			if o > s || (o == s && (dy < 0 || (dy == 0 && dx < 0))) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,84 @@
+package features
+
+// features.go — Local-feature fingerprints for partial matches
+//
+// The global fingerprint and the perceptual hashes describe a whole image,
+// so a crop of it looks like a different picture. Local features survive
+// cropping: ORB-style keypoints (FAST corners ranked by Harris response,
+// oriented by intensity centroid, described by steered 256-bit BRIEF) are
+// detected over a scale pyramid and stored per image. A crop query matches
+// its descriptors against candidate originals and fits a scale+translation
+// model with RANSAC, which also yields where in the original the crop sits
+// (match.go). Candidates come from an LSH bucket index (lsh.go) so a query
+// doesn't have to touch every stored image.
+
+import (
+	"image"
+	"math"
+)
+
+const (
+	// MaxKeypoints caps the keypoints kept per image over all levels.
+	MaxKeypoints = 500
+
+	pyramidLevels = 5
+	pyramidScale  = math.Sqrt2
+)
+
+// Keypoint is one detected feature. Coordinates are in pixels of the
+// original image, whatever resolution detection ran at.
+type Keypoint struct {
+	X, Y       float32
+	Size       float32 // patch diameter in original pixels
+	Angle      float32 // radians
+	Response   float32 // Harris response
+	Descriptor Descriptor
+}
+
+// Set is the local-feature fingerprint of one image.
+type Set struct {
+	Width, Height int // original image size
+	Keypoints     []Keypoint
+}
+
+// Extract detects up to MaxKeypoints features in img.
+func Extract(img image.Image) *Set {
+	b := img.Bounds()
+	set := &Set{Width: b.Dx(), Height: b.Dy()}
+
+	levels := []*grey{toGrey(img)}
+	for len(levels) < pyramidLevels {
+		prev := levels[len(levels)-1]
+		if float64(min(prev.w, prev.h))/pyramidScale <= 2*border+8 {
+			break
+		}
+		levels = append(levels, prev.blur(1).downscale(pyramidScale))
+	}
+
+	// Share the keypoint budget between levels by area, so coarse levels
+	// don't crowd out the detail of the full-resolution one.
+	totalArea := 0
+	for _, l := range levels {
+		totalArea += l.w * l.h
+	}
+
+	for _, l := range levels {
+		quota := int(math.Round(float64(MaxKeypoints) * float64(l.w*l.h) / float64(totalArea)))
+		sx := float64(set.Width) / float64(l.w)
+		sy := float64(set.Height) / float64(l.h)
+		blurred := l.blur(blurRadius)
+
+		for _, c := range detect(l, border, quota) {
+			angle := orientation(l, c.x, c.y)
+			set.Keypoints = append(set.Keypoints, Keypoint{
+				X:          float32((float64(c.x)+0.5)*sx - 0.5),
+				Y:          float32((float64(c.y)+0.5)*sy - 0.5),
+				Size:       float32((2*patchRadius + 1) * sx),
+				Angle:      float32(angle),
+				Response:   c.response,
+				Descriptor: describe(blurred, c.x, c.y, angle),
+			})
+		}
+	}
+	return set
+}
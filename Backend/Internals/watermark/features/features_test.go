@@ -0,0 +1,186 @@
+package features
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"math/rand"
+	"testing"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// shapes draws random overlapping rectangles and discs with gradient
+// fills over a noisy background, which gives FAST plenty of distinctive
+// corners at several scales.
+func shapes(w, h int, seed int64) *image.RGBA {
+	rng := rand.New(rand.NewSource(seed))
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = uint8(96 + rng.Intn(64))
+	}
+
+	for i := 0; i < 300; i++ {
+		r, g, b := rng.Float64()*255, rng.Float64()*255, rng.Float64()*255
+		gx, gy := rng.Float64()*4-2, rng.Float64()*4-2
+		x, y := rng.Intn(w), rng.Intn(h)
+		size := 6 + rng.Intn(40)
+		disc := rng.Intn(2) == 0
+
+		for dy := 0; dy < size; dy++ {
+			for dx := 0; dx < size; dx++ {
+				cx, cy := dx-size/2, dy-size/2
+				if disc && cx*cx+cy*cy > size*size/4 {
+					continue
+				}
+				if !image.Pt(x+dx, y+dy).In(img.Bounds()) {
+					continue
+				}
+				shade := gx*float64(dx) + gy*float64(dy)
+				img.Set(x+dx, y+dy, color.RGBA{clamp8(r + shade), clamp8(g + shade), clamp8(b + shade), 255})
+			}
+		}
+	}
+	return img
+}
+
+func clamp8(v float64) uint8 {
+	return uint8(math.Max(0, math.Min(255, v)))
+}
+
+func crop(img image.Image, r image.Rectangle) *image.RGBA {
+	out := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(out, out.Bounds(), img, r.Min, draw.Src)
+	return out
+}
+
+func scale(img image.Image, f float64) *image.RGBA {
+	b := img.Bounds()
+	out := image.NewRGBA(image.Rect(0, 0, int(float64(b.Dx())*f), int(float64(b.Dy())*f)))
+	xdraw.CatmullRom.Scale(out, out.Bounds(), img, b, draw.Src, nil)
+	return out
+}
+
+func TestExtractIsDeterministic(t *testing.T) {
+	img := shapes(400, 300, 1)
+	a, b := Extract(img), Extract(img)
+
+	if len(a.Keypoints) < 100 || len(a.Keypoints) > MaxKeypoints {
+		t.Fatalf("got %d keypoints, want 100..%d", len(a.Keypoints), MaxKeypoints)
+	}
+	if len(a.Keypoints) != len(b.Keypoints) {
+		t.Fatalf("keypoint count differs: %d vs %d", len(a.Keypoints), len(b.Keypoints))
+	}
+	for i := range a.Keypoints {
+		if a.Keypoints[i] != b.Keypoints[i] {
+			t.Fatalf("keypoint %d differs", i)
+		}
+	}
+	for _, kp := range a.Keypoints {
+		if kp.X < 0 || kp.Y < 0 || kp.X >= 400 || kp.Y >= 300 {
+			t.Fatalf("keypoint outside the image: %+v", kp)
+		}
+	}
+}
+
+func TestEncodingRoundTrip(t *testing.T) {
+	set := Extract(shapes(320, 240, 2))
+	data, err := set.MarshalBinary()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var got Set
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatal(err)
+	}
+	if got.Width != set.Width || got.Height != set.Height || len(got.Keypoints) != len(set.Keypoints) {
+		t.Fatalf("header differs: got %dx%d/%d", got.Width, got.Height, len(got.Keypoints))
+	}
+	for i := range got.Keypoints {
+		if got.Keypoints[i] != set.Keypoints[i] {
+			t.Fatalf("keypoint %d differs after round trip", i)
+		}
+	}
+
+	if err := got.UnmarshalBinary(data[:len(data)-1]); err == nil {
+		t.Fatal("UnmarshalBinary accepted a truncated set")
+	}
+}
+
+func TestLocateCrop(t *testing.T) {
+	original := shapes(800, 600, 3)
+	ref := Extract(original)
+	area := image.Rect(240, 180, 640, 480)
+
+	tests := []struct {
+		name  string
+		query image.Image
+		scale float64 // reference pixels per query pixel
+	}{
+		{"plain crop", crop(original, area), 1},
+		{"downscaled crop", scale(crop(original, area), 0.7), 1 / 0.7},
+		{"upscaled crop", scale(crop(original, area), 1.5), 1 / 1.5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := LocateCrop(Extract(tt.query), ref)
+			if !ok {
+				t.Fatalf("crop not found (%d matches, %d inliers)", got.Matches, got.Inliers)
+			}
+			if math.Abs(got.Transform.Scale-tt.scale) > 0.05*tt.scale {
+				t.Errorf("scale %.3f, want %.3f", got.Transform.Scale, tt.scale)
+			}
+			for _, d := range []int{
+				got.Rect.Min.X - area.Min.X, got.Rect.Min.Y - area.Min.Y,
+				got.Rect.Max.X - area.Max.X, got.Rect.Max.Y - area.Max.Y,
+			} {
+				if d < -8 || d > 8 {
+					t.Fatalf("rect %v, want about %v", got.Rect, area)
+				}
+			}
+		})
+	}
+
+	t.Run("unrelated image", func(t *testing.T) {
+		if got, ok := LocateCrop(Extract(shapes(400, 300, 4)), ref); ok {
+			t.Fatalf("unrelated image located at %v with %d inliers", got.Rect, got.Inliers)
+		}
+	})
+}
+
+func TestBucketKeysRankOriginalFirst(t *testing.T) {
+	// Bucket -> images holding it, over a small corpus.
+	index := make(map[int64]map[int]bool)
+	for i := 0; i < 8; i++ {
+		for _, k := range Extract(shapes(800, 600, int64(10+i))).BucketKeys() {
+			if index[k] == nil {
+				index[k] = make(map[int]bool)
+			}
+			index[k][i] = true
+		}
+	}
+
+	for _, target := range []int{0, 5} {
+		query := Extract(crop(shapes(800, 600, int64(10+target)), image.Rect(200, 100, 600, 400)))
+
+		votes := make([]int, 8)
+		seen := make(map[int64]bool)
+		for _, k := range query.BucketKeys() {
+			if seen[k] {
+				continue
+			}
+			seen[k] = true
+			for i := range index[k] {
+				votes[i]++
+			}
+		}
+		for i, v := range votes {
+			if i != target && v >= votes[target] {
+				t.Fatalf("image %d has %d votes, original %d only %d", i, v, target, votes[target])
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package features
+
+import (
+	"image"
+	"math"
+
+	"golang.org/x/image/draw"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/engine"
+)
+
+// maxWorkingSide caps the resolution features are detected at; larger
+// images are downscaled first and coordinates mapped back afterwards.
+const maxWorkingSide = 1024
+
+// grey is a single-channel float image.
+type grey struct {
+	w, h int
+	pix  []float32
+}
+
+func newGrey(w, h int) *grey {
+	return &grey{w: w, h: h, pix: make([]float32, w*h)}
+}
+
+func (g *grey) at(x, y int) float32 { return g.pix[y*g.w+x] }
+
+// toGrey returns the luminance of img at working resolution.
+func toGrey(img image.Image) *grey {
+	b := img.Bounds()
+	if side := max(b.Dx(), b.Dy()); side > maxWorkingSide {
+		f := float64(maxWorkingSide) / float64(side)
+		dst := image.NewRGBA(image.Rect(0, 0,
+			max(1, int(math.Round(float64(b.Dx())*f))),
+			max(1, int(math.Round(float64(b.Dy())*f)))))
+		draw.CatmullRom.Scale(dst, dst.Bounds(), img, b, draw.Over, nil)
+		img = dst
+	}
+
+	_, Ymatrix := engine.ConvertToYC(img)
+
+	g := newGrey(len(Ymatrix[0]), len(Ymatrix))
+	for y, row := range Ymatrix {
+		for x, v := range row {
+			g.pix[y*g.w+x] = float32(v + 128.0)
+		}
+	}
+	return g
+}
+
+// downscale shrinks g by factor f (> 1) with bilinear sampling. Callers
+// blur first when aliasing matters.
+func (g *grey) downscale(f float64) *grey {
+	w := int(float64(g.w) / f)
+	h := int(float64(g.h) / f)
+	out := newGrey(w, h)
+
+	sx := float64(g.w) / float64(w)
+	sy := float64(g.h) / float64(h)
+	for y := 0; y < h; y++ {
+		fy := (float64(y)+0.5)*sy - 0.5
+		y0 := clampInt(int(math.Floor(fy)), 0, g.h-1)
+		y1 := clampInt(y0+1, 0, g.h-1)
+		wy := float32(fy - math.Floor(fy))
+		for x := 0; x < w; x++ {
+			fx := (float64(x)+0.5)*sx - 0.5
+			x0 := clampInt(int(math.Floor(fx)), 0, g.w-1)
+			x1 := clampInt(x0+1, 0, g.w-1)
+			wx := float32(fx - math.Floor(fx))
+
+			top := g.at(x0, y0)*(1-wx) + g.at(x1, y0)*wx
+			bottom := g.at(x0, y1)*(1-wx) + g.at(x1, y1)*wx
+			out.pix[y*w+x] = top*(1-wy) + bottom*wy
+		}
+	}
+	return out
+}
+
+// blur applies a separable box filter of the given radius, clamping at
+// the edges. BRIEF tests compare single pixels, so they are taken on a
+// smoothed image to make them stable against noise.
+func (g *grey) blur(radius int) *grey {
+	tmp := newGrey(g.w, g.h)
+	out := newGrey(g.w, g.h)
+	n := float32(2*radius + 1)
+
+	for y := 0; y < g.h; y++ {
+		for x := 0; x < g.w; x++ {
+			var sum float32
+			for d := -radius; d <= radius; d++ {
+				sum += g.at(clampInt(x+d, 0, g.w-1), y)
+			}
+			tmp.pix[y*g.w+x] = sum / n
+		}
+	}
+	for y := 0; y < g.h; y++ {
+		for x := 0; x < g.w; x++ {
+			var sum float32
+			for d := -radius; d <= radius; d++ {
+				sum += tmp.at(x, clampInt(y+d, 0, g.h-1))
+			}
+			out.pix[y*g.w+x] = sum / n
+		}
+	}
+	return out
+}
+
+func clampInt(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
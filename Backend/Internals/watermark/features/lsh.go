@@ -0,0 +1,47 @@
+package features
+
+// lsh.go — Candidate lookup by descriptor buckets
+//
+// Bit-sampling LSH: each of lshTables tables reads a fixed random subset of
+// lshBits descriptor bits, and descriptors that agree on all of them share
+// a bucket. Near descriptors collide in at least one table with high
+// probability, so counting a query's bucket hits per stored image ranks
+// the images worth verifying. Keys pack the table number above the bits,
+// so all tables can live in one index column.
+
+import "math/rand"
+
+const (
+	lshTables = 4
+	lshBits   = 16
+)
+
+var lshSample [lshTables][lshBits]int
+
+func init() {
+	rng := rand.New(rand.NewSource(0x15b0c4e7))
+	for t := range lshSample {
+		perm := rng.Perm(descriptorBits)
+		copy(lshSample[t][:], perm[:lshBits])
+	}
+}
+
+// BucketKeys returns the LSH bucket key of every keypoint in every table,
+// lshTables keys per keypoint.
+func (s *Set) BucketKeys() []int64 {
+	keys := make([]int64, 0, len(s.Keypoints)*lshTables)
+	for _, kp := range s.Keypoints {
+		for t := range lshSample {
+			keys = append(keys, bucketKey(kp.Descriptor, t))
+		}
+	}
+	return keys
+}
+
+func bucketKey(d Descriptor, table int) int64 {
+	key := int64(table) << lshBits
+	for i, b := range lshSample[table] {
+		key |= int64(d.bit(b)) << uint(lshBits-1-i)
+	}
+	return key
+}
@@ -0,0 +1,176 @@
+package features
+
+// match.go — Descriptor matching and crop localisation
+//
+// A crop is the original scaled by some factor and shifted, so matched
+// keypoints satisfy  original = Scale·query + (TX, TY). Two correct
+// matches fix the model; RANSAC picks the one most matches agree with and
+// refits it on those inliers by least squares. Rotated crops aren't
+// modelled — the steered descriptors still match, but the geometry check
+// rejects them.
+
+import (
+	"image"
+	"math"
+	"math/rand"
+)
+
+const (
+	// maxMatchDistance is the largest Hamming distance (of 256 bits)
+	// accepted as a match.
+	maxMatchDistance = 64
+	// ratioTest rejects a match unless it is clearly better than the
+	// runner-up, which drops ambiguous repetitive texture.
+	ratioTest = 0.8
+
+	ransacIterations = 1000
+
+	// MinInliers is how many geometrically consistent matches it takes
+	// to call a crop found.
+	MinInliers = 8
+)
+
+// Match pairs query keypoint Query with reference keypoint Ref.
+type Match struct {
+	Query, Ref int
+	Distance   int
+}
+
+// MatchDescriptors finds, for every query keypoint, its nearest reference
+// keypoint, keeping matches that pass the distance and ratio tests.
+func MatchDescriptors(query, ref []Keypoint) []Match {
+	var matches []Match
+	for qi, q := range query {
+		best, second, bestIdx := descriptorBits+1, descriptorBits+1, -1
+		for ri, r := range ref {
+			d := q.Descriptor.Distance(r.Descriptor)
+			if d < best {
+				best, second, bestIdx = d, best, ri
+			} else if d < second {
+				second = d
+			}
+		}
+		if bestIdx < 0 || best > maxMatchDistance || float64(best) >= ratioTest*float64(second) {
+			continue
+		}
+		matches = append(matches, Match{Query: qi, Ref: bestIdx, Distance: best})
+	}
+	return matches
+}
+
+// Transform maps query coordinates into the reference image.
+type Transform struct {
+	Scale, TX, TY float64
+}
+
+// Apply maps (x, y) from query to reference coordinates.
+func (t Transform) Apply(x, y float64) (float64, float64) {
+	return t.Scale*x + t.TX, t.Scale*y + t.TY
+}
+
+// CropMatch describes where a query image was found inside a reference.
+type CropMatch struct {
+	Rect      image.Rectangle // crop area in reference pixels
+	Transform Transform
+	Matches   int // descriptor matches before the geometry check
+	Inliers   int // matches consistent with Transform
+}
+
+// LocateCrop reports whether query shows part of ref and, if so, where.
+func LocateCrop(query, ref *Set) (CropMatch, bool) {
+	matches := MatchDescriptors(query.Keypoints, ref.Keypoints)
+	if len(matches) < MinInliers {
+		return CropMatch{Matches: len(matches)}, false
+	}
+
+	// Tolerance grows with the reference so large originals aren't held
+	// to sub-pixel agreement.
+	diag := math.Hypot(float64(ref.Width), float64(ref.Height))
+	tol := math.Min(math.Max(0.01*diag, 3), 12)
+
+	t, inliers := ransac(query.Keypoints, ref.Keypoints, matches, tol)
+	if len(inliers) < MinInliers {
+		return CropMatch{Matches: len(matches), Inliers: len(inliers)}, false
+	}
+	t = refine(query.Keypoints, ref.Keypoints, inliers)
+
+	x0, y0 := t.Apply(0, 0)
+	x1, y1 := t.Apply(float64(query.Width), float64(query.Height))
+	rect := image.Rect(
+		int(math.Round(x0)), int(math.Round(y0)),
+		int(math.Round(x1)), int(math.Round(y1)),
+	).Intersect(image.Rect(0, 0, ref.Width, ref.Height))
+
+	return CropMatch{
+		Rect:      rect,
+		Transform: t,
+		Matches:   len(matches),
+		Inliers:   len(inliers),
+	}, true
+}
+
+// ransac returns the scale+translation model with the most inliers.
+// Sampling is seeded, so the same inputs always give the same answer.
+func ransac(query, ref []Keypoint, matches []Match, tol float64) (Transform, []Match) {
+	rng := rand.New(rand.NewSource(1))
+
+	var best Transform
+	var bestInliers []Match
+	for it := 0; it < ransacIterations; it++ {
+		a := matches[rng.Intn(len(matches))]
+		b := matches[rng.Intn(len(matches))]
+
+		qa, qb := query[a.Query], query[b.Query]
+		ra, rb := ref[a.Ref], ref[b.Ref]
+		qd := math.Hypot(float64(qa.X-qb.X), float64(qa.Y-qb.Y))
+		rd := math.Hypot(float64(ra.X-rb.X), float64(ra.Y-rb.Y))
+		if qd < 10 {
+			continue // too close together to fix the scale
+		}
+		s := rd / qd
+		if s < 0.1 || s > 10 {
+			continue
+		}
+		t := Transform{Scale: s, TX: float64(ra.X) - s*float64(qa.X), TY: float64(ra.Y) - s*float64(qa.Y)}
+
+		inliers := consistent(query, ref, matches, t, tol)
+		if len(inliers) > len(bestInliers) {
+			best, bestInliers = t, inliers
+		}
+	}
+	return best, bestInliers
+}
+
+func consistent(query, ref []Keypoint, matches []Match, t Transform, tol float64) []Match {
+	var in []Match
+	for _, m := range matches {
+		x, y := t.Apply(float64(query[m.Query].X), float64(query[m.Query].Y))
+		if math.Hypot(x-float64(ref[m.Ref].X), y-float64(ref[m.Ref].Y)) <= tol {
+			in = append(in, m)
+		}
+	}
+	return in
+}
+
+// refine is the least-squares scale+translation fit over the inliers.
+func refine(query, ref []Keypoint, inliers []Match) Transform {
+	var qx, qy, rx, ry float64
+	for _, m := range inliers {
+		qx += float64(query[m.Query].X)
+		qy += float64(query[m.Query].Y)
+		rx += float64(ref[m.Ref].X)
+		ry += float64(ref[m.Ref].Y)
+	}
+	n := float64(len(inliers))
+	qx, qy, rx, ry = qx/n, qy/n, rx/n, ry/n
+
+	var num, den float64
+	for _, m := range inliers {
+		dqx, dqy := float64(query[m.Query].X)-qx, float64(query[m.Query].Y)-qy
+		drx, dry := float64(ref[m.Ref].X)-rx, float64(ref[m.Ref].Y)-ry
+		num += dqx*drx + dqy*dry
+		den += dqx*dqx + dqy*dqy
+	}
+	s := num / den
+	return Transform{Scale: s, TX: rx - s*qx, TY: ry - s*qy}
+}
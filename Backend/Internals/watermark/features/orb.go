@@ -0,0 +1,109 @@
+package features
+
+import (
+	"math"
+	"math/bits"
+	"math/rand"
+)
+
+const (
+	// patchRadius bounds the BRIEF sampling pattern and the orientation
+	// moment; keypoints keep border pixels away from the image edge.
+	patchRadius = 15
+	border      = patchRadius + 1
+
+	descriptorBits = 256
+	angleBins      = 30 // steered patterns, 12 degrees apart
+
+	blurRadius = 2
+)
+
+// Descriptor is a 256-bit binary BRIEF descriptor.
+type Descriptor [descriptorBits / 64]uint64
+
+// Distance returns the Hamming distance between d and o.
+func (d Descriptor) Distance(o Descriptor) int {
+	n := 0
+	for i := range d {
+		n += bits.OnesCount64(d[i] ^ o[i])
+	}
+	return n
+}
+
+func (d Descriptor) bit(i int) uint64 {
+	return d[i/64] >> (63 - uint(i%64)) & 1
+}
+
+type point struct{ x, y float64 }
+
+// pattern holds the BRIEF test pairs, drawn once from an isotropic Gaussian
+// around the keypoint and clipped to the patch. It is seeded so every
+// process computes the same descriptors for the same image.
+var (
+	pattern [descriptorBits][2]point
+	steered [angleBins][descriptorBits][4]int
+)
+
+func init() {
+	rng := rand.New(rand.NewSource(0x0b1e5eed))
+	sigma := float64(2*patchRadius+1) / 5
+
+	sample := func() point {
+		for {
+			p := point{rng.NormFloat64() * sigma, rng.NormFloat64() * sigma}
+			if p.x*p.x+p.y*p.y <= patchRadius*patchRadius {
+				return p
+			}
+		}
+	}
+	for i := range pattern {
+		pattern[i] = [2]point{sample(), sample()}
+	}
+
+	// The pattern lies inside a circle, so rotating it keeps it inside the
+	// patch.
+	for b := range steered {
+		sin, cos := math.Sincos(float64(b) * 2 * math.Pi / angleBins)
+		for i, pair := range pattern {
+			for k, p := range pair {
+				steered[b][i][2*k] = int(math.Round(cos*p.x - sin*p.y))
+				steered[b][i][2*k+1] = int(math.Round(sin*p.x + cos*p.y))
+			}
+		}
+	}
+}
+
+// orientation is the ORB intensity-centroid angle of the patch around
+// (x, y), in radians.
+func orientation(g *grey, x, y int) float64 {
+	var m01, m10 float64
+	for dy := -patchRadius; dy <= patchRadius; dy++ {
+		for dx := -patchRadius; dx <= patchRadius; dx++ {
+			if dx*dx+dy*dy > patchRadius*patchRadius {
+				continue
+			}
+			v := float64(g.at(x+dx, y+dy))
+			m10 += float64(dx) * v
+			m01 += float64(dy) * v
+		}
+	}
+	return math.Atan2(m01, m10)
+}
+
+// describe computes the steered BRIEF descriptor at (x, y) on the blurred
+// image: bit i is set when the first point of pair i is darker than the
+// second.
+func describe(blurred *grey, x, y int, angle float64) Descriptor {
+	bin := int(math.Round(angle/(2*math.Pi)*angleBins)) % angleBins
+	if bin < 0 {
+		bin += angleBins
+	}
+
+	var d Descriptor
+	for i, p := range steered[bin] {
+		if blurred.at(x+p[0], y+p[1]) < blurred.at(x+p[2], y+p[3]) {
+			d[i/64] |= 1 << (63 - uint(i%64))
+		}
+	}
+	return d
+}
@@ -0,0 +1,87 @@
+package fingerprint
+
+// attributes.go — Image attributes mirrored next to the vectors
+//
+// A similarity search can only be narrowed by what the vector store knows,
+// so a few columns of image_metadata are copied onto each image's vectors
+// (the point payload in Qdrant). They are written by the same outbox entry
+// that writes the vectors and are never read back as the source of truth.
+
+import (
+	"slices"
+	"time"
+)
+
+// Attributes are the image properties a similarity search can filter on.
+type Attributes struct {
+	IsAIGenerated bool
+	Owner         string
+	MimeType      string
+	Tags          []string
+	CreatedAt     time.Time
+}
+
+// Payload field names of the attributes in Qdrant.
+const (
+	attrAIGenerated = "is_ai_generated"
+	attrOwner       = "owner"
+	attrMimeType    = "mime_type"
+	attrTags        = "tags"
+	attrCreatedAt   = "created_at" // unix seconds
+)
+
+// Filter restricts a query to images whose attributes match every set
+// field. The zero value matches everything.
+type Filter struct {
+	IsAIGenerated *bool
+	Owner         string
+	MimeType      string
+
+	// Tags must all be present on the image.
+	Tags []string
+
+	// CreatedFrom is inclusive, CreatedTo exclusive; zero leaves that end
+	// open.
+	CreatedFrom time.Time
+	CreatedTo   time.Time
+}
+
+// Empty reports whether f matches everything.
+func (f *Filter) Empty() bool {
+	return f == nil || (f.IsAIGenerated == nil && f.Owner == "" && f.MimeType == "" &&
+		len(f.Tags) == 0 && f.CreatedFrom.IsZero() && f.CreatedTo.IsZero())
+}
+
+// Matches reports whether attributes a pass f. ok says whether a was
+// recorded at all; images without attributes only pass an empty filter.
+func (f *Filter) Matches(a Attributes, ok bool) bool {
+	if f.Empty() {
+		return true
+	}
+	if !ok {
+		return false
+	}
+	if f.IsAIGenerated != nil && a.IsAIGenerated != *f.IsAIGenerated {
+		return false
+	}
+	if f.Owner != "" && a.Owner != f.Owner {
+		return false
+	}
+	if f.MimeType != "" && a.MimeType != f.MimeType {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !slices.Contains(a.Tags, tag) {
+			return false
+		}
+	}
+	// Compared at payload precision, so both stores agree.
+	created := a.CreatedAt.Unix()
+	if !f.CreatedFrom.IsZero() && created < f.CreatedFrom.Unix() {
+		return false
+	}
+	if !f.CreatedTo.IsZero() && created >= f.CreatedTo.Unix() {
+		return false
+	}
+	return true
+}
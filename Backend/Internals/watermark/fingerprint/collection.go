@@ -0,0 +1,208 @@
+package fingerprint
+
+// collection.go — How the Qdrant collection is laid out and indexed
+//
+// The collection name, distance, HNSW parameters and quantisation come
+// from configuration. A new collection is created with them; an existing
+// one is first checked against the fingerprint dimensions and distance,
+// which can't change without recreating it, and startup is refused on a
+// mismatch. HNSW and quantisation settings that differ are applied to the
+// existing collection, and Qdrant rebuilds its index in the background.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+// DefaultCollection is the Qdrant collection used when none is configured.
+const DefaultCollection = "image_fingerprints"
+
+// Distances accepted by CollectionConfig.Distance.
+const (
+	DistanceCosine = "cosine"
+	DistanceDot    = "dot"
+)
+
+// Quantisations accepted by CollectionConfig.Quantization.
+const (
+	QuantizationScalar = "scalar" // int8 per dimension, 4x smaller
+	QuantizationBinary = "binary" // one bit per dimension, 32x smaller
+)
+
+// ErrCollectionMismatch is returned when an existing collection can't hold
+// the configured fingerprints.
+var ErrCollectionMismatch = errors.New("vector collection does not match the configuration")
+
+// CollectionConfig lays out the Qdrant collection. Zero values keep the
+// defaults: DefaultCollection, cosine distance, Qdrant's own HNSW
+// parameters and no quantisation.
+type CollectionConfig struct {
+	Name string
+
+	// Distance is "cosine" or "dot". Thresholds are cosine similarities,
+	// so a dot collection is sent L2-normalised vectors and scores the
+	// same; metrics that aren't similarities are refused.
+	Distance string
+
+	// HNSW index: links per node and the candidate list sizes used while
+	// building the index and while searching it. Zero leaves Qdrant's.
+	HNSWM           int
+	HNSWEfConstruct int
+	HNSWEf          int
+
+	// Quantization is "" (none), "scalar" or "binary". Quantised searches
+	// rescore their candidates with the original vectors.
+	Quantization string
+}
+
+// withDefaults fills in the zero values and validates c.
+func (c CollectionConfig) withDefaults() (CollectionConfig, error) {
+	if c.Name == "" {
+		c.Name = DefaultCollection
+	}
+	if c.Distance == "" {
+		c.Distance = DistanceCosine
+	}
+	if c.Distance != DistanceCosine && c.Distance != DistanceDot {
+		return c, fmt.Errorf("unknown vector distance %q, expected cosine or dot", c.Distance)
+	}
+	if c.HNSWM < 0 || c.HNSWEfConstruct < 0 || c.HNSWEf < 0 {
+		return c, errors.New("HNSW parameters must not be negative")
+	}
+	switch c.Quantization {
+	case "", QuantizationScalar, QuantizationBinary:
+	default:
+		return c, fmt.Errorf("unknown quantization %q, expected scalar or binary", c.Quantization)
+	}
+	return c, nil
+}
+
+// distance is the configured metric as Qdrant's enum.
+func (c CollectionConfig) distance() qdrant.Distance {
+	if c.Distance == DistanceDot {
+		return qdrant.Distance_Dot
+	}
+	return qdrant.Distance_Cosine
+}
+
+// hnsw is the HNSW part of the configuration, nil when none is set.
+func (c CollectionConfig) hnsw() *qdrant.HnswConfigDiff {
+	if c.HNSWM == 0 && c.HNSWEfConstruct == 0 {
+		return nil
+	}
+	h := &qdrant.HnswConfigDiff{}
+	if c.HNSWM > 0 {
+		h.M = qdrant.PtrOf(uint64(c.HNSWM))
+	}
+	if c.HNSWEfConstruct > 0 {
+		h.EfConstruct = qdrant.PtrOf(uint64(c.HNSWEfConstruct))
+	}
+	return h
+}
+
+// quantization is the configured quantisation, nil for none.
+func (c CollectionConfig) quantization() *qdrant.QuantizationConfig {
+	switch c.Quantization {
+	case QuantizationScalar:
+		return qdrant.NewQuantizationScalar(&qdrant.ScalarQuantization{Type: qdrant.QuantizationType_Int8})
+	case QuantizationBinary:
+		return qdrant.NewQuantizationBinary(&qdrant.BinaryQuantization{})
+	}
+	return nil
+}
+
+// searchParams are the per-query settings, nil when Qdrant's defaults do.
+func (c CollectionConfig) searchParams() *qdrant.SearchParams {
+	if c.HNSWEf == 0 && c.Quantization == "" {
+		return nil
+	}
+	p := &qdrant.SearchParams{}
+	if c.HNSWEf > 0 {
+		p.HnswEf = qdrant.PtrOf(uint64(c.HNSWEf))
+	}
+	if c.Quantization != "" {
+		p.Quantization = &qdrant.QuantizationSearchParams{Rescore: qdrant.PtrOf(true)}
+	}
+	return p
+}
+
+// checkCollection verifies that an existing collection holds fingerprint
+// sized vectors at the configured distance, and records its vector names.
+func (q *QdrantDB) checkCollection(info *qdrant.CollectionInfo) error {
+	vectors := info.GetConfig().GetParams().GetVectorsConfig()
+
+	params := map[string]*qdrant.VectorParams{VectorStructure: vectors.GetParams()}
+	q.names = nil
+	if named := vectors.GetParamsMap(); named != nil {
+		params = named.GetMap()
+		q.names = make(map[string]bool, len(params))
+		for name := range params {
+			q.names[name] = true
+		}
+		if !q.names[VectorStructure] {
+			return fmt.Errorf("%w: collection %q has no %s vector", ErrCollectionMismatch, q.cfg.Name, VectorStructure)
+		}
+	}
+
+	for name, p := range params {
+		size, ok := vectorSizes[name]
+		if !ok {
+			continue // not ours
+		}
+		if p.GetSize() != uint64(size) {
+			return fmt.Errorf("%w: collection %q holds %d-D %s vectors, fingerprints are %d-D",
+				ErrCollectionMismatch, q.cfg.Name, p.GetSize(), name, size)
+		}
+		if p.GetDistance() != q.cfg.distance() {
+			return fmt.Errorf("%w: collection %q compares %s vectors by %s, configured %s",
+				ErrCollectionMismatch, q.cfg.Name, name, p.GetDistance(), q.cfg.Distance)
+		}
+	}
+	return nil
+}
+
+// updateIndexing applies configured HNSW and quantisation settings that
+// the existing collection doesn't have yet.
+func (q *QdrantDB) updateIndexing(ctx context.Context, info *qdrant.CollectionInfo) error {
+	current := info.GetConfig()
+	update := &qdrant.UpdateCollection{CollectionName: q.cfg.Name}
+	changed := false
+
+	if h := q.cfg.hnsw(); h != nil {
+		have := current.GetHnswConfig()
+		if (h.M != nil && h.GetM() != have.GetM()) || (h.EfConstruct != nil && h.GetEfConstruct() != have.GetEfConstruct()) {
+			update.HnswConfig = h
+			changed = true
+		}
+	}
+
+	have := current.GetQuantizationConfig()
+	switch q.cfg.Quantization {
+	case QuantizationScalar:
+		if have.GetScalar() == nil {
+			update.QuantizationConfig = qdrant.NewQuantizationDiffScalar(q.cfg.quantization().GetScalar())
+			changed = true
+		}
+	case QuantizationBinary:
+		if have.GetBinary() == nil {
+			update.QuantizationConfig = qdrant.NewQuantizationDiffBinary(q.cfg.quantization().GetBinary())
+			changed = true
+		}
+	default:
+		if have.GetQuantization() != nil {
+			update.QuantizationConfig = qdrant.NewQuantizationDiffDisabled()
+			changed = true
+		}
+	}
+
+	if !changed {
+		return nil
+	}
+	if err := q.client.UpdateCollection(ctx, update); err != nil {
+		return fmt.Errorf("update collection indexing: %w", err)
+	}
+	return nil
+}
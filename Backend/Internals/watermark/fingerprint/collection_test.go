@@ -0,0 +1,90 @@
+package fingerprint
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/qdrant/go-client/qdrant"
+)
+
+func collectionInfo(vectors *qdrant.VectorsConfig) *qdrant.CollectionInfo {
+	return &qdrant.CollectionInfo{Config: &qdrant.CollectionConfig{Params: &qdrant.CollectionParams{VectorsConfig: vectors}}}
+}
+
+func TestCheckCollection(t *testing.T) {
+	cosine := func(size int) *qdrant.VectorParams {
+		return &qdrant.VectorParams{Size: uint64(size), Distance: qdrant.Distance_Cosine}
+	}
+	tests := []struct {
+		name    string
+		vectors *qdrant.VectorsConfig
+		names   int // named vectors recorded; 0 for a legacy collection
+		ok      bool
+	}{
+		{"named", qdrant.NewVectorsConfigMap(map[string]*qdrant.VectorParams{
+			VectorStructure: cosine(vectorSize), VectorColour: cosine(colourVectorSize),
+		}), 2, true},
+		{"legacy", qdrant.NewVectorsConfig(cosine(vectorSize)), 0, true},
+		{"legacy wrong size", qdrant.NewVectorsConfig(cosine(512)), 0, false},
+		{"colour wrong size", qdrant.NewVectorsConfigMap(map[string]*qdrant.VectorParams{
+			VectorStructure: cosine(vectorSize), VectorColour: cosine(64),
+		}), 2, false},
+		{"no structure vector", qdrant.NewVectorsConfigMap(map[string]*qdrant.VectorParams{
+			VectorColour: cosine(colourVectorSize),
+		}), 1, false},
+		{"other distance", qdrant.NewVectorsConfig(&qdrant.VectorParams{Size: vectorSize, Distance: qdrant.Distance_Euclid}), 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, _ := CollectionConfig{}.withDefaults()
+			q := &QdrantDB{cfg: cfg}
+			err := q.checkCollection(collectionInfo(tt.vectors))
+			if tt.ok && err != nil {
+				t.Fatal(err)
+			}
+			if !tt.ok && !errors.Is(err, ErrCollectionMismatch) {
+				t.Fatalf("err = %v, want ErrCollectionMismatch", err)
+			}
+			if tt.ok && len(q.names) != tt.names {
+				t.Fatalf("recorded vectors %v, want %d", q.names, tt.names)
+			}
+		})
+	}
+}
+
+func TestCollectionConfigDefaults(t *testing.T) {
+	cfg, err := CollectionConfig{}.withDefaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Name != DefaultCollection || cfg.distance() != qdrant.Distance_Cosine {
+		t.Fatalf("defaults = %+v", cfg)
+	}
+	if cfg.hnsw() != nil || cfg.quantization() != nil || cfg.searchParams() != nil {
+		t.Fatal("defaults should leave HNSW, quantisation and search parameters to Qdrant")
+	}
+
+	tuned, err := CollectionConfig{Distance: DistanceDot, HNSWM: 32, HNSWEf: 128, Quantization: QuantizationScalar}.withDefaults()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tuned.hnsw().GetM() != 32 || tuned.hnsw().EfConstruct != nil {
+		t.Fatalf("hnsw = %v, want m=32 only", tuned.hnsw())
+	}
+	if p := tuned.searchParams(); p.GetHnswEf() != 128 || !p.GetQuantization().GetRescore() {
+		t.Fatalf("search params = %v", p)
+	}
+	if tuned.quantization().GetScalar() == nil {
+		t.Fatal("scalar quantisation not configured")
+	}
+
+	for _, bad := range []CollectionConfig{
+		{Distance: "euclid"},
+		{Quantization: "product"},
+		{HNSWM: -1},
+	} {
+		if _, err := bad.withDefaults(); err == nil {
+			t.Fatalf("%+v accepted", bad)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package fingerprint
+
+// colour.go — Colour-aware fingerprint component
+//
+// The 1024-D fingerprint only sees luminance, so a recoloured copy and an
+// unrelated picture with the same layout look alike. The colour vector is
+// stored next to it as a second named vector and describes the chroma
+// planes of ConvertToYC:
+//
+//   32  chroma layout: 4x4 lowest DCT coefficients of an 8x8 grid of mean
+//       Cb and of mean Cr (both centred on neutral grey)
+//   64  chroma histogram: 8x8 bins over (Cb, Cr), fraction of pixels
+//
+// Each half is scaled to unit length so neither dominates the cosine. A
+// grey image has no layout energy (below about one chroma level, which is
+// rounding noise) and is described by its histogram alone.
+// Queries blend the structure and colour similarities with Weights.
+
+import (
+	"context"
+	"image"
+	"math"
+	"sort"
+
+	"github.com/google/uuid"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/engine"
+)
+
+// Names of the vectors kept per image. Store/Query work on
+// VectorStructure.
+const (
+	VectorStructure = "structure"
+	VectorColour    = "colour"
+)
+
+const (
+	colourGrid       = 8 // cells per side of the chroma layout
+	colourCoeffs     = 4 // DCT coefficients kept per side and channel
+	colourHistBins   = 8 // histogram bins per chroma axis
+	colourBinWidth   = 16
+	colourVectorSize = 2*colourCoeffs*colourCoeffs + colourHistBins*colourHistBins
+
+	// minLayoutNorm is the layout length of a uniform one-level chroma
+	// shift (the orthonormal DC of an 8x8 grid is 8x its mean).
+	minLayoutNorm = colourGrid
+)
+
+// vectorSizes is the dimension of every named vector.
+var vectorSizes = map[string]int{
+	VectorStructure: vectorSize,
+	VectorColour:    colourVectorSize,
+}
+
+// CreateColourFingerprint computes the 96-D colour vector of img.
+func CreateColourFingerprint(img image.Image) Vector {
+	const side = 256
+	const cell = side / colourGrid
+
+	ycb, _ := engine.ConvertToYC(ResizeImage(img, side, side))
+
+	var cb, cr [colourGrid][colourGrid]float64
+	hist := make([]float64, colourHistBins*colourHistBins)
+	for y := 0; y < side; y++ {
+		for x := 0; x < side; x++ {
+			o := ycb.COffset(x, y)
+			b := float64(ycb.Cb[o]) - 128
+			r := float64(ycb.Cr[o]) - 128
+
+			cb[y/cell][x/cell] += b
+			cr[y/cell][x/cell] += r
+			hist[chromaBin(b)*colourHistBins+chromaBin(r)]++
+		}
+	}
+
+	vec := make([]float64, 0, colourVectorSize)
+	for _, plane := range []*[colourGrid][colourGrid]float64{&cb, &cr} {
+		for i := range plane {
+			for j := range plane[i] {
+				plane[i][j] /= cell * cell
+			}
+		}
+		vec = append(vec, lowDCT(plane)...)
+	}
+	if l2Norm(vec) < minLayoutNorm {
+		clear(vec)
+	}
+	unitLength(vec)
+	unitLength(hist)
+	return NewVector(append(vec, hist...))
+}
+
+// chromaBin maps a centred chroma value to its histogram bin. The bins
+// cover ±64 around neutral, where nearly all real colours fall; the outer
+// bins take the rest.
+func chromaBin(v float64) int {
+	bin := int(math.Floor(v/colourBinWidth)) + colourHistBins/2
+	if bin < 0 {
+		return 0
+	}
+	if bin >= colourHistBins {
+		return colourHistBins - 1
+	}
+	return bin
+}
+
+// lowDCT returns the colourCoeffs x colourCoeffs lowest coefficients of
+// the orthonormal 2D DCT-II of an 8x8 grid, row-major.
+func lowDCT(m *[colourGrid][colourGrid]float64) []float64 {
+	const n = colourGrid
+	out := make([]float64, 0, colourCoeffs*colourCoeffs)
+	for v := 0; v < colourCoeffs; v++ {
+		for u := 0; u < colourCoeffs; u++ {
+			sum := 0.0
+			for y := 0; y < n; y++ {
+				for x := 0; x < n; x++ {
+					sum += m[y][x] *
+						math.Cos(float64(2*x+1)*float64(u)*math.Pi/(2*n)) *
+						math.Cos(float64(2*y+1)*float64(v)*math.Pi/(2*n))
+				}
+			}
+			out = append(out, sum*dctScale(u, n)*dctScale(v, n))
+		}
+	}
+	return out
+}
+
+func dctScale(k, n int) float64 {
+	if k == 0 {
+		return math.Sqrt(1 / float64(n))
+	}
+	return math.Sqrt(2 / float64(n))
+}
+
+func l2Norm(vec []float64) float64 {
+	sum := 0.0
+	for _, v := range vec {
+		sum += v * v
+	}
+	return math.Sqrt(sum)
+}
+
+// unitLength scales vec to length 1 in place; a zero vector stays zero.
+func unitLength(vec []float64) {
+	n := l2Norm(vec)
+	if n == 0 {
+		return
+	}
+	for i := range vec {
+		vec[i] /= n
+	}
+}
+
+// Weights blends structure and colour similarity. Only the ratio matters;
+// a zero Colour weight is a plain structure query.
+type Weights struct {
+	Structure float64
+	Colour    float64
+}
+
+// WeightedMatch is one image ranked by blended similarity.
+type WeightedMatch struct {
+	ImageID        uuid.UUID
+	Score          float32
+	StructureScore float32
+	ColourScore    float32
+}
+
+// CandidatePool is how many hits of each vector are fetched before
+// blending, so images ranked moderately on both still surface.
+func CandidatePool(k int) int {
+	return max(4*k, 50)
+}
+
+// CombineScores blends two ranked hit lists by w. An image found by only
+// one search gets, for the other, the lowest score that search returned:
+// it ranked below all of those, so this is an upper bound. An empty list
+// contributes nothing.
+func CombineScores(
+	structIDs []uuid.UUID, structScores []float32,
+	colourIDs []uuid.UUID, colourScores []float32,
+	w Weights,
+) []WeightedMatch {
+
+	floor := func(scores []float32) float32 {
+		if len(scores) == 0 {
+			return 0
+		}
+		return scores[len(scores)-1]
+	}
+
+	byID := make(map[uuid.UUID]*WeightedMatch)
+	get := func(id uuid.UUID) *WeightedMatch {
+		m, ok := byID[id]
+		if !ok {
+			m = &WeightedMatch{ImageID: id, StructureScore: floor(structScores), ColourScore: floor(colourScores)}
+			byID[id] = m
+		}
+		return m
+	}
+	for i, id := range structIDs {
+		get(id).StructureScore = structScores[i]
+	}
+	for i, id := range colourIDs {
+		get(id).ColourScore = colourScores[i]
+	}
+
+	total := w.Structure + w.Colour
+	matches := make([]WeightedMatch, 0, len(byID))
+	for _, m := range byID {
+		if total > 0 {
+			m.Score = float32((w.Structure*float64(m.StructureScore) + w.Colour*float64(m.ColourScore)) / total)
+		}
+		matches = append(matches, *m)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ImageID.String() < matches[j].ImageID.String()
+	})
+	return matches
+}
+
+// FindSimilarColour returns the k images whose colour vectors are closest
+// to queryImg's.
+func FindSimilarColour(ctx context.Context, store VectorStore, queryImg image.Image, k int, opts QueryOptions) ([]uuid.UUID, []float32, error) {
+	return store.QueryNamed(ctx, VectorColour, CreateColourFingerprint(queryImg), k, opts)
+}
@@ -0,0 +1,144 @@
+package fingerprint
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// swapRedBlue keeps the layout but moves every colour to another hue.
+func swapRedBlue(img *image.RGBA) *image.RGBA {
+	out := image.NewRGBA(img.Bounds())
+	for i := 0; i < len(img.Pix); i += 4 {
+		out.Pix[i], out.Pix[i+1], out.Pix[i+2], out.Pix[i+3] = img.Pix[i+2], img.Pix[i+1], img.Pix[i], img.Pix[i+3]
+	}
+	return out
+}
+
+func TestColourFingerprintSeparatesRecolouredCopies(t *testing.T) {
+	base := scene(320, 240, 0)
+	recoloured := swapRedBlue(base)
+
+	colour := CreateColourFingerprint(base)
+	if len(colour) != colourVectorSize {
+		t.Fatalf("colour vector has %d values, want %d", len(colour), colourVectorSize)
+	}
+
+	if c := cosine(colour, CreateColourFingerprint(jpegCopy(t, base, 80))); c < 0.95 {
+		t.Errorf("colour similarity to a JPEG copy = %.3f, want >= 0.95", c)
+	}
+	if c := cosine(colour, CreateColourFingerprint(recoloured)); c > 0.8 {
+		t.Errorf("colour similarity to a recoloured copy = %.3f, want <= 0.8", c)
+	}
+	if c := cosine(mustFingerprint(t, base, V3), mustFingerprint(t, recoloured, V3)); c < 0.9 {
+		t.Errorf("structure similarity to a recoloured copy = %.3f, want >= 0.9", c)
+	}
+}
+
+func TestColourFingerprintOfGreyImage(t *testing.T) {
+	grey := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 64; x++ {
+			v := uint8(x * 4)
+			grey.SetRGBA(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+
+	vec := CreateColourFingerprint(grey)
+	layout, hist := vec[:colourVectorSize-colourHistBins*colourHistBins], vec[colourVectorSize-colourHistBins*colourHistBins:]
+	for i, v := range layout {
+		if math.Abs(float64(v)) > 1e-9 {
+			t.Fatalf("layout[%d] = %g for a grey image, want 0", i, v)
+		}
+	}
+	sum := 0.0
+	for _, v := range hist {
+		sum += float64(v) * float64(v)
+	}
+	if math.Abs(sum-1) > 1e-6 { // float32 precision
+		t.Fatalf("histogram length² = %g, want 1", sum)
+	}
+}
+
+func TestCombineScores(t *testing.T) {
+	a, b, c := uuid.New(), uuid.New(), uuid.New()
+
+	structIDs, structScores := []uuid.UUID{a, b}, []float32{0.9, 0.6}
+	colourIDs, colourScores := []uuid.UUID{c, a}, []float32{0.95, 0.5}
+
+	got := CombineScores(structIDs, structScores, colourIDs, colourScores, Weights{Structure: 1, Colour: 1})
+	want := []WeightedMatch{
+		{ImageID: a, Score: 0.7, StructureScore: 0.9, ColourScore: 0.5},
+		{ImageID: c, Score: 0.775, StructureScore: 0.6, ColourScore: 0.95}, // structure: floor
+		{ImageID: b, Score: 0.55, StructureScore: 0.6, ColourScore: 0.5},   // colour: floor
+	}
+	// Sort want the way CombineScores does.
+	want[0], want[1] = want[1], want[0]
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d matches, want %d", len(got), len(want))
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.ImageID != w.ImageID || math.Abs(float64(g.Score-w.Score)) > 1e-6 ||
+			g.StructureScore != w.StructureScore || g.ColourScore != w.ColourScore {
+			t.Fatalf("match %d = %+v, want %+v", i, g, w)
+		}
+	}
+
+	structOnly := CombineScores(structIDs, structScores, colourIDs, colourScores, Weights{Structure: 1})
+	if structOnly[0].ImageID != a || structOnly[0].Score != 0.9 {
+		t.Fatalf("structure-only blend ranked %+v first", structOnly[0])
+	}
+}
+
+func testColourVector(seed int) Vector {
+	vec := make(Vector, colourVectorSize)
+	for i := range vec {
+		vec[i] = float32((i*seed)%11) - 5
+	}
+	return vec
+}
+
+func TestMemoryStoreNamedVectors(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewMemoryStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id := uuid.New()
+	if err := store.Store(ctx, id, testVector(3), V3); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.StoreNamed(ctx, id, VectorColour, testColourVector(2)); err != nil {
+		t.Fatal(err)
+	}
+
+	// Adding the colour vector leaves the structure vector in place.
+	if got, scores, _ := store.Query(ctx, testVector(3), 1); len(got) != 1 || scores[0] < 0.9999 {
+		t.Fatalf("structure query after StoreNamed = %v %v", got, scores)
+	}
+	if got, scores, _ := store.QueryNamed(ctx, VectorColour, testColourVector(2), 1, QueryOptions{}); len(got) != 1 || got[0] != id || scores[0] < 0.9999 {
+		t.Fatalf("colour query = %v %v", got, scores)
+	}
+
+	if err := store.StoreNamed(ctx, id, "texture", testColourVector(2)); !errors.Is(err, ErrUnknownVector) {
+		t.Fatalf("StoreNamed(unknown) = %v, want ErrUnknownVector", err)
+	}
+	if err := store.StoreNamed(ctx, id, VectorColour, testVector(2)); err == nil {
+		t.Fatal("StoreNamed accepted a structure-sized colour vector")
+	}
+
+	if err := store.Delete(ctx, id); err != nil {
+		t.Fatal(err)
+	}
+	if got, _, _ := store.QueryNamed(ctx, VectorColour, testColourVector(2), 1, QueryOptions{}); len(got) != 0 {
+		t.Fatalf("colour vector survived Delete: %v", got)
+	}
+}
@@ -29,13 +29,21 @@ func ResizeImage(img image.Image, width, height int) *image.RGBA {
 	return dst
 }
 
-func Createfingerprint(img image.Image) []float64 { // Fixed: return the vector
-	const_matrices := CalculateConstant(4, 4) // Fixed: no longer a pointer, used directly
-
+// Createfingerprint computes the original (version 1) fingerprint. Use
+// CreateFingerprintVersion for the normalised variants.
+func Createfingerprint(img image.Image) Vector { // Fixed: return the vector
 	resized_img := ResizeImage(img, 256, 256)
 
 	_, Ymatrix := engine.ConvertToYC(resized_img)
 
+	return NewVector(blockCoefficients(Ymatrix))
+}
+
+// blockCoefficients returns the 4x4 lowest DCT coefficients of each of the
+// 8x8 grid of 32x32 blocks of a 256x256 Y matrix.
+func blockCoefficients(Ymatrix [][]float64) []float64 {
+	const_matrices := CalculateConstant(4, 4) // Fixed: no longer a pointer, used directly
+
 	vector1024d := []float64{} // Fixed: missing {} for slice literal
 
 	for i := 0; i < 8; i++ {
@@ -0,0 +1,391 @@
+package fingerprint
+
+// memory_store.go — In-process VectorStore
+//
+// A brute-force cosine index that needs no external service. Vectors are
+// kept L2-normalised as float32 (like Qdrant does for cosine collections),
+// so a query is one dot product per stored image. That is fast enough for
+// the tens of thousands of images a development database holds.
+//
+// When a path is given the whole index is rewritten to it (atomically,
+// via a temp file) after every change and loaded again on start-up.
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// MemoryStore is a VectorStore held entirely in memory.
+type MemoryStore struct {
+	path string
+
+	mu         sync.RWMutex
+	vectors    map[string]map[uuid.UUID][]float32 // by vector name
+	versions   map[uuid.UUID]Version              // of the structure vectors
+	attributes map[uuid.UUID]Attributes
+}
+
+// memoryFile is the persisted form of a MemoryStore.
+type memoryFile struct {
+	Vectors    map[string]map[uuid.UUID][]float32
+	Versions   map[uuid.UUID]Version
+	Attributes map[uuid.UUID]Attributes
+}
+
+// NewMemoryStore returns an empty store, or the one previously saved at
+// path. An empty path disables persistence.
+func NewMemoryStore(path string) (*MemoryStore, error) {
+	m := &MemoryStore{
+		path:       path,
+		vectors:    make(map[string]map[uuid.UUID][]float32),
+		versions:   make(map[uuid.UUID]Version),
+		attributes: make(map[uuid.UUID]Attributes),
+	}
+	for name := range vectorSizes {
+		m.vectors[name] = make(map[uuid.UUID][]float32)
+	}
+	if path == "" {
+		return m, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open vector store file: %w", err)
+	}
+
+	var file memoryFile
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&file); err != nil {
+		return nil, fmt.Errorf("load vector store file: %w", err)
+	}
+	for name, vecs := range file.Vectors {
+		if _, ok := vectorSizes[name]; ok && vecs != nil {
+			m.vectors[name] = vecs
+		}
+	}
+	if file.Versions != nil {
+		m.versions = file.Versions
+	}
+	if file.Attributes != nil {
+		m.attributes = file.Attributes
+	}
+	return m, nil
+}
+
+// Close releases nothing; every change has already been saved.
+func (m *MemoryStore) Close() error { return nil }
+
+// Store saves a 1024-D fingerprint vector of version v for imageID.
+func (m *MemoryStore) Store(ctx context.Context, imageID uuid.UUID, vec Vector, v Version) error {
+	if len(vec) != vectorSize {
+		return fmt.Errorf("fingerprint must be %d-dimensional, got %d", vectorSize, len(vec))
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.vectors[VectorStructure][imageID] = normalise(vec)
+	m.versions[imageID] = v
+	return m.save()
+}
+
+// StoreNamed saves one named vector for imageID.
+func (m *MemoryStore) StoreNamed(ctx context.Context, imageID uuid.UUID, name string, vec Vector) error {
+	if err := checkNamed(name, vec); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.vectors[name][imageID] = normalise(vec)
+	return m.save()
+}
+
+// SetAttributes replaces the filterable attributes of imageID.
+func (m *MemoryStore) SetAttributes(ctx context.Context, imageID uuid.UUID, attrs Attributes) error {
+	attrs.Tags = slices.Clone(attrs.Tags)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.attributes[imageID] = attrs
+	return m.save()
+}
+
+// Get returns the structure vector of imageID and its version.
+func (m *MemoryStore) Get(ctx context.Context, imageID uuid.UUID) (Vector, Version, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stored, ok := m.vectors[VectorStructure][imageID]
+	if !ok {
+		return nil, 0, nil
+	}
+	return Vector(stored).Clone(), m.versions[imageID], nil
+}
+
+// Delete removes the vectors of imageID, if any.
+func (m *MemoryStore) Delete(ctx context.Context, imageID uuid.UUID) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.versions, imageID)
+	_, found := m.attributes[imageID]
+	delete(m.attributes, imageID)
+	for _, vecs := range m.vectors {
+		if _, ok := vecs[imageID]; ok {
+			delete(vecs, imageID)
+			found = true
+		}
+	}
+	if !found {
+		return nil
+	}
+	return m.save()
+}
+
+// Query scores every structure vector against vec and returns the best k.
+func (m *MemoryStore) Query(ctx context.Context, vec Vector, k int) ([]uuid.UUID, []float32, error) {
+	if len(vec) != vectorSize {
+		return nil, nil, fmt.Errorf("query vector must be %d-dimensional, got %d", vectorSize, len(vec))
+	}
+	return m.QueryNamed(ctx, VectorStructure, vec, k, QueryOptions{})
+}
+
+// QueryNamed scores every vector of the given name against vec and
+// returns the best k after opts.Offset, leaving out excluded images, those
+// not matching opts.Filter and those below a non-zero opts.MinScore.
+func (m *MemoryStore) QueryNamed(ctx context.Context, name string, vec Vector, k int, opts QueryOptions) ([]uuid.UUID, []float32, error) {
+	if err := checkNamed(name, vec); err != nil {
+		return nil, nil, err
+	}
+	if k <= 0 {
+		return nil, nil, nil
+	}
+
+	q := normalise(vec)
+
+	type hit struct {
+		id    uuid.UUID
+		score float32
+	}
+
+	excluded := make(map[uuid.UUID]bool, len(opts.Exclude))
+	for _, id := range opts.Exclude {
+		excluded[id] = true
+	}
+
+	m.mu.RLock()
+	hits := make([]hit, 0, len(m.vectors[name]))
+	for id, v := range m.vectors[name] {
+		if excluded[id] {
+			continue
+		}
+		if attrs, ok := m.attributes[id]; !opts.Filter.Matches(attrs, ok) {
+			continue
+		}
+		if score := dot(q, v); opts.MinScore == 0 || score >= opts.MinScore {
+			hits = append(hits, hit{id: id, score: score})
+		}
+	}
+	m.mu.RUnlock()
+
+	// Highest score first; ties broken by ID so results are stable.
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].score != hits[j].score {
+			return hits[i].score > hits[j].score
+		}
+		return hits[i].id.String() < hits[j].id.String()
+	})
+	hits = hits[min(opts.Offset, len(hits)):]
+	if len(hits) > k {
+		hits = hits[:k]
+	}
+
+	ids := make([]uuid.UUID, len(hits))
+	scores := make([]float32, len(hits))
+	for i, h := range hits {
+		ids[i] = h.id
+		scores[i] = h.score
+	}
+	return ids, scores, nil
+}
+
+// Count returns the number of stored structure vectors.
+func (m *MemoryStore) Count(ctx context.Context) (uint64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return uint64(len(m.vectors[VectorStructure])), nil
+}
+
+// Scroll visits the structure vectors in ID order. It works on a snapshot of
+// the IDs, so fn may modify the store.
+func (m *MemoryStore) Scroll(ctx context.Context, fn func(imageID uuid.UUID, vec Vector, v Version) error) error {
+	m.mu.RLock()
+	ids := make([]uuid.UUID, 0, len(m.vectors[VectorStructure]))
+	for id := range m.vectors[VectorStructure] {
+		ids = append(ids, id)
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		m.mu.RLock()
+		stored, ok := m.vectors[VectorStructure][id]
+		v := m.versions[id]
+		m.mu.RUnlock()
+		if !ok {
+			continue // deleted meanwhile
+		}
+
+		if err := fn(id, Vector(stored).Clone(), v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// StoreBatch replaces the given points and saves the index once.
+func (m *MemoryStore) StoreBatch(ctx context.Context, points []Point) error {
+	for _, p := range points {
+		if err := checkPoint(p); err != nil {
+			return err
+		}
+	}
+	if len(points) == 0 {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, p := range points {
+		for name, vecs := range m.vectors {
+			if vec, ok := p.Vectors[name]; ok {
+				vecs[p.ID] = normalise(vec)
+			} else {
+				delete(vecs, p.ID)
+			}
+		}
+		m.versions[p.ID] = p.Version
+		if p.Attributes != nil {
+			attrs := *p.Attributes
+			attrs.Tags = slices.Clone(attrs.Tags)
+			m.attributes[p.ID] = attrs
+		} else {
+			delete(m.attributes, p.ID)
+		}
+	}
+	return m.save()
+}
+
+// ScrollPoints visits the points in ID order, on a snapshot of the IDs
+// like Scroll.
+func (m *MemoryStore) ScrollPoints(ctx context.Context, fn func(Point) error) error {
+	m.mu.RLock()
+	ids := make([]uuid.UUID, 0, len(m.vectors[VectorStructure]))
+	for id := range m.vectors[VectorStructure] {
+		ids = append(ids, id)
+	}
+	m.mu.RUnlock()
+
+	sort.Slice(ids, func(i, j int) bool { return ids[i].String() < ids[j].String() })
+
+	for _, id := range ids {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		m.mu.RLock()
+		p := Point{ID: id, Version: m.versions[id], Vectors: make(map[string]Vector, len(m.vectors))}
+		for name, vecs := range m.vectors {
+			if stored, ok := vecs[id]; ok {
+				p.Vectors[name] = Vector(stored).Clone()
+			}
+		}
+		if attrs, ok := m.attributes[id]; ok {
+			attrs.Tags = slices.Clone(attrs.Tags)
+			p.Attributes = &attrs
+		}
+		m.mu.RUnlock()
+		if p.Vectors[VectorStructure] == nil {
+			continue // deleted meanwhile
+		}
+
+		if err := fn(p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// save writes the index to m.path. Callers hold m.mu.
+func (m *MemoryStore) save() error {
+	if m.path == "" {
+		return nil
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(m.path), filepath.Base(m.path)+".*")
+	if err != nil {
+		return fmt.Errorf("save vector store: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if err := gob.NewEncoder(tmp).Encode(memoryFile{Vectors: m.vectors, Versions: m.versions, Attributes: m.attributes}); err != nil {
+		tmp.Close()
+		return fmt.Errorf("save vector store: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("save vector store: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), m.path); err != nil {
+		return fmt.Errorf("save vector store: %w", err)
+	}
+	return nil
+}
+
+// normalise converts vec to a unit-length float32 vector. A zero vector
+// stays zero and so scores 0 against everything.
+func normalise(vec Vector) []float32 {
+	var sum float64
+	for _, v := range vec {
+		sum += float64(v) * float64(v)
+	}
+	norm := math.Sqrt(sum)
+
+	out := make([]float32, len(vec))
+	if norm == 0 {
+		return out
+	}
+	for i, v := range vec {
+		out[i] = float32(float64(v) / norm)
+	}
+	return out
+}
+
+func dot(a, b []float32) float32 {
+	var sum float32
+	for i := range a {
+		sum += a[i] * b[i]
+	}
+	return sum
+}
@@ -0,0 +1,213 @@
+package fingerprint
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func testVector(seed int) Vector {
+	vec := make(Vector, vectorSize)
+	for i := range vec {
+		vec[i] = float32((i*seed)%17) - 8
+	}
+	return vec
+}
+
+func TestMemoryStoreQueryRanksBySimilarity(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewMemoryStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New()}
+	for i, id := range ids {
+		if err := store.Store(ctx, id, testVector(i+2), V3); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, scores, err := store.Query(ctx, testVector(3), 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0] != ids[1] {
+		t.Fatalf("Query = %v, want %v first", got, ids[1])
+	}
+	if scores[0] < 0.9999 || scores[1] >= scores[0] {
+		t.Fatalf("unexpected scores %v", scores)
+	}
+
+	if err := store.Delete(ctx, ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := store.Count(ctx); n != 2 {
+		t.Fatalf("Count = %d after delete, want 2", n)
+	}
+}
+
+func TestMemoryStoreQueryOptions(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewMemoryStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New(), uuid.New()}
+	for i, id := range ids {
+		if err := store.Store(ctx, id, testVector(i+2), V3); err != nil {
+			t.Fatal(err)
+		}
+	}
+	all, scores, err := store.Query(ctx, testVector(3), 4)
+	if err != nil || len(all) != 4 {
+		t.Fatalf("Query = %v, %v", all, err)
+	}
+
+	page, _, err := store.QueryNamed(ctx, VectorStructure, testVector(3), 2, QueryOptions{Offset: 1})
+	if err != nil || len(page) != 2 || page[0] != all[1] || page[1] != all[2] {
+		t.Fatalf("Offset 1 = %v, want %v", page, all[1:3])
+	}
+
+	rest, _, err := store.QueryNamed(ctx, VectorStructure, testVector(3), 4, QueryOptions{Exclude: []uuid.UUID{all[0]}})
+	if err != nil || len(rest) != 3 || rest[0] != all[1] {
+		t.Fatalf("Exclude best = %v, want %v", rest, all[1:])
+	}
+
+	strong, _, err := store.QueryNamed(ctx, VectorStructure, testVector(3), 4, QueryOptions{MinScore: scores[1]})
+	if err != nil || len(strong) != 2 {
+		t.Fatalf("MinScore %v kept %v, want the best two", scores[1], strong)
+	}
+}
+
+func TestMemoryStoreFilter(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewMemoryStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jan := time.Date(2025, 1, 10, 12, 0, 0, 0, time.UTC)
+	attrs := []Attributes{
+		{Owner: "acme", MimeType: "image/jpeg", Tags: []string{"news", "sport"}, CreatedAt: jan},
+		{Owner: "acme", IsAIGenerated: true, MimeType: "image/png", Tags: []string{"news"}, CreatedAt: jan},
+		{Owner: "other", MimeType: "image/jpeg", CreatedAt: jan.AddDate(-1, 0, 0)},
+	}
+	ids := []uuid.UUID{uuid.New(), uuid.New(), uuid.New(), uuid.New()} // the last has no attributes
+	for i, id := range ids {
+		if err := store.Store(ctx, id, testVector(i+2), V3); err != nil {
+			t.Fatal(err)
+		}
+		if i < len(attrs) {
+			if err := store.SetAttributes(ctx, id, attrs[i]); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	human := false
+	tests := []struct {
+		name   string
+		filter *Filter
+		want   []uuid.UUID
+	}{
+		{"none", nil, ids},
+		{"empty", &Filter{}, ids},
+		{"owner", &Filter{Owner: "acme"}, ids[:2]},
+		{"not AI", &Filter{IsAIGenerated: &human}, []uuid.UUID{ids[0], ids[2]}},
+		{"mime type", &Filter{MimeType: "image/png"}, ids[1:2]},
+		{"all tags", &Filter{Tags: []string{"news", "sport"}}, ids[:1]},
+		{"created in 2025", &Filter{
+			CreatedFrom: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+			CreatedTo:   time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		}, ids[:2]},
+		{"created before", &Filter{CreatedTo: jan}, ids[2:3]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, _, err := store.QueryNamed(ctx, VectorStructure, testVector(3), 10, QueryOptions{Filter: tt.filter})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !sameIDs(got, tt.want) {
+				t.Fatalf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if err := store.Delete(ctx, ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Store(ctx, ids[0], testVector(2), V3); err != nil {
+		t.Fatal(err)
+	}
+	if got, _, _ := store.QueryNamed(ctx, VectorStructure, testVector(3), 10, QueryOptions{Filter: &Filter{Owner: "acme"}}); len(got) != 1 {
+		t.Fatalf("attributes survived Delete: %v", got)
+	}
+}
+
+// sameIDs compares two ID sets, ignoring order.
+func sameIDs(a, b []uuid.UUID) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := make(map[uuid.UUID]bool, len(a))
+	for _, id := range a {
+		seen[id] = true
+	}
+	for _, id := range b {
+		if !seen[id] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestMemoryStorePersists(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "vectors.gob")
+
+	store, err := NewMemoryStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := uuid.New()
+	if err := store.Store(ctx, id, testVector(5), V2); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetAttributes(ctx, id, Attributes{Owner: "acme"}); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened, err := NewMemoryStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := reopened.Query(ctx, testVector(5), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != id {
+		t.Fatalf("reopened store returned %v, want [%v]", got, id)
+	}
+	if vec, v, err := reopened.Get(ctx, id); err != nil || len(vec) != vectorSize || v != V2 {
+		t.Fatalf("Get after reopening = %d values, v%d, %v; want %d, v%d", len(vec), v, err, vectorSize, V2)
+	}
+	if got, _, _ := reopened.QueryNamed(ctx, VectorStructure, testVector(5), 1, QueryOptions{Filter: &Filter{Owner: "acme"}}); len(got) != 1 {
+		t.Fatal("attributes were not persisted")
+	}
+	if vec, v, err := reopened.Get(ctx, uuid.New()); vec != nil || v != 0 || err != nil {
+		t.Fatalf("Get(unknown) = %v, %d, %v; want nil, 0, nil", vec, v, err)
+	}
+}
+
+func TestMemoryStoreRejectsWrongDimension(t *testing.T) {
+	store, _ := NewMemoryStore("")
+	if err := store.Store(context.Background(), uuid.New(), make(Vector, 3), V3); err == nil {
+		t.Fatal("expected dimension error")
+	}
+}
@@ -0,0 +1,256 @@
+package fingerprint
+
+// normalise.go — Versioned fingerprint variants
+//
+// Version 1 is the original vector: 16 raw DCT coefficients (DC first) of
+// each 32x32 block. The DC term carries the block's mean brightness, so a
+// global brightness change moves every block and cosine similarity drops
+// for obvious copies. Later versions post-process the same coefficients:
+//
+//   v1  raw coefficients                             (default)
+//   v2  DC dropped, AC z-scored over the whole vector
+//   v3  DC dropped, AC z-scored per block            (recommended)
+//   v4  as v3, on a histogram-equalised image
+//
+// Every version keeps the 1024-D layout (dropped DC slots are zero), so
+// they all fit the same collection — but vectors of different versions
+// must never be compared with each other.
+//
+// v2 and v3 only transform the stored coefficients and are scale-invariant,
+// so MigrateVector can derive them from a stored v1 vector (even one the
+// vector store has L2-normalised). v4 changes the pixels before the DCT
+// and needs the source image.
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/engine"
+)
+
+// Version identifies how a fingerprint vector was computed.
+type Version int
+
+const (
+	V1 Version = 1
+	V2 Version = 2
+	V3 Version = 3
+	V4 Version = 4
+
+	// DefaultVersion is used wherever no version is configured. It is the
+	// version of stores written before versions were recorded, so an
+	// existing store is never switched implicitly; new deployments should
+	// choose V3.
+	DefaultVersion = V1
+)
+
+// coeffsPerBlock is the number of DCT coefficients kept per 32x32 block;
+// index 0 is the DC term.
+const coeffsPerBlock = 16
+
+// variant lists the processing steps of one version.
+type variant struct {
+	equalise   bool // histogram-equalise Y before the DCT
+	dropDC     bool // zero the DC coefficient of every block
+	blockZ     bool // z-score the AC coefficients of each block
+	globalZ    bool // z-score all AC coefficients together
+	derivedOf1 bool // computable from a v1 vector alone
+}
+
+var variants = map[Version]variant{
+	V1: {derivedOf1: true},
+	V2: {dropDC: true, globalZ: true, derivedOf1: true},
+	V3: {dropDC: true, blockZ: true, derivedOf1: true},
+	V4: {equalise: true, dropDC: true, blockZ: true},
+}
+
+// ErrNotDerivable means a stored vector can't be converted to the target
+// version; the image has to be fingerprinted again.
+var ErrNotDerivable = errors.New("fingerprint version cannot be derived from the stored vector")
+
+// Valid reports whether v is a known fingerprint version.
+func (v Version) Valid() bool {
+	_, ok := variants[v]
+	return ok
+}
+
+// CreateFingerprintVersion computes the 1024-D fingerprint of img as
+// defined by version v.
+func CreateFingerprintVersion(img image.Image, v Version) (Vector, error) {
+	Ymatrix, vr, err := prepareLuminance(img, v)
+	if err != nil {
+		return nil, err
+	}
+
+	vec := blockCoefficients(Ymatrix)
+	normaliseVector(vec, vr)
+	return NewVector(vec), nil
+}
+
+// prepareLuminance runs the pixel-level steps of version v: resize to
+// 256x256, convert to Y and, if the version asks for it, equalise.
+func prepareLuminance(img image.Image, v Version) ([][]float64, variant, error) {
+	vr, ok := variants[v]
+	if !ok {
+		return nil, vr, fmt.Errorf("unknown fingerprint version %d", v)
+	}
+
+	resized_img := ResizeImage(img, 256, 256)
+	_, Ymatrix := engine.ConvertToYC(resized_img)
+	if vr.equalise {
+		equaliseHistogram(Ymatrix)
+	}
+	return Ymatrix, vr, nil
+}
+
+// MigrateVector converts a stored vector of version from into version to
+// without the source image. It fails with ErrNotDerivable when the target
+// needs the pixels again.
+func MigrateVector(vec Vector, from, to Version) (Vector, error) {
+	if len(vec) != vectorSize {
+		return nil, fmt.Errorf("fingerprint must be %d-dimensional, got %d", vectorSize, len(vec))
+	}
+	if !from.Valid() || !to.Valid() {
+		return nil, fmt.Errorf("unknown fingerprint version %d -> %d", from, to)
+	}
+	if from == to {
+		return vec.Clone(), nil
+	}
+	if !Derivable(from, to) {
+		return nil, ErrNotDerivable
+	}
+
+	out := vec.Float64s()
+	normaliseVector(out, variants[to])
+	return NewVector(out), nil
+}
+
+// Derivable reports whether MigrateVector can convert vectors of version
+// from into version to.
+func Derivable(from, to Version) bool {
+	return from == to || (from == V1 && variants[to].derivedOf1)
+}
+
+// normaliseVector applies the coefficient-level steps of vr in place.
+func normaliseVector(vec []float64, vr variant) {
+	if vr.dropDC {
+		for b := 0; b < len(vec); b += coeffsPerBlock {
+			vec[b] = 0
+		}
+	}
+
+	if vr.globalZ {
+		zScore(vec, acIndices(len(vec)))
+	}
+
+	if vr.blockZ {
+		// Blocks with (relatively) no texture are zeroed instead of having
+		// their rounding noise blown up to unit variance. The floor scales
+		// with the vector, so the result doesn't depend on its length.
+		floor := 1e-3 * stdDev(vec, acIndices(len(vec)))
+		for b := 0; b < len(vec); b += coeffsPerBlock {
+			idx := make([]int, 0, coeffsPerBlock-1)
+			for i := b + 1; i < b+coeffsPerBlock; i++ {
+				idx = append(idx, i)
+			}
+			if stdDev(vec, idx) <= floor {
+				for _, i := range idx {
+					vec[i] = 0
+				}
+				continue
+			}
+			zScore(vec, idx)
+		}
+	}
+}
+
+// acIndices lists every non-DC position of an n-D vector.
+func acIndices(n int) []int {
+	idx := make([]int, 0, n-n/coeffsPerBlock)
+	for i := 0; i < n; i++ {
+		if i%coeffsPerBlock != 0 {
+			idx = append(idx, i)
+		}
+	}
+	return idx
+}
+
+func stdDev(vec []float64, idx []int) float64 {
+	mean := 0.0
+	for _, i := range idx {
+		mean += vec[i]
+	}
+	mean /= float64(len(idx))
+
+	sum := 0.0
+	for _, i := range idx {
+		d := vec[i] - mean
+		sum += d * d
+	}
+	return math.Sqrt(sum / float64(len(idx)))
+}
+
+// zScore rescales vec[idx] to zero mean and unit variance.
+func zScore(vec []float64, idx []int) {
+	mean := 0.0
+	for _, i := range idx {
+		mean += vec[i]
+	}
+	mean /= float64(len(idx))
+
+	sd := stdDev(vec, idx)
+	for _, i := range idx {
+		if sd == 0 {
+			vec[i] = 0
+		} else {
+			vec[i] = (vec[i] - mean) / sd
+		}
+	}
+}
+
+// equaliseHistogram spreads the luminance of a zero-centred Y matrix over
+// the full range, which undoes gamma and contrast curves before the DCT.
+func equaliseHistogram(Ymatrix [][]float64) {
+	var hist [256]int
+	n := 0
+	for _, row := range Ymatrix {
+		for _, v := range row {
+			hist[level(v)]++
+			n++
+		}
+	}
+
+	var cdf [256]int
+	sum, cdfMin := 0, 0
+	for i, c := range hist {
+		sum += c
+		cdf[i] = sum
+		if cdfMin == 0 && sum > 0 {
+			cdfMin = sum
+		}
+	}
+	if n == cdfMin {
+		return // a single grey level: nothing to spread
+	}
+
+	for _, row := range Ymatrix {
+		for x, v := range row {
+			eq := float64(cdf[level(v)]-cdfMin) / float64(n-cdfMin) * 255
+			row[x] = eq - 128.0
+		}
+	}
+}
+
+// level maps a zero-centred luminance value to its 0..255 histogram bin.
+func level(v float64) int {
+	l := int(math.Round(v + 128.0))
+	if l < 0 {
+		return 0
+	}
+	if l > 255 {
+		return 255
+	}
+	return l
+}
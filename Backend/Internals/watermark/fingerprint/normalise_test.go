@@ -0,0 +1,143 @@
+package fingerprint
+
+import (
+	"context"
+	"errors"
+	"image"
+	"math"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// adjust applies a linear brightness/contrast change around mid-grey.
+func adjust(img *image.RGBA, gain, offset float64) *image.RGBA {
+	out := image.NewRGBA(img.Bounds())
+	for i := 0; i < len(img.Pix); i += 4 {
+		for c := 0; c < 3; c++ {
+			v := (float64(img.Pix[i+c])-128)*gain + 128 + offset
+			out.Pix[i+c] = uint8(math.Max(0, math.Min(255, math.Round(v))))
+		}
+		out.Pix[i+3] = 255
+	}
+	return out
+}
+
+func cosine(a, b Vector) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		x, y := float64(a[i]), float64(b[i])
+		dot += x * y
+		na += x * x
+		nb += y * y
+	}
+	return dot / math.Sqrt(na*nb)
+}
+
+func mustFingerprint(t *testing.T, img image.Image, v Version) Vector {
+	t.Helper()
+	vec, err := CreateFingerprintVersion(img, v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vec
+}
+
+func TestNormalisedFingerprintIsBrightnessAndContrastInvariant(t *testing.T) {
+	orig := scene(512, 384, 0)
+	edits := map[string]*image.RGBA{
+		"brighter":     adjust(orig, 1, 30),
+		"darker":       adjust(orig, 1, -30),
+		"low contrast": adjust(orig, 0.6, 0),
+	}
+
+	for name, edited := range edits {
+		v1 := cosine(mustFingerprint(t, orig, V1), mustFingerprint(t, edited, V1))
+		for _, v := range []Version{V2, V3, V4} {
+			got := cosine(mustFingerprint(t, orig, v), mustFingerprint(t, edited, v))
+			if got < 0.99 {
+				t.Errorf("%s: v%d similarity %.4f, want >= 0.99", name, v, got)
+			}
+			if got < v1-1e-3 {
+				t.Errorf("%s: v%d similarity %.4f below v1's %.4f", name, v, got, v1)
+			}
+		}
+	}
+}
+
+func TestMigrateVectorMatchesDirectFingerprint(t *testing.T) {
+	img := scene(400, 300, 1)
+	v1 := mustFingerprint(t, img, V1)
+
+	// Stores hand back L2-normalised vectors; migration must not care.
+	stored := make(Vector, len(v1))
+	for i, x := range v1 {
+		stored[i] = x * 1e-3
+	}
+
+	for _, to := range []Version{V2, V3} {
+		got, err := MigrateVector(stored, V1, to)
+		if err != nil {
+			t.Fatalf("v1 -> v%d: %v", to, err)
+		}
+		if c := cosine(got, mustFingerprint(t, img, to)); c < 0.999999 {
+			t.Errorf("v1 -> v%d differs from direct fingerprint (cosine %.8f)", to, c)
+		}
+
+		// Re-running a migration on already converted vectors is harmless.
+		again, err := MigrateVector(got, V1, to)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if c := cosine(again, got); c < 0.999999 {
+			t.Errorf("v%d migration is not idempotent (cosine %.8f)", to, c)
+		}
+	}
+
+	if _, err := MigrateVector(stored, V1, V4); !errors.Is(err, ErrNotDerivable) {
+		t.Fatalf("v1 -> v4: err = %v, want ErrNotDerivable", err)
+	}
+	if _, err := MigrateVector(stored, V1, Version(9)); err == nil {
+		t.Fatal("unknown target version accepted")
+	}
+}
+
+func TestMemoryStoreScrollMigratesInPlace(t *testing.T) {
+	ctx := context.Background()
+	store, _ := NewMemoryStore("")
+
+	imgs := []*image.RGBA{scene(300, 300, 0), scene(300, 300, 2)}
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+	for i, img := range imgs {
+		if err := store.Store(ctx, ids[i], Createfingerprint(img), V1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	err := store.Scroll(ctx, func(id uuid.UUID, vec Vector, v Version) error {
+		if v != V1 {
+			t.Fatalf("Scroll reported version %d, want %d", v, V1)
+		}
+		out, err := MigrateVector(vec, v, V3)
+		if err != nil {
+			return err
+		}
+		return store.Store(ctx, id, out, V3)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, v, _ := store.Get(ctx, ids[0]); v != V3 {
+		t.Fatalf("Get reported version %d after migrating, want %d", v, V3)
+	}
+
+	// A brightened copy now finds its original through the v3 index.
+	query := mustFingerprint(t, adjust(imgs[1], 1, 25), V3)
+	got, scores, err := store.Query(ctx, query, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got[0] != ids[1] || scores[0] < 0.99 {
+		t.Fatalf("Query = %v %v, want %v with score >= 0.99", got, scores, ids[1])
+	}
+}
@@ -0,0 +1,183 @@
+package fingerprint
+
+// orientation.go — Rotation- and flip-tolerant search
+//
+// The 8x8 block grid of a fingerprint is tied to the image's orientation,
+// so a mirrored or rotated repost lands far away from its original. For an
+// orientation-tolerant query the luminance matrix is put through all eight
+// symmetries of the square (the dihedral group), each result is
+// fingerprinted and searched, and the best hit per image wins. Because the
+// image is resized to 256x256 first, this also works for non-square images
+// rotated by 90 degrees.
+
+import (
+	"context"
+	"image"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// Orientation is one of the eight rotations/reflections of an image.
+// Rotations are clockwise.
+type Orientation int
+
+const (
+	Identity Orientation = iota
+	FlipHorizontal
+	FlipVertical
+	Rotate180
+	Transpose // mirror along the main diagonal
+	Rotate90
+	Rotate270
+	Transverse // mirror along the anti-diagonal
+)
+
+// Orientations lists every orientation, Identity first.
+var Orientations = []Orientation{
+	Identity, FlipHorizontal, FlipVertical, Rotate180,
+	Transpose, Rotate90, Rotate270, Transverse,
+}
+
+var orientationNames = map[Orientation]string{
+	Identity:       "identity",
+	FlipHorizontal: "flip_horizontal",
+	FlipVertical:   "flip_vertical",
+	Rotate180:      "rotate_180",
+	Transpose:      "transpose",
+	Rotate90:       "rotate_90",
+	Rotate270:      "rotate_270",
+	Transverse:     "transverse",
+}
+
+func (o Orientation) String() string {
+	if name, ok := orientationNames[o]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// Inverse returns the orientation that undoes o.
+func (o Orientation) Inverse() Orientation {
+	switch o {
+	case Rotate90:
+		return Rotate270
+	case Rotate270:
+		return Rotate90
+	}
+	return o // every other symmetry is its own inverse
+}
+
+// steps decomposes o into an optional transpose followed by optional
+// horizontal and vertical flips.
+func (o Orientation) steps() (transpose, flipH, flipV bool) {
+	switch o {
+	case FlipHorizontal:
+		return false, true, false
+	case FlipVertical:
+		return false, false, true
+	case Rotate180:
+		return false, true, true
+	case Transpose:
+		return true, false, false
+	case Rotate90:
+		return true, true, false
+	case Rotate270:
+		return true, false, true
+	case Transverse:
+		return true, true, true
+	}
+	return false, false, false
+}
+
+// orient returns o applied to the square matrix m.
+func orient(m [][]float64, o Orientation) [][]float64 {
+	transpose, flipH, flipV := o.steps()
+	n := len(m)
+
+	out := make([][]float64, n)
+	for r := range out {
+		out[r] = make([]float64, n)
+		for c := range out[r] {
+			sr, sc := r, c
+			if flipV {
+				sr = n - 1 - sr
+			}
+			if flipH {
+				sc = n - 1 - sc
+			}
+			if transpose {
+				sr, sc = sc, sr
+			}
+			out[r][c] = m[sr][sc]
+		}
+	}
+	return out
+}
+
+// OrientedFingerprints returns the version v fingerprint of every
+// orientation of img, indexed by Orientation.
+func OrientedFingerprints(img image.Image, v Version) ([]Vector, error) {
+	Ymatrix, vr, err := prepareLuminance(img, v)
+	if err != nil {
+		return nil, err
+	}
+
+	vecs := make([]Vector, len(Orientations))
+	for _, o := range Orientations {
+		vec := blockCoefficients(orient(Ymatrix, o))
+		normaliseVector(vec, vr)
+		vecs[o] = NewVector(vec)
+	}
+	return vecs, nil
+}
+
+// OrientedMatch is one stored image found by an orientation-tolerant
+// query. Transform is what was done to the stored original to produce the
+// query image.
+type OrientedMatch struct {
+	ImageID   uuid.UUID
+	Score     float32
+	Transform Orientation
+}
+
+// FindSimilarOriented is FindSimilar over all eight orientations of
+// queryImg. Each image is reported once, with its best-scoring transform;
+// on equal scores the untransformed match wins.
+func FindSimilarOriented(ctx context.Context, store VectorStore, v Version, queryImg image.Image, k int, opts QueryOptions) ([]OrientedMatch, error) {
+	vecs, err := OrientedFingerprints(queryImg, v)
+	if err != nil {
+		return nil, err
+	}
+
+	best := make(map[uuid.UUID]OrientedMatch)
+	for _, o := range Orientations {
+		ids, scores, err := store.QueryNamed(ctx, VectorStructure, vecs[o], k, opts)
+		if err != nil {
+			return nil, err
+		}
+		for i, id := range ids {
+			if prev, seen := best[id]; seen && prev.Score >= scores[i] {
+				continue
+			}
+			// Turning the query by o gives the original, so the query is
+			// the original turned by o's inverse.
+			best[id] = OrientedMatch{ImageID: id, Score: scores[i], Transform: o.Inverse()}
+		}
+	}
+
+	matches := make([]OrientedMatch, 0, len(best))
+	for _, m := range best {
+		matches = append(matches, m)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		return matches[i].ImageID.String() < matches[j].ImageID.String()
+	})
+	if len(matches) > k {
+		matches = matches[:k]
+	}
+	return matches, nil
+}
@@ -0,0 +1,103 @@
+package fingerprint
+
+import (
+	"context"
+	"image"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// transformImage applies o to img pixel by pixel, independently of orient.
+func transformImage(img *image.RGBA, o Orientation) *image.RGBA {
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	var out *image.RGBA
+	switch o {
+	case Transpose, Rotate90, Rotate270, Transverse:
+		out = image.NewRGBA(image.Rect(0, 0, h, w))
+	default:
+		out = image.NewRGBA(image.Rect(0, 0, w, h))
+	}
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var nx, ny int
+			switch o {
+			case Identity:
+				nx, ny = x, y
+			case FlipHorizontal:
+				nx, ny = w-1-x, y
+			case FlipVertical:
+				nx, ny = x, h-1-y
+			case Rotate180:
+				nx, ny = w-1-x, h-1-y
+			case Transpose:
+				nx, ny = y, x
+			case Rotate90: // clockwise
+				nx, ny = h-1-y, x
+			case Rotate270:
+				nx, ny = y, w-1-x
+			case Transverse:
+				nx, ny = h-1-y, w-1-x
+			}
+			out.Set(nx, ny, img.At(x, y))
+		}
+	}
+	return out
+}
+
+func TestOrientedFingerprintsMatchTransformedImages(t *testing.T) {
+	img := scene(320, 240, 0.7)
+	vecs, err := OrientedFingerprints(img, V3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, o := range Orientations {
+		direct := mustFingerprint(t, transformImage(img, o), V3)
+		if c := cosine(vecs[o], direct); c < 0.999 {
+			t.Errorf("%s: oriented fingerprint vs transformed image cosine %.5f", o, c)
+		}
+		if o.Inverse().Inverse() != o {
+			t.Errorf("%s: inverse is not an involution", o)
+		}
+	}
+}
+
+func TestFindSimilarOrientedReportsTransform(t *testing.T) {
+	ctx := context.Background()
+	store, _ := NewMemoryStore("")
+
+	originals := []*image.RGBA{scene(320, 240, 0.7), scene(320, 240, 2.2)}
+	ids := []uuid.UUID{uuid.New(), uuid.New()}
+	for i, img := range originals {
+		if err := store.Store(ctx, ids[i], mustFingerprint(t, img, V3), V3); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, o := range Orientations {
+		query := transformImage(originals[0], o)
+
+		matches, err := FindSimilarOriented(ctx, store, V3, query, 2, QueryOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(matches) == 0 || matches[0].ImageID != ids[0] {
+			t.Fatalf("%s: best match %v, want %v", o, matches, ids[0])
+		}
+		if matches[0].Transform != o || matches[0].Score < 0.99 {
+			t.Errorf("%s: reported %s with score %.4f", o, matches[0].Transform, matches[0].Score)
+		}
+	}
+
+	// A plain query misses the mirrored copy that the oriented one finds.
+	mirrored := transformImage(originals[0], FlipHorizontal)
+	_, scores, err := FindSimilar(ctx, store, V3, mirrored, 1, QueryOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if scores[0] > 0.9 {
+		t.Errorf("plain query scored the mirrored copy %.4f; orientation test is not meaningful", scores[0])
+	}
+}
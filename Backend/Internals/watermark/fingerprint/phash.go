@@ -0,0 +1,263 @@
+package fingerprint
+
+// phash.go — Compact binary perceptual hashes
+//
+// Next to the 1024-D DCT vector every image gets a handful of short bit
+// strings that survive re-encoding and resizing:
+//
+//   phash     64 bits   signs of the 8x8 lowest DCT coefficients (32x32 image)
+//   phash256  256 bits  the same over 16x16 coefficients (64x64 image)
+//   dhash     64 bits   horizontal gradient signs (9x8 image)
+//   ahash     64 bits   pixels above the mean (8x8 image)
+//   whash     64 bits   Haar LL band above the median (64x64 image, 3 levels)
+//
+// Two images are near-duplicates when the Hamming distance between their
+// hashes is small, which can be checked without the vector DB.
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"math/bits"
+	"sort"
+	"strings"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/engine"
+)
+
+// HashKind names one of the perceptual hashes.
+type HashKind string
+
+const (
+	PHash    HashKind = "phash"
+	PHash256 HashKind = "phash256"
+	DHash    HashKind = "dhash"
+	AHash    HashKind = "ahash"
+	WHash    HashKind = "whash"
+)
+
+// HashKinds lists every hash ComputeHashes produces.
+var HashKinds = []HashKind{PHash, PHash256, DHash, AHash, WHash}
+
+// Bits returns the length of the hash, or 0 for an unknown kind.
+func (k HashKind) Bits() int {
+	switch k {
+	case PHash, DHash, AHash, WHash:
+		return 64
+	case PHash256:
+		return 256
+	}
+	return 0
+}
+
+// Hash is a perceptual hash, 64 bits per word, most significant bit first.
+type Hash []uint64
+
+// ImageHashes holds every hash of one image.
+type ImageHashes map[HashKind]Hash
+
+// Distance returns the Hamming distance between h and o, or -1 if their
+// lengths differ.
+func (h Hash) Distance(o Hash) int {
+	if len(h) != len(o) {
+		return -1
+	}
+	d := 0
+	for i := range h {
+		d += bits.OnesCount64(h[i] ^ o[i])
+	}
+	return d
+}
+
+// String encodes the hash as lowercase hex.
+func (h Hash) String() string {
+	var sb strings.Builder
+	for _, w := range h {
+		fmt.Fprintf(&sb, "%016x", w)
+	}
+	return sb.String()
+}
+
+// BitString spells the hash out as '0'/'1' characters, the text form of a
+// Postgres BIT(n) value.
+func (h Hash) BitString() string {
+	var sb strings.Builder
+	for _, w := range h {
+		fmt.Fprintf(&sb, "%064b", w)
+	}
+	return sb.String()
+}
+
+// ParseHash decodes the hex form produced by String.
+func ParseHash(s string) (Hash, error) {
+	raw, err := hex.DecodeString(s)
+	if err != nil || len(raw) == 0 || len(raw)%8 != 0 {
+		return nil, errors.New("hash must be a non-empty hex string of whole 64-bit words")
+	}
+	h := make(Hash, len(raw)/8)
+	for i := range h {
+		for _, b := range raw[i*8 : i*8+8] {
+			h[i] = h[i]<<8 | uint64(b)
+		}
+	}
+	return h, nil
+}
+
+// ParseBitString decodes the '0'/'1' form produced by BitString.
+func ParseBitString(s string) (Hash, error) {
+	if len(s) == 0 || len(s)%64 != 0 {
+		return nil, fmt.Errorf("bit string length %d is not a multiple of 64", len(s))
+	}
+	h := make(Hash, len(s)/64)
+	for i, c := range s {
+		if c != '0' && c != '1' {
+			return nil, fmt.Errorf("invalid bit %q", c)
+		}
+		h[i/64] = h[i/64]<<1 | uint64(c-'0')
+	}
+	return h, nil
+}
+
+// ComputeHashes returns every perceptual hash of img.
+func ComputeHashes(img image.Image) ImageHashes {
+	return ImageHashes{
+		PHash:    dctHash(img, 32, 8),
+		PHash256: dctHash(img, 64, 16),
+		DHash:    differenceHash(img),
+		AHash:    averageHash(img),
+		WHash:    waveletHash(img),
+	}
+}
+
+// grey resizes img and returns its luminance (0..255).
+func grey(img image.Image, w, h int) [][]float64 {
+	_, Ymatrix := engine.ConvertToYC(ResizeImage(img, w, h))
+	for _, row := range Ymatrix {
+		for x := range row {
+			row[x] += 128.0 // ConvertToYC centres Y on zero
+		}
+	}
+	return Ymatrix
+}
+
+// packBits sets bit i of the hash when set(i) is true.
+func packBits(n int, set func(i int) bool) Hash {
+	h := make(Hash, (n+63)/64)
+	for i := 0; i < n; i++ {
+		h[i/64] <<= 1
+		if set(i) {
+			h[i/64] |= 1
+		}
+	}
+	return h
+}
+
+func median(values []float64) float64 {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
+
+// dctHash is pHash: the low size x size DCT coefficients of an n x n
+// greyscale copy, each compared with their median. The DC term is left
+// out of the median so overall brightness doesn't skew it.
+func dctHash(img image.Image, n, size int) Hash {
+	coeffs := dct2(grey(img, n, n), size)
+
+	low := make([]float64, 0, size*size)
+	for v := 0; v < size; v++ {
+		low = append(low, coeffs[v][:size]...)
+	}
+	m := median(low[1:])
+
+	return packBits(size*size, func(i int) bool { return low[i] > m })
+}
+
+// dct2 returns the size x size lowest-frequency coefficients of the
+// orthonormal 2-D DCT-II of the square matrix in.
+func dct2(in [][]float64, size int) [][]float64 {
+	n := len(in)
+
+	basis := make([][]float64, size)
+	for u := range basis {
+		basis[u] = make([]float64, n)
+		k := math.Sqrt(2.0 / float64(n))
+		if u == 0 {
+			k = math.Sqrt(1.0 / float64(n))
+		}
+		for x := 0; x < n; x++ {
+			basis[u][x] = k * math.Cos(math.Pi*float64(2*x+1)*float64(u)/float64(2*n))
+		}
+	}
+
+	// Rows first, then columns.
+	rows := make([][]float64, n)
+	for y := 0; y < n; y++ {
+		rows[y] = make([]float64, size)
+		for u := 0; u < size; u++ {
+			for x := 0; x < n; x++ {
+				rows[y][u] += basis[u][x] * in[y][x]
+			}
+		}
+	}
+
+	out := make([][]float64, size)
+	for v := 0; v < size; v++ {
+		out[v] = make([]float64, size)
+		for u := 0; u < size; u++ {
+			for y := 0; y < n; y++ {
+				out[v][u] += basis[v][y] * rows[y][u]
+			}
+		}
+	}
+	return out
+}
+
+// differenceHash is dHash: one bit per horizontally adjacent pixel pair of
+// a 9x8 greyscale copy, set when brightness increases to the right.
+func differenceHash(img image.Image) Hash {
+	g := grey(img, 9, 8)
+	return packBits(64, func(i int) bool {
+		y, x := i/8, i%8
+		return g[y][x] < g[y][x+1]
+	})
+}
+
+// averageHash is aHash: one bit per pixel of an 8x8 greyscale copy, set
+// when the pixel is brighter than the mean.
+func averageHash(img image.Image) Hash {
+	g := grey(img, 8, 8)
+
+	mean := 0.0
+	for _, row := range g {
+		for _, v := range row {
+			mean += v
+		}
+	}
+	mean /= 64
+
+	return packBits(64, func(i int) bool { return g[i/8][i%8] > mean })
+}
+
+// waveletHash is wHash: three Haar levels take a 64x64 greyscale copy down
+// to an 8x8 LL band, whose coefficients are compared with their median.
+func waveletHash(img image.Image) Hash {
+	ll := grey(img, 64, 64)
+	for level := 0; level < 3; level++ {
+		ll = engine.PerformCompleteDWT(ll).LL
+	}
+
+	values := make([]float64, 0, 64)
+	for _, row := range ll {
+		values = append(values, row...)
+	}
+	m := median(values)
+
+	return packBits(64, func(i int) bool { return values[i] > m })
+}
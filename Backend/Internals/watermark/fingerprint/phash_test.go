@@ -0,0 +1,94 @@
+package fingerprint
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"testing"
+)
+
+func scene(w, h int, phase float64) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			fx, fy := float64(x)/float64(w), float64(y)/float64(h)
+			v := 128 + 60*math.Sin(7*fx+phase) + 40*math.Cos(5*fy-2*phase) + 20*math.Sin(13*fx*fy)
+			img.SetRGBA(x, y, color.RGBA{uint8(v), uint8(v*0.9 + 10), uint8(v*0.8 + 20), 255})
+		}
+	}
+	return img
+}
+
+// mirror flips img left to right: same colours and texture, different
+// layout.
+func mirror(img *image.RGBA) *image.RGBA {
+	b := img.Bounds()
+	out := image.NewRGBA(b)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			out.Set(b.Max.X-1-(x-b.Min.X), y, img.At(x, y))
+		}
+	}
+	return out
+}
+
+func jpegCopy(t *testing.T, img image.Image, quality int) image.Image {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality}); err != nil {
+		t.Fatal(err)
+	}
+	out, err := jpeg.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestHashesSurviveResizeAndJPEG(t *testing.T) {
+	orig := ComputeHashes(scene(640, 480, 0))
+	copies := map[string]ImageHashes{
+		"resized":  ComputeHashes(ResizeImage(scene(640, 480, 0), 320, 240)),
+		"jpeg q70": ComputeHashes(jpegCopy(t, scene(640, 480, 0), 70)),
+	}
+	other := ComputeHashes(mirror(scene(640, 480, 0)))
+
+	for _, kind := range HashKinds {
+		if got := len(orig[kind]) * 64; got != kind.Bits() {
+			t.Fatalf("%s has %d bits, want %d", kind, got, kind.Bits())
+		}
+
+		limit := kind.Bits() * 10 / 64
+		for name, h := range copies {
+			if d := orig[kind].Distance(h[kind]); d > limit {
+				t.Errorf("%s: %s copy is %d bits away, want <= %d", kind, name, d, limit)
+			}
+		}
+		if d := orig[kind].Distance(other[kind]); d <= limit {
+			t.Errorf("%s: different image only %d bits away", kind, d)
+		}
+	}
+}
+
+func TestHashEncodings(t *testing.T) {
+	h := ComputeHashes(scene(200, 200, 1))[PHash256]
+
+	fromHex, err := ParseHash(h.String())
+	if err != nil || fromHex.Distance(h) != 0 {
+		t.Fatalf("ParseHash(String()) = %v, %v", fromHex, err)
+	}
+
+	fromBits, err := ParseBitString(h.BitString())
+	if err != nil || fromBits.Distance(h) != 0 {
+		t.Fatalf("ParseBitString(BitString()) = %v, %v", fromBits, err)
+	}
+
+	if _, err := ParseHash("xyz"); err == nil {
+		t.Fatal("ParseHash accepted non-hex input")
+	}
+	if d := h.Distance(Hash{0}); d != -1 {
+		t.Fatalf("Distance across lengths = %d, want -1", d)
+	}
+}
@@ -0,0 +1,165 @@
+package fingerprint
+
+// transfer.go — Moving a whole index between stores
+//
+// Export writes every point of a VectorStore to a portable NDJSON file and
+// Import loads one into any other store, so Qdrant can be backed up and a
+// local MemoryStore seeded from production. The first line describes the
+// file; each following line is one image:
+//
+//	{"format":"image-fingerprints","version":1,"vectors":{"colour":96,"structure":1024}}
+//	{"id":"…","version":3,"vectors":{"structure":"<base64>","colour":"<base64>"},"attributes":{…}}
+//
+// Vectors are written as stored (L2-normalised by cosine stores), in the
+// base64 form of vector.go. Only the vector store is involved: importing
+// into an empty environment leaves vectors without image_metadata rows,
+// which the reconcile command reports as orphans.
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	transferFormat  = "image-fingerprints"
+	transferVersion = 1
+
+	// DefaultImportBatch is how many points Import hands to StoreBatch at
+	// once when the caller doesn't say.
+	DefaultImportBatch = 256
+)
+
+// transferHeader is the first line of an export.
+type transferHeader struct {
+	Format  string         `json:"format"`
+	Version int            `json:"version"`
+	Vectors map[string]int `json:"vectors"` // dimension by name
+}
+
+// transferPoint is one exported image.
+type transferPoint struct {
+	ID         uuid.UUID           `json:"id"`
+	Version    Version             `json:"version"`
+	Vectors    map[string]Vector   `json:"vectors"`
+	Attributes *transferAttributes `json:"attributes,omitempty"`
+}
+
+type transferAttributes struct {
+	IsAIGenerated bool      `json:"is_ai_generated"`
+	Owner         string    `json:"owner,omitempty"`
+	MimeType      string    `json:"mime_type,omitempty"`
+	Tags          []string  `json:"tags,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// Export writes every point of store to w and returns how many it wrote.
+func Export(ctx context.Context, store VectorStore, w io.Writer) (int, error) {
+	enc := json.NewEncoder(w)
+	if err := enc.Encode(transferHeader{Format: transferFormat, Version: transferVersion, Vectors: vectorSizes}); err != nil {
+		return 0, fmt.Errorf("write export header: %w", err)
+	}
+
+	n := 0
+	err := store.ScrollPoints(ctx, func(p Point) error {
+		line := transferPoint{ID: p.ID, Version: p.Version, Vectors: p.Vectors}
+		if a := p.Attributes; a != nil {
+			line.Attributes = &transferAttributes{
+				IsAIGenerated: a.IsAIGenerated,
+				Owner:         a.Owner,
+				MimeType:      a.MimeType,
+				Tags:          a.Tags,
+				CreatedAt:     a.CreatedAt.UTC(),
+			}
+		}
+		if err := enc.Encode(line); err != nil {
+			return fmt.Errorf("write point %s: %w", p.ID, err)
+		}
+		n++
+		return nil
+	})
+	return n, err
+}
+
+// Import reads an Export from r into store, batch points per StoreBatch
+// call (DefaultImportBatch if batch < 1), and returns how many it stored.
+// Points already in store are replaced. A file whose dimensions differ
+// from this build's is refused before anything is written.
+func Import(ctx context.Context, store VectorStore, r io.Reader, batch int) (int, error) {
+	if batch < 1 {
+		batch = DefaultImportBatch
+	}
+	dec := json.NewDecoder(r)
+
+	var header transferHeader
+	if err := dec.Decode(&header); err != nil {
+		return 0, fmt.Errorf("read import header: %w", err)
+	}
+	if header.Format != transferFormat || header.Version != transferVersion {
+		return 0, fmt.Errorf("not a version %d %s export (format %q, version %d)",
+			transferVersion, transferFormat, header.Format, header.Version)
+	}
+	for name, size := range header.Vectors {
+		want, ok := vectorSizes[name]
+		if !ok {
+			return 0, fmt.Errorf("export holds %w: %q", ErrUnknownVector, name)
+		}
+		if size != want {
+			return 0, fmt.Errorf("export holds %d-D %q vectors, this store expects %d", size, name, want)
+		}
+	}
+
+	n := 0
+	pending := make([]Point, 0, batch)
+	flush := func() error {
+		if err := store.StoreBatch(ctx, pending); err != nil {
+			return err
+		}
+		n += len(pending)
+		pending = pending[:0]
+		return nil
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			return n, err
+		}
+
+		var line transferPoint
+		err := dec.Decode(&line)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return n, fmt.Errorf("read point %d: %w", n+len(pending)+1, err)
+		}
+
+		p := Point{ID: line.ID, Version: line.Version, Vectors: line.Vectors}
+		if a := line.Attributes; a != nil {
+			p.Attributes = &Attributes{
+				IsAIGenerated: a.IsAIGenerated,
+				Owner:         a.Owner,
+				MimeType:      a.MimeType,
+				Tags:          a.Tags,
+				CreatedAt:     a.CreatedAt,
+			}
+		}
+		// Version 0 marks vectors stored before versions were recorded.
+		if p.Version != 0 && !p.Version.Valid() {
+			return n, fmt.Errorf("point %s: unknown fingerprint version %d", p.ID, p.Version)
+		}
+
+		pending = append(pending, p)
+		if len(pending) == batch {
+			if err := flush(); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, flush()
+}
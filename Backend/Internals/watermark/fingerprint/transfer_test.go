@@ -0,0 +1,131 @@
+package fingerprint
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestExportImportRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	src, err := NewMemoryStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC)
+	points := []Point{
+		{
+			ID: uuid.New(), Version: V3,
+			Vectors:    map[string]Vector{VectorStructure: testVector(2), VectorColour: testColourVector(3)},
+			Attributes: &Attributes{Owner: "acme", MimeType: "image/png", Tags: []string{"news"}, CreatedAt: created},
+		},
+		{ID: uuid.New(), Version: V1, Vectors: map[string]Vector{VectorStructure: testVector(3)}},
+	}
+	if err := src.StoreBatch(ctx, points); err != nil {
+		t.Fatal(err)
+	}
+
+	var file bytes.Buffer
+	n, err := Export(ctx, src, &file)
+	if err != nil || n != 2 {
+		t.Fatalf("Export = %d, %v; want 2 points", n, err)
+	}
+	if lines := strings.Count(file.String(), "\n"); lines != 3 {
+		t.Fatalf("export has %d lines, want a header and 2 points", lines)
+	}
+
+	dst, _ := NewMemoryStore("")
+	if n, err := Import(ctx, dst, bytes.NewReader(file.Bytes()), 1); err != nil || n != 2 {
+		t.Fatalf("Import = %d, %v; want 2 points", n, err)
+	}
+
+	var got []Point
+	if err := dst.ScrollPoints(ctx, func(p Point) error { got = append(got, p); return nil }); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("imported store holds %d points, want 2", len(got))
+	}
+	for _, p := range got {
+		want := points[0]
+		if p.ID != want.ID {
+			want = points[1]
+		}
+		if p.Version != want.Version || len(p.Vectors) != len(want.Vectors) {
+			t.Fatalf("point %s = v%d with %d vectors, want v%d with %d", p.ID, p.Version, len(p.Vectors), want.Version, len(want.Vectors))
+		}
+		for name, vec := range want.Vectors {
+			if s := Similarity(p.Vectors[name], vec); s < 0.9999 {
+				t.Fatalf("point %s %s vector scores %v against the original", p.ID, name, s)
+			}
+		}
+		if (p.Attributes == nil) != (want.Attributes == nil) {
+			t.Fatalf("point %s attributes = %+v, want %+v", p.ID, p.Attributes, want.Attributes)
+		}
+		if a := p.Attributes; a != nil {
+			if a.Owner != "acme" || a.MimeType != "image/png" || !slices.Equal(a.Tags, []string{"news"}) || !a.CreatedAt.Equal(created) {
+				t.Fatalf("attributes = %+v, want %+v", a, want.Attributes)
+			}
+		}
+	}
+}
+
+func TestStoreBatchReplacesPoints(t *testing.T) {
+	ctx := context.Background()
+	store, _ := NewMemoryStore("")
+
+	id := uuid.New()
+	if err := store.Store(ctx, id, testVector(2), V2); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.StoreNamed(ctx, id, VectorColour, testColourVector(3)); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.SetAttributes(ctx, id, Attributes{Owner: "acme"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := store.StoreBatch(ctx, []Point{{ID: id, Version: V3, Vectors: map[string]Vector{VectorStructure: testVector(4)}}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, v, _ := store.Get(ctx, id); v != V3 {
+		t.Fatalf("version = %d after StoreBatch, want %d", v, V3)
+	}
+	if got, _, _ := store.QueryNamed(ctx, VectorColour, testColourVector(3), 1, QueryOptions{}); len(got) != 0 {
+		t.Fatal("colour vector left out of the point survived")
+	}
+	if got, _, _ := store.QueryNamed(ctx, VectorStructure, testVector(4), 1, QueryOptions{Filter: &Filter{Owner: "acme"}}); len(got) != 0 {
+		t.Fatal("attributes left out of the point survived")
+	}
+
+	err := store.StoreBatch(ctx, []Point{{ID: uuid.New(), Vectors: map[string]Vector{VectorColour: testColourVector(3)}}})
+	if err == nil {
+		t.Fatal("expected an error for a point without a structure vector")
+	}
+}
+
+func TestImportRejectsForeignFiles(t *testing.T) {
+	ctx := context.Background()
+	for name, file := range map[string]string{
+		"not an export":   `{"format":"other","version":1}` + "\n",
+		"wrong dimension": `{"format":"image-fingerprints","version":1,"vectors":{"structure":512}}` + "\n",
+		"unknown version": `{"format":"image-fingerprints","version":1,"vectors":{}}` + "\n" +
+			`{"id":"` + uuid.NewString() + `","version":99,"vectors":{}}` + "\n",
+	} {
+		t.Run(name, func(t *testing.T) {
+			store, _ := NewMemoryStore("")
+			if _, err := Import(ctx, store, strings.NewReader(file), 0); err == nil {
+				t.Fatal("expected an error")
+			}
+			if n, _ := store.Count(ctx); n != 0 {
+				t.Fatalf("store holds %d points after a failed import", n)
+			}
+		})
+	}
+}
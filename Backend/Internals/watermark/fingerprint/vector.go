@@ -0,0 +1,126 @@
+package fingerprint
+
+// vector.go — The fingerprint value and its wire format
+//
+// Fingerprints are computed in float64 but every store keeps float32, so
+// that is what leaves this package: a Vector. Its binary form is the
+// little-endian float32 values (4 KiB for a structure fingerprint) and its
+// text form is that in standard base64, which is how it travels in JSON,
+// in the X-Fingerprint header and in vector exports. JSON decoding still
+// accepts a plain array of numbers, as fingerprints used to be sent.
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math"
+)
+
+// Vector is a fingerprint as stored and exchanged.
+type Vector []float32
+
+// NewVector narrows a computed fingerprint to a Vector.
+func NewVector(vec []float64) Vector {
+	out := make(Vector, len(vec))
+	for i, x := range vec {
+		out[i] = float32(x)
+	}
+	return out
+}
+
+// Float64s widens v for arithmetic.
+func (v Vector) Float64s() []float64 {
+	out := make([]float64, len(v))
+	for i, x := range v {
+		out[i] = float64(x)
+	}
+	return out
+}
+
+// Clone returns a copy of v, nil for nil.
+func (v Vector) Clone() Vector {
+	if v == nil {
+		return nil
+	}
+	return append(Vector(nil), v...)
+}
+
+// MarshalBinary packs v as little-endian float32s.
+func (v Vector) MarshalBinary() ([]byte, error) {
+	buf := make([]byte, 4*len(v))
+	for i, x := range v {
+		binary.LittleEndian.PutUint32(buf[4*i:], math.Float32bits(x))
+	}
+	return buf, nil
+}
+
+// UnmarshalBinary is the inverse of MarshalBinary.
+func (v *Vector) UnmarshalBinary(buf []byte) error {
+	if len(buf)%4 != 0 {
+		return fmt.Errorf("vector of %d bytes is not a float32 array", len(buf))
+	}
+	out := make(Vector, len(buf)/4)
+	for i := range out {
+		out[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+	}
+	*v = out
+	return nil
+}
+
+// String is the base64 form of v.
+func (v Vector) String() string {
+	buf, _ := v.MarshalBinary()
+	return base64.StdEncoding.EncodeToString(buf)
+}
+
+// ParseVector decodes the base64 form of a Vector.
+func ParseVector(s string) (Vector, error) {
+	buf, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("fingerprint is not base64: %w", err)
+	}
+	var v Vector
+	if err := v.UnmarshalBinary(buf); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// MarshalText encodes v as base64; JSON uses it too.
+func (v Vector) MarshalText() ([]byte, error) {
+	return []byte(v.String()), nil
+}
+
+// UnmarshalText decodes base64 text.
+func (v *Vector) UnmarshalText(text []byte) error {
+	out, err := ParseVector(string(text))
+	if err != nil {
+		return err
+	}
+	*v = out
+	return nil
+}
+
+// UnmarshalJSON accepts a base64 string or an array of numbers.
+func (v *Vector) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*v = nil
+		return nil
+	}
+	if len(data) > 0 && data[0] == '[' {
+		var values []float32
+		if err := json.Unmarshal(data, &values); err != nil {
+			return err
+		}
+		*v = values
+		return nil
+	}
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("fingerprint must be a base64 string or an array of numbers")
+	}
+	return v.UnmarshalText([]byte(s))
+}
@@ -4,33 +4,48 @@ package fingerprint
 //
 // Architecture:
 //   PostgreSQL (db.go)  → users, admins, image_metadata
-//   Qdrant (qdrant.go)  → image_fingerprints (1024-D vectors)
+//   Qdrant (qdrant.go)  → image_fingerprints by default (named vectors: 1024-D
+//                         "structure", 96-D "colour"; payload: version
+//                         and the attributes of attributes.go)
 //
 // The image UUID from PostgreSQL is used as the point ID in Qdrant,
 // keeping both databases in sync.
+//
+// Collections created before colour vectors existed have a single unnamed
+// vector. They keep working for structure fingerprints; colour calls on
+// them fail with ErrUnknownVector until the collection is recreated.
+//
+// The collection's name, distance and indexing are set by CollectionConfig
+// (collection.go).
 
 import (
 	"context"
 	"fmt"
-	"image"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/qdrant/go-client/qdrant"
 )
 
-const (
-	collectionName = "image_fingerprints"
-	vectorSize     = 1024
-)
+const vectorSize = 1024
 
-// QdrantDB wraps the Qdrant client.
+// QdrantDB wraps the Qdrant client. It is the production VectorStore.
 type QdrantDB struct {
 	client *qdrant.Client
+	cfg    CollectionConfig
+
+	// names lists the collection's named vectors; nil for a legacy
+	// collection with one unnamed vector. Set by CreateCollection.
+	names map[string]bool
 }
 
 // NewQdrantDB connects to a running Qdrant instance.
 // addr is the host:port of the gRPC endpoint, e.g. "localhost:6334"
-func NewQdrantDB(host string, port int) (*QdrantDB, error) {
+func NewQdrantDB(host string, port int, cfg CollectionConfig) (*QdrantDB, error) {
+	cfg, err := cfg.withDefaults()
+	if err != nil {
+		return nil, err
+	}
 
 	client, err := qdrant.NewClient(&qdrant.Config{
 		Host: host,
@@ -40,104 +55,377 @@ func NewQdrantDB(host string, port int) (*QdrantDB, error) {
 		return nil, fmt.Errorf("connect to qdrant: %w", err)
 	}
 
-	return &QdrantDB{client: client}, nil
+	return &QdrantDB{client: client, cfg: cfg}, nil
 }
 
 // Close shuts down the Qdrant client connection.
-func (q *QdrantDB) Close() { q.client.Close() }
+func (q *QdrantDB) Close() error { return q.client.Close() }
 
 // CreateCollection sets up the vector collection in Qdrant.
 // Call this once when setting up the database for the first time.
-// It is safe to call multiple times — skips creation if already exists.
+// It is safe to call multiple times — an existing collection is checked
+// against the configuration (collection.go) instead.
 func (q *QdrantDB) CreateCollection(ctx context.Context) error {
-	exists, err := q.client.CollectionExists(ctx, collectionName)
+	exists, err := q.client.CollectionExists(ctx, q.cfg.Name)
 	if err != nil {
 		return fmt.Errorf("check collection: %w", err)
 	}
 	if exists {
-		return nil // already set up, nothing to do
+		info, err := q.client.GetCollectionInfo(ctx, q.cfg.Name)
+		if err != nil {
+			return fmt.Errorf("inspect collection: %w", err)
+		}
+		if err := q.checkCollection(info); err != nil {
+			return err
+		}
+		if err := q.updateIndexing(ctx, info); err != nil {
+			return err
+		}
+		return q.indexPayload(ctx)
 	}
 
+	params := make(map[string]*qdrant.VectorParams, len(vectorSizes))
+	for name, size := range vectorSizes {
+		params[name] = &qdrant.VectorParams{
+			Size:     uint64(size),
+			Distance: q.cfg.distance(),
+		}
+	}
 	err = q.client.CreateCollection(ctx, &qdrant.CreateCollection{
-		CollectionName: collectionName,
-		VectorsConfig: qdrant.NewVectorsConfig(&qdrant.VectorParams{
-			Size:     vectorSize,
-			Distance: qdrant.Distance_Cosine, // matches our DCT fingerprint comparison
-		}),
+		CollectionName:     q.cfg.Name,
+		VectorsConfig:      qdrant.NewVectorsConfigMap(params),
+		HnswConfig:         q.cfg.hnsw(),
+		QuantizationConfig: q.cfg.quantization(),
 	})
 	if err != nil {
 		return fmt.Errorf("create collection: %w", err)
 	}
+
+	q.names = make(map[string]bool, len(params))
+	for name := range params {
+		q.names[name] = true
+	}
+	return q.indexPayload(ctx)
+}
+
+// payloadIndexes are the attribute fields Qdrant indexes for filtering.
+var payloadIndexes = map[string]qdrant.FieldType{
+	attrAIGenerated: qdrant.FieldType_FieldTypeBool,
+	attrOwner:       qdrant.FieldType_FieldTypeKeyword,
+	attrMimeType:    qdrant.FieldType_FieldTypeKeyword,
+	attrTags:        qdrant.FieldType_FieldTypeKeyword,
+	attrCreatedAt:   qdrant.FieldType_FieldTypeInteger,
+}
+
+// indexPayload creates the payload indexes filtered queries use. Qdrant
+// accepts an index that already exists, so collections made before
+// attributes existed pick them up on the next start.
+func (q *QdrantDB) indexPayload(ctx context.Context) error {
+	for field, kind := range payloadIndexes {
+		_, err := q.client.CreateFieldIndex(ctx, &qdrant.CreateFieldIndexCollection{
+			CollectionName: q.cfg.Name,
+			Wait:           qdrant.PtrOf(true),
+			FieldName:      field,
+			FieldType:      qdrant.PtrOf(kind),
+		})
+		if err != nil {
+			return fmt.Errorf("index payload field %s: %w", field, err)
+		}
+	}
 	return nil
 }
 
-// StoreFingerprint saves a 1024-D fingerprint vector to Qdrant.
+// supports reports whether the collection holds vectors called name.
+func (q *QdrantDB) supports(name string) bool {
+	if q.names == nil {
+		return name == VectorStructure
+	}
+	return q.names[name]
+}
+
+// using is the vector name to put in requests; legacy collections take
+// none.
+func (q *QdrantDB) using(name string) *string {
+	if q.names == nil {
+		return nil
+	}
+	return qdrant.PtrOf(name)
+}
+
+// versionKey is the payload field holding a point's fingerprint version.
+const versionKey = "fingerprint_version"
+
+// Store saves a 1024-D fingerprint vector to Qdrant, recording its version
+// in the point payload.
 // imageID is the UUID from PostgreSQL's image_metadata table — this is
 // how we link the vector back to the full metadata.
-func (q *QdrantDB) StoreFingerprint(ctx context.Context, imageID uuid.UUID, vec []float64) error {
+func (q *QdrantDB) Store(ctx context.Context, imageID uuid.UUID, vec Vector, v Version) error {
 	if len(vec) != vectorSize {
 		return fmt.Errorf("fingerprint must be %d-dimensional, got %d", vectorSize, len(vec))
 	}
+	payload := qdrant.NewValueMap(map[string]any{versionKey: int64(v)})
+	return q.storeVector(ctx, imageID, VectorStructure, vec, payload)
+}
+
+// StoreNamed saves one named vector, leaving the point's other vectors
+// and payload alone.
+func (q *QdrantDB) StoreNamed(ctx context.Context, imageID uuid.UUID, name string, vec Vector) error {
+	if err := checkNamed(name, vec); err != nil {
+		return err
+	}
+	return q.storeVector(ctx, imageID, name, vec, nil)
+}
+
+// storeVector writes one vector plus optional payload fields. An upsert
+// would replace the point's other vectors and payload too, so existing
+// points only get this vector and the given fields updated.
+func (q *QdrantDB) storeVector(
+	ctx context.Context,
+	imageID uuid.UUID,
+	name string,
+	vec Vector,
+	payload map[string]*qdrant.Value,
+) error {
+
+	if !q.supports(name) {
+		return fmt.Errorf("%w: %q", ErrUnknownVector, name)
+	}
+
+	vec32 := q.vector(vec)
+
+	// Store the PostgreSQL UUID as the Qdrant point ID
+	id := qdrant.NewIDUUID(imageID.String())
 
-	// Convert []float64 to []float32 (Qdrant uses float32 internally)
-	vec32 := make([]float32, len(vec))
-	for i, v := range vec {
-		vec32[i] = float32(v)
+	var vectors *qdrant.Vectors
+	if q.names == nil {
+		vectors = qdrant.NewVectors(vec32...)
+	} else {
+		vectors = qdrant.NewVectorsMap(map[string]*qdrant.Vector{name: qdrant.NewVector(vec32...)})
 	}
 
-	_, err := q.client.Upsert(ctx, &qdrant.UpsertPoints{
-		CollectionName: collectionName,
-		Points: []*qdrant.PointStruct{
-			{
-				// Store the PostgreSQL UUID as the Qdrant point ID
-				Id:      qdrant.NewIDUUID(imageID.String()),
-				Vectors: qdrant.NewVectors(vec32...),
-			},
-		},
+	existing, err := q.client.Get(ctx, &qdrant.GetPoints{
+		CollectionName: q.cfg.Name,
+		Ids:            []*qdrant.PointId{id},
+		WithPayload:    qdrant.NewWithPayload(false),
+		WithVectors:    qdrant.NewWithVectors(false),
 	})
 	if err != nil {
 		return fmt.Errorf("store fingerprint in qdrant: %w", err)
 	}
+
+	if len(existing) == 0 {
+		_, err = q.client.Upsert(ctx, &qdrant.UpsertPoints{
+			CollectionName: q.cfg.Name,
+			Points:         []*qdrant.PointStruct{{Id: id, Vectors: vectors, Payload: payload}},
+		})
+		if err != nil {
+			return fmt.Errorf("store %s vector in qdrant: %w", name, err)
+		}
+		return nil
+	}
+
+	_, err = q.client.UpdateVectors(ctx, &qdrant.UpdatePointVectors{
+		CollectionName: q.cfg.Name,
+		Points:         []*qdrant.PointVectors{{Id: id, Vectors: vectors}},
+	})
+	if err != nil {
+		return fmt.Errorf("store %s vector in qdrant: %w", name, err)
+	}
+	if len(payload) > 0 {
+		_, err = q.client.SetPayload(ctx, &qdrant.SetPayloadPoints{
+			CollectionName: q.cfg.Name,
+			Payload:        payload,
+			PointsSelector: qdrant.NewPointsSelector(id),
+		})
+		if err != nil {
+			return fmt.Errorf("store %s payload in qdrant: %w", name, err)
+		}
+	}
 	return nil
 }
 
-// DeleteFingerprint removes a vector from Qdrant by image UUID.
+// SetAttributes overwrites the attribute fields of the point's payload,
+// keeping the recorded version.
+func (q *QdrantDB) SetAttributes(ctx context.Context, imageID uuid.UUID, attrs Attributes) error {
+	_, err := q.client.SetPayload(ctx, &qdrant.SetPayloadPoints{
+		CollectionName: q.cfg.Name,
+		Payload:        attributesPayload(attrs),
+		PointsSelector: qdrant.NewPointsSelector(qdrant.NewIDUUID(imageID.String())),
+	})
+	if err != nil {
+		return fmt.Errorf("store attributes in qdrant: %w", err)
+	}
+	return nil
+}
+
+// attributesPayload is attrs as payload fields.
+func attributesPayload(attrs Attributes) map[string]*qdrant.Value {
+	tags := make([]*qdrant.Value, len(attrs.Tags))
+	for i, tag := range attrs.Tags {
+		tags[i] = qdrant.NewValueString(tag)
+	}
+	return map[string]*qdrant.Value{
+		attrAIGenerated: qdrant.NewValueBool(attrs.IsAIGenerated),
+		attrOwner:       qdrant.NewValueString(attrs.Owner),
+		attrMimeType:    qdrant.NewValueString(attrs.MimeType),
+		attrTags:        qdrant.NewValueFromList(tags...),
+		attrCreatedAt:   qdrant.NewValueInt(attrs.CreatedAt.Unix()),
+	}
+}
+
+// pointAttributes reads the attributes back from a payload; nil when the
+// point has none.
+func pointAttributes(payload map[string]*qdrant.Value) *Attributes {
+	created, ok := payload[attrCreatedAt]
+	if !ok {
+		return nil
+	}
+	attrs := &Attributes{
+		IsAIGenerated: payload[attrAIGenerated].GetBoolValue(),
+		Owner:         payload[attrOwner].GetStringValue(),
+		MimeType:      payload[attrMimeType].GetStringValue(),
+		CreatedAt:     time.Unix(created.GetIntegerValue(), 0).UTC(),
+	}
+	for _, tag := range payload[attrTags].GetListValue().GetValues() {
+		attrs.Tags = append(attrs.Tags, tag.GetStringValue())
+	}
+	return attrs
+}
+
+// filterConditions turns f into payload conditions that must all hold.
+func filterConditions(f *Filter) []*qdrant.Condition {
+	if f.Empty() {
+		return nil
+	}
+	var must []*qdrant.Condition
+	if f.IsAIGenerated != nil {
+		must = append(must, qdrant.NewMatchBool(attrAIGenerated, *f.IsAIGenerated))
+	}
+	if f.Owner != "" {
+		must = append(must, qdrant.NewMatchKeyword(attrOwner, f.Owner))
+	}
+	if f.MimeType != "" {
+		must = append(must, qdrant.NewMatchKeyword(attrMimeType, f.MimeType))
+	}
+	for _, tag := range f.Tags {
+		must = append(must, qdrant.NewMatchKeyword(attrTags, tag))
+	}
+	if !f.CreatedFrom.IsZero() || !f.CreatedTo.IsZero() {
+		r := &qdrant.Range{}
+		if !f.CreatedFrom.IsZero() {
+			r.Gte = qdrant.PtrOf(float64(f.CreatedFrom.Unix()))
+		}
+		if !f.CreatedTo.IsZero() {
+			r.Lt = qdrant.PtrOf(float64(f.CreatedTo.Unix()))
+		}
+		must = append(must, qdrant.NewRange(attrCreatedAt, r))
+	}
+	return must
+}
+
+// Get fetches the structure vector of one point and its version.
+func (q *QdrantDB) Get(ctx context.Context, imageID uuid.UUID) (Vector, Version, error) {
+	points, err := q.client.Get(ctx, &qdrant.GetPoints{
+		CollectionName: q.cfg.Name,
+		Ids:            []*qdrant.PointId{qdrant.NewIDUUID(imageID.String())},
+		WithPayload:    qdrant.NewWithPayloadInclude(versionKey),
+		WithVectors:    q.structureSelector(),
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("get qdrant point: %w", err)
+	}
+	if len(points) == 0 {
+		return nil, 0, nil
+	}
+
+	v := q.structureVector(points[0].GetVectors())
+	if v == nil {
+		return nil, 0, nil
+	}
+	return vectorData(v), pointVersion(points[0].GetPayload()), nil
+}
+
+// structureSelector asks for just the structure vector.
+func (q *QdrantDB) structureSelector() *qdrant.WithVectorsSelector {
+	if q.names == nil {
+		return qdrant.NewWithVectors(true)
+	}
+	return qdrant.NewWithVectorsInclude(VectorStructure)
+}
+
+// structureVector picks the structure vector out of a returned point.
+func (q *QdrantDB) structureVector(v *qdrant.VectorsOutput) *qdrant.VectorOutput {
+	if q.names == nil {
+		return v.GetVector()
+	}
+	return v.GetVectors().GetVectors()[VectorStructure]
+}
+
+// pointVersion reads the recorded version; 0 when there is none.
+func pointVersion(payload map[string]*qdrant.Value) Version {
+	return Version(payload[versionKey].GetIntegerValue())
+}
+
+// Delete removes a vector from Qdrant by image UUID.
 // Call this when soft-deleting or permanently deleting an image.
-func (q *QdrantDB) DeleteFingerprint(ctx context.Context, imageID uuid.UUID) error {
+func (q *QdrantDB) Delete(ctx context.Context, imageID uuid.UUID) error {
 	_, err := q.client.Delete(ctx, &qdrant.DeletePoints{
-		CollectionName: collectionName,
+		CollectionName: q.cfg.Name,
 		Points:         qdrant.NewPointsSelector(qdrant.NewIDUUID(imageID.String())),
 	})
 	return err
 }
 
-// FindSimilar generates a fingerprint from queryImg and returns the
-// imageIDs of the k most similar images, ranked by cosine similarity.
-// Use these IDs to fetch full metadata from PostgreSQL via db.go.
-func (q *QdrantDB) FindSimilar(ctx context.Context, queryImg image.Image, k int) ([]uuid.UUID, []float32, error) {
-	vec := Createfingerprint(queryImg)
-	return q.FindSimilarByVector(ctx, vec, k)
-}
-
-// FindSimilarByVector performs nearest-neighbour search using a raw vector.
+// Query performs nearest-neighbour search using a raw vector.
 // Returns a slice of imageIDs (to fetch metadata from PostgreSQL) and
 // their corresponding similarity scores (1.0 = identical, 0.0 = unrelated).
-func (q *QdrantDB) FindSimilarByVector(ctx context.Context, vec []float64, k int) ([]uuid.UUID, []float32, error) {
+func (q *QdrantDB) Query(ctx context.Context, vec Vector, k int) ([]uuid.UUID, []float32, error) {
 	if len(vec) != vectorSize {
 		return nil, nil, fmt.Errorf("query vector must be %d-dimensional, got %d", vectorSize, len(vec))
 	}
+	return q.QueryNamed(ctx, VectorStructure, vec, k, QueryOptions{})
+}
 
-	vec32 := make([]float32, len(vec))
-	for i, v := range vec {
-		vec32[i] = float32(v)
+// QueryNamed is Query against the named vector, with opts.MinScore as
+// Qdrant's score threshold, opts.Filter as must conditions on the payload
+// and the excluded IDs as a must_not filter.
+func (q *QdrantDB) QueryNamed(ctx context.Context, name string, vec Vector, k int, opts QueryOptions) ([]uuid.UUID, []float32, error) {
+	if err := checkNamed(name, vec); err != nil {
+		return nil, nil, err
 	}
+	if !q.supports(name) {
+		return nil, nil, fmt.Errorf("%w: %q", ErrUnknownVector, name)
+	}
+
+	vec32 := q.vector(vec)
 
-	results, err := q.client.Query(ctx, &qdrant.QueryPoints{
-		CollectionName: collectionName,
+	query := &qdrant.QueryPoints{
+		CollectionName: q.cfg.Name,
 		Query:          qdrant.NewQuery(vec32...),
+		Using:          q.using(name),
 		Limit:          qdrant.PtrOf(uint64(k)),
 		WithPayload:    qdrant.NewWithPayload(false), // we only need IDs + scores
-	})
+		Params:         q.cfg.searchParams(),
+	}
+	if opts.MinScore != 0 {
+		query.ScoreThreshold = qdrant.PtrOf(opts.MinScore)
+	}
+	if opts.Offset > 0 {
+		query.Offset = qdrant.PtrOf(uint64(opts.Offset))
+	}
+	filter := &qdrant.Filter{Must: filterConditions(opts.Filter)}
+	if len(opts.Exclude) > 0 {
+		ids := make([]*qdrant.PointId, len(opts.Exclude))
+		for i, id := range opts.Exclude {
+			ids[i] = qdrant.NewIDUUID(id.String())
+		}
+		filter.MustNot = []*qdrant.Condition{qdrant.NewHasID(ids...)}
+	}
+	if len(filter.Must) > 0 || len(filter.MustNot) > 0 {
+		query.Filter = filter
+	}
+
+	results, err := q.client.Query(ctx, query)
 	if err != nil {
 		return nil, nil, fmt.Errorf("qdrant similarity search: %w", err)
 	}
@@ -156,3 +444,185 @@ func (q *QdrantDB) FindSimilarByVector(ctx context.Context, vec []float64, k int
 
 	return ids, scores, nil
 }
+
+// Count returns the exact number of stored fingerprints.
+func (q *QdrantDB) Count(ctx context.Context) (uint64, error) {
+	n, err := q.client.Count(ctx, &qdrant.CountPoints{
+		CollectionName: q.cfg.Name,
+		Exact:          qdrant.PtrOf(true),
+	})
+	if err != nil {
+		return 0, fmt.Errorf("count qdrant points: %w", err)
+	}
+	return n, nil
+}
+
+// scrollPage is the number of points fetched per Scroll request.
+const scrollPage = 256
+
+// Scroll pages through the whole collection with structure vectors and
+// versions included.
+func (q *QdrantDB) Scroll(ctx context.Context, fn func(imageID uuid.UUID, vec Vector, v Version) error) error {
+	var offset *qdrant.PointId
+	for {
+		points, next, err := q.client.ScrollAndOffset(ctx, &qdrant.ScrollPoints{
+			CollectionName: q.cfg.Name,
+			Offset:         offset,
+			Limit:          qdrant.PtrOf(uint32(scrollPage)),
+			WithPayload:    qdrant.NewWithPayloadInclude(versionKey),
+			WithVectors:    q.structureSelector(),
+		})
+		if err != nil {
+			return fmt.Errorf("scroll qdrant points: %w", err)
+		}
+
+		for _, p := range points {
+			uid, err := uuid.Parse(p.GetId().GetUuid())
+			if err != nil {
+				continue
+			}
+			v := q.structureVector(p.GetVectors())
+			if v == nil {
+				continue // point without a structure vector
+			}
+			if err := fn(uid, vectorData(v), pointVersion(p.GetPayload())); err != nil {
+				return err
+			}
+		}
+
+		if next == nil {
+			return nil
+		}
+		offset = next
+	}
+}
+
+// vectorData returns a dense vector, whichever field the server filled
+// in.
+func vectorData(v *qdrant.VectorOutput) Vector {
+	data := v.GetDense().GetData()
+	if data == nil {
+		data = v.GetData()
+	}
+	return Vector(data)
+}
+
+// upsertBatch is the number of points sent per StoreBatch request.
+const upsertBatch = 256
+
+// StoreBatch upserts whole points, upsertBatch at a time. Unlike Store it
+// replaces each point outright, payload included. A legacy collection
+// refuses points carrying vectors other than the structure one.
+func (q *QdrantDB) StoreBatch(ctx context.Context, points []Point) error {
+	for start := 0; start < len(points); start += upsertBatch {
+		batch := points[start:min(start+upsertBatch, len(points))]
+
+		structs := make([]*qdrant.PointStruct, len(batch))
+		for i, p := range batch {
+			if err := checkPoint(p); err != nil {
+				return err
+			}
+
+			var vectors *qdrant.Vectors
+			if q.names == nil {
+				for name := range p.Vectors {
+					if name != VectorStructure {
+						return fmt.Errorf("point %s: %w: %q", p.ID, ErrUnknownVector, name)
+					}
+				}
+				vectors = qdrant.NewVectors(q.vector(p.Vectors[VectorStructure])...)
+			} else {
+				named := make(map[string]*qdrant.Vector, len(p.Vectors))
+				for name, vec := range p.Vectors {
+					if !q.names[name] {
+						return fmt.Errorf("point %s: %w: %q", p.ID, ErrUnknownVector, name)
+					}
+					named[name] = qdrant.NewVector(q.vector(vec)...)
+				}
+				vectors = qdrant.NewVectorsMap(named)
+			}
+
+			payload := map[string]*qdrant.Value{versionKey: qdrant.NewValueInt(int64(p.Version))}
+			if p.Attributes != nil {
+				for field, value := range attributesPayload(*p.Attributes) {
+					payload[field] = value
+				}
+			}
+
+			structs[i] = &qdrant.PointStruct{
+				Id:      qdrant.NewIDUUID(p.ID.String()),
+				Vectors: vectors,
+				Payload: payload,
+			}
+		}
+
+		_, err := q.client.Upsert(ctx, &qdrant.UpsertPoints{
+			CollectionName: q.cfg.Name,
+			Wait:           qdrant.PtrOf(true),
+			Points:         structs,
+		})
+		if err != nil {
+			return fmt.Errorf("store %d points in qdrant: %w", len(structs), err)
+		}
+	}
+	return nil
+}
+
+// ScrollPoints pages through the whole collection with every vector and
+// the payload included.
+func (q *QdrantDB) ScrollPoints(ctx context.Context, fn func(Point) error) error {
+	var offset *qdrant.PointId
+	for {
+		points, next, err := q.client.ScrollAndOffset(ctx, &qdrant.ScrollPoints{
+			CollectionName: q.cfg.Name,
+			Offset:         offset,
+			Limit:          qdrant.PtrOf(uint32(scrollPage)),
+			WithPayload:    qdrant.NewWithPayload(true),
+			WithVectors:    qdrant.NewWithVectors(true),
+		})
+		if err != nil {
+			return fmt.Errorf("scroll qdrant points: %w", err)
+		}
+
+		for _, p := range points {
+			uid, err := uuid.Parse(p.GetId().GetUuid())
+			if err != nil {
+				continue
+			}
+			v := q.structureVector(p.GetVectors())
+			if v == nil {
+				continue // point without a structure vector
+			}
+
+			point := Point{
+				ID:         uid,
+				Version:    pointVersion(p.GetPayload()),
+				Vectors:    map[string]Vector{VectorStructure: vectorData(v)},
+				Attributes: pointAttributes(p.GetPayload()),
+			}
+			if q.names != nil {
+				for name, vec := range p.GetVectors().GetVectors().GetVectors() {
+					point.Vectors[name] = vectorData(vec)
+				}
+			}
+			if err := fn(point); err != nil {
+				return err
+			}
+		}
+
+		if next == nil {
+			return nil
+		}
+		offset = next
+	}
+}
+
+// vector is vec as sent to Qdrant. A dot collection gets it
+// L2-normalised, so its scores are the cosine similarities a cosine
+// collection returns.
+func (q *QdrantDB) vector(vec Vector) []float32 {
+	if q.cfg.Distance == DistanceDot {
+		return normalise(vec)
+	}
+	return vec
+}
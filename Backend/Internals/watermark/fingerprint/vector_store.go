@@ -0,0 +1,213 @@
+package fingerprint
+
+// vector_store.go — Storage-agnostic access to image fingerprints
+//
+// ImageService only talks to a VectorStore. Production uses Qdrant
+// (vector_db.go); local development and tests can use the in-process
+// MemoryStore (memory_store.go), optionally persisted to a file.
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/google/uuid"
+)
+
+// VectorStore keeps named fingerprint vectors per image UUID — the
+// structure fingerprint and, optionally, the colour one (colour.go) — and
+// answers nearest-neighbour queries by cosine similarity.
+type VectorStore interface {
+	// Store inserts or replaces the structure fingerprint of imageID and
+	// records the version it was computed with.
+	Store(ctx context.Context, imageID uuid.UUID, vec Vector, v Version) error
+
+	// Get returns the structure fingerprint of imageID and its recorded
+	// version, or a nil vector if there is none. Vectors stored before
+	// versions were recorded come back as version 0.
+	Get(ctx context.Context, imageID uuid.UUID) (Vector, Version, error)
+
+	// StoreNamed inserts or replaces one named vector of imageID, leaving
+	// its other vectors alone.
+	StoreNamed(ctx context.Context, imageID uuid.UUID, name string, vec Vector) error
+
+	// SetAttributes replaces the filterable attributes of imageID. Call it
+	// after Store; images without attributes only match unfiltered queries.
+	SetAttributes(ctx context.Context, imageID uuid.UUID, attrs Attributes) error
+
+	// Delete removes every vector and attribute of imageID. Deleting an
+	// unknown ID is not an error.
+	Delete(ctx context.Context, imageID uuid.UUID) error
+
+	// Query returns up to k image IDs ranked by structure similarity to
+	// vec, with their scores (1.0 = identical, 0.0 = unrelated).
+	Query(ctx context.Context, vec Vector, k int) ([]uuid.UUID, []float32, error)
+
+	// QueryNamed is Query against the named vector, narrowed by opts.
+	// Images without that vector are not returned.
+	QueryNamed(ctx context.Context, name string, vec Vector, k int, opts QueryOptions) ([]uuid.UUID, []float32, error)
+
+	// Count returns the number of stored structure fingerprints.
+	Count(ctx context.Context) (uint64, error)
+
+	// Scroll calls fn for every structure fingerprint, stopping at the first
+	// error. Vectors come back as stored (cosine stores L2-normalise them),
+	// with their recorded version. fn may call Store for the vector it was
+	// handed.
+	Scroll(ctx context.Context, fn func(imageID uuid.UUID, vec Vector, v Version) error) error
+
+	// StoreBatch inserts or replaces whole points — vectors, version and
+	// attributes — in as few round trips as the store allows. Vectors and
+	// attributes a point leaves out are removed from it.
+	StoreBatch(ctx context.Context, points []Point) error
+
+	// ScrollPoints calls fn for every point holding a structure vector,
+	// with all its vectors and attributes, stopping at the first error.
+	ScrollPoints(ctx context.Context, fn func(Point) error) error
+
+	Close() error
+}
+
+// Point is everything a VectorStore holds for one image, the unit of
+// StoreBatch and ScrollPoints.
+type Point struct {
+	ID      uuid.UUID
+	Version Version
+
+	// Vectors by name (VectorStructure, VectorColour); the structure
+	// vector is required.
+	Vectors map[string]Vector
+
+	// Attributes is nil for an image stored without any.
+	Attributes *Attributes
+}
+
+// checkPoint validates every vector of p before it is stored.
+func checkPoint(p Point) error {
+	if _, ok := p.Vectors[VectorStructure]; !ok {
+		return fmt.Errorf("point %s has no %s vector", p.ID, VectorStructure)
+	}
+	for name, vec := range p.Vectors {
+		if err := checkNamed(name, vec); err != nil {
+			return fmt.Errorf("point %s: %w", p.ID, err)
+		}
+	}
+	return nil
+}
+
+// Dimension is the length of every structure fingerprint, ColourDimension
+// that of every colour one.
+const (
+	Dimension       = vectorSize
+	ColourDimension = colourVectorSize
+)
+
+// QueryOptions narrows a nearest-neighbour query. The zero value returns
+// the plain top k.
+type QueryOptions struct {
+	// MinScore drops hits scoring below it; the store applies it, so
+	// fewer than k hits may come back. Zero means no limit.
+	MinScore float32
+
+	// Offset skips that many of the best hits, for paging.
+	Offset int
+
+	// Exclude leaves these images out, e.g. the one being searched from.
+	Exclude []uuid.UUID
+
+	// Filter keeps only images whose attributes match. Nil matches all.
+	Filter *Filter
+}
+
+// ErrUnknownVector is returned for a vector name the store doesn't hold,
+// e.g. colour vectors in a collection created before they existed.
+var ErrUnknownVector = errors.New("vector name not supported by this store")
+
+// checkNamed validates a named vector's dimension.
+func checkNamed(name string, vec Vector) error {
+	size, ok := vectorSizes[name]
+	if !ok {
+		return fmt.Errorf("%w: %q", ErrUnknownVector, name)
+	}
+	if len(vec) != size {
+		return fmt.Errorf("%s vector must be %d-dimensional, got %d", name, size, len(vec))
+	}
+	return nil
+}
+
+// Vector store backends accepted by StoreConfig.Backend.
+const (
+	BackendQdrant = "qdrant"
+	BackendMemory = "memory"
+)
+
+// StoreConfig selects and configures a VectorStore.
+type StoreConfig struct {
+	Backend string // "qdrant" (default) or "memory"
+
+	// Qdrant gRPC endpoint and collection layout.
+	QdrantHost string
+	QdrantPort int
+	Collection CollectionConfig
+
+	// Path persists the memory backend between restarts. Empty keeps
+	// everything in RAM only.
+	Path string
+}
+
+// OpenVectorStore connects to the configured backend and makes sure it is
+// ready to accept fingerprints.
+func OpenVectorStore(ctx context.Context, cfg StoreConfig) (VectorStore, error) {
+	switch cfg.Backend {
+	case "", BackendQdrant:
+		q, err := NewQdrantDB(cfg.QdrantHost, cfg.QdrantPort, cfg.Collection)
+		if err != nil {
+			return nil, err
+		}
+		if err := q.CreateCollection(ctx); err != nil {
+			q.Close()
+			return nil, err
+		}
+		return q, nil
+
+	case BackendMemory:
+		return NewMemoryStore(cfg.Path)
+
+	default:
+		return nil, fmt.Errorf("unknown vector store backend %q", cfg.Backend)
+	}
+}
+
+// FindSimilar generates a version v fingerprint from queryImg and returns
+// the imageIDs of the k most similar images in store, ranked by cosine
+// similarity. v must match the version of the stored vectors. Use these
+// IDs to fetch full metadata from PostgreSQL.
+func FindSimilar(ctx context.Context, store VectorStore, v Version, queryImg image.Image, k int, opts QueryOptions) ([]uuid.UUID, []float32, error) {
+	vec, err := CreateFingerprintVersion(queryImg, v)
+	if err != nil {
+		return nil, nil, err
+	}
+	return store.QueryNamed(ctx, VectorStructure, vec, k, opts)
+}
+
+// Similarity is the cosine similarity of two fingerprints, on the same
+// scale as query scores. Vectors of different lengths, or a zero vector,
+// score 0.
+func Similarity(a, b Vector) float32 {
+	if len(a) != len(b) {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		x, y := float64(a[i]), float64(b[i])
+		dot += x * y
+		na += x * x
+		nb += y * y
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return float32(dot / math.Sqrt(na*nb))
+}
@@ -0,0 +1,41 @@
+package fingerprint
+
+import (
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestVectorEncodings(t *testing.T) {
+	vec := testVector(3)
+
+	parsed, err := ParseVector(vec.String())
+	if err != nil || !slices.Equal(parsed, vec) {
+		t.Fatalf("ParseVector(String()) = %d values, %v", len(parsed), err)
+	}
+	if n := len(vec.String()); n > 4*len(vec)*4/3+4 {
+		t.Fatalf("base64 form is %d bytes, want about %d", n, 4*len(vec)*4/3)
+	}
+
+	data, err := json.Marshal(struct{ V Vector }{vec})
+	if err != nil || !strings.Contains(string(data), `"`+vec.String()+`"`) {
+		t.Fatalf("JSON = %.60s…, %v; want the base64 string", data, err)
+	}
+	var back struct{ V Vector }
+	if err := json.Unmarshal(data, &back); err != nil || !slices.Equal(back.V, vec) {
+		t.Fatalf("JSON round trip = %d values, %v", len(back.V), err)
+	}
+
+	var fromArray Vector
+	if err := json.Unmarshal([]byte("[0.5, -1.25, 3]"), &fromArray); err != nil || !slices.Equal(fromArray, Vector{0.5, -1.25, 3}) {
+		t.Fatalf("array form = %v, %v", fromArray, err)
+	}
+
+	for _, bad := range []string{`"not base64!"`, `"AAAA"`, `{}`, `[1, "x"]`} {
+		var v Vector
+		if err := json.Unmarshal([]byte(bad), &v); err == nil {
+			t.Fatalf("%s decoded to %v", bad, v)
+		}
+	}
+}
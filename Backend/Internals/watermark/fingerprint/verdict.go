@@ -0,0 +1,69 @@
+package fingerprint
+
+// verdict.go — Turning similarity scores into verdicts
+//
+// A cosine score on its own means little to a client: whether 0.93 is a
+// re-encoded copy or merely a similar scene depends on the fingerprint
+// version. Thresholds maps scores onto four verdicts instead, from the
+// top down: a score at or above ExactCopy is an exact copy, at or above
+// NearDuplicate a near-duplicate (re-encoded, resized, lightly edited),
+// at or above Related a related image (same scene, heavier edits), and
+// anything lower is unrelated.
+
+import (
+	"fmt"
+)
+
+// Verdict classifies how a similar image relates to the query.
+type Verdict string
+
+const (
+	VerdictExactCopy     Verdict = "exact-copy"
+	VerdictNearDuplicate Verdict = "near-duplicate"
+	VerdictRelated       Verdict = "related"
+	VerdictUnrelated     Verdict = "unrelated"
+)
+
+// Thresholds are the lowest scores of each verdict.
+type Thresholds struct {
+	ExactCopy     float32
+	NearDuplicate float32
+	Related       float32
+}
+
+// DefaultThresholds suit the v3 fingerprint: a JPEG re-encode or resize
+// of a copy scores above 0.999, while trimming 15% off the edges brings it
+// to about 0.9.
+var DefaultThresholds = Thresholds{
+	ExactCopy:     0.995,
+	NearDuplicate: 0.95,
+	Related:       0.85,
+}
+
+// Validate checks that the thresholds lie in [-1, 1] and descend.
+func (t Thresholds) Validate() error {
+	for _, x := range []float32{t.ExactCopy, t.NearDuplicate, t.Related} {
+		if x < -1 || x > 1 {
+			return fmt.Errorf("similarity threshold %v outside [-1, 1]", x)
+		}
+	}
+	if !(t.ExactCopy >= t.NearDuplicate && t.NearDuplicate >= t.Related) {
+		return fmt.Errorf("similarity thresholds must descend: exact-copy %v, near-duplicate %v, related %v",
+			t.ExactCopy, t.NearDuplicate, t.Related)
+	}
+	return nil
+}
+
+// Classify returns the verdict for a similarity score.
+func (t Thresholds) Classify(score float32) Verdict {
+	switch {
+	case score >= t.ExactCopy:
+		return VerdictExactCopy
+	case score >= t.NearDuplicate:
+		return VerdictNearDuplicate
+	case score >= t.Related:
+		return VerdictRelated
+	default:
+		return VerdictUnrelated
+	}
+}
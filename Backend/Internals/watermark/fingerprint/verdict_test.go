@@ -0,0 +1,101 @@
+package fingerprint
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/jpeg"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestClassify(t *testing.T) {
+	th := Thresholds{ExactCopy: 0.99, NearDuplicate: 0.9, Related: 0.7}
+	tests := []struct {
+		score float32
+		want  Verdict
+	}{
+		{1, VerdictExactCopy},
+		{0.99, VerdictExactCopy},
+		{0.95, VerdictNearDuplicate},
+		{0.9, VerdictNearDuplicate},
+		{0.8, VerdictRelated},
+		{0.69, VerdictUnrelated},
+		{-0.5, VerdictUnrelated},
+	}
+	for _, tt := range tests {
+		if got := th.Classify(tt.score); got != tt.want {
+			t.Errorf("Classify(%v) = %s, want %s", tt.score, got, tt.want)
+		}
+	}
+
+	if err := DefaultThresholds.Validate(); err != nil {
+		t.Errorf("DefaultThresholds: %v", err)
+	}
+	if err := (Thresholds{ExactCopy: 0.8, NearDuplicate: 0.9, Related: 0.7}).Validate(); err == nil {
+		t.Error("thresholds out of order accepted")
+	}
+	if err := (Thresholds{ExactCopy: 1.5}).Validate(); err == nil {
+		t.Error("threshold above 1 accepted")
+	}
+}
+
+func TestDefaultThresholdsOnEdits(t *testing.T) {
+	img := scene(512, 384, 0.7)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 70}); err != nil {
+		t.Fatal(err)
+	}
+	reencoded, err := jpeg.Decode(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	store, err := NewMemoryStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	original, other := uuid.New(), uuid.New()
+	if err := store.Store(ctx, original, mustFingerprint(t, img, V3), V3); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Store(ctx, other, mustFingerprint(t, scene(512, 384, 3.5), V3), V3); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		query image.Image
+		want  Verdict
+	}{
+		{"re-encoded", reencoded, VerdictExactCopy},
+		{"cropped", img.SubImage(image.Rect(40, 30, 472, 354)), VerdictRelated},
+	}
+	for _, tt := range tests {
+		ids, scores, err := FindSimilar(ctx, store, V3, tt.query, 2, QueryOptions{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ids[0] != original {
+			t.Fatalf("%s: original not ranked first", tt.name)
+		}
+		if got := DefaultThresholds.Classify(scores[0]); got != tt.want {
+			t.Errorf("%s: score %.4f classified %s, want %s", tt.name, scores[0], got, tt.want)
+		}
+		if got := DefaultThresholds.Classify(scores[1]); got != VerdictUnrelated {
+			t.Errorf("%s: other scene (%.4f) classified %s", tt.name, scores[1], got)
+		}
+	}
+
+	// MinScore leaves the unrelated scene out.
+	ids, _, err := FindSimilar(ctx, store, V3, reencoded, 2, QueryOptions{MinScore: DefaultThresholds.Related})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 1 || ids[0] != original {
+		t.Fatalf("MinScore query returned %v", ids)
+	}
+}
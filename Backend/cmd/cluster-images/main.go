@@ -0,0 +1,80 @@
+// Command cluster-images groups the catalogue's near-identical images and
+// saves the clusters for the /api/v1/admin/clusters report:
+//
+//	go run ./cmd/cluster-images                       # near-duplicate threshold
+//	go run ./cmd/cluster-images -threshold 0.99 -k 20
+//
+// Every stored fingerprint is compared with its k nearest neighbours;
+// images scoring at least the threshold, directly or through a chain of
+// such matches, share a cluster. Each run replaces the previous clusters.
+// It uses the same database, VECTOR_STORE and SIMILARITY_* settings as the
+// server.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/config"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/database"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/repository"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/services"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
+)
+
+func main() {
+	threshold := flag.Float64("threshold", 0, "lowest score joining two images (0: SIMILARITY_NEAR_DUPLICATE)")
+	k := flag.Int("k", services.DefaultClusterNeighbours, "neighbours compared per image")
+	flag.Parse()
+
+	cfg := config.LoadConfig()
+	ctx := context.Background()
+
+	db, err := database.Connect(cfg)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer db.Close()
+
+	store, err := fingerprint.OpenVectorStore(ctx, fingerprint.StoreConfig{
+		Backend:    cfg.VectorStore,
+		QdrantHost: cfg.QdrantHost,
+		QdrantPort: cfg.QdrantPort,
+		Path:       cfg.VectorStorePath,
+		Collection: fingerprint.CollectionConfig{
+			Name:            cfg.QdrantCollection,
+			Distance:        cfg.QdrantDistance,
+			HNSWM:           cfg.QdrantHNSWM,
+			HNSWEfConstruct: cfg.QdrantHNSWEfConstruct,
+			HNSWEf:          cfg.QdrantHNSWEf,
+			Quantization:    cfg.QdrantQuantization,
+		},
+	})
+	if err != nil {
+		log.Fatal("Failed to open vector store:", err)
+	}
+	defer store.Close()
+
+	svc := services.NewImageService(repository.NewDB(db), store, services.ImageServiceConfig{
+		FingerprintVersion: fingerprint.Version(cfg.FingerprintVersion),
+		Thresholds: fingerprint.Thresholds{
+			ExactCopy:     float32(cfg.ExactCopyThreshold),
+			NearDuplicate: float32(cfg.NearDuplicateThreshold),
+			Related:       float32(cfg.RelatedThreshold),
+		},
+	})
+
+	report, err := svc.ClusterCatalogue(ctx, services.ClusterRequest{
+		Threshold:  float32(*threshold),
+		Neighbours: *k,
+	})
+	if err != nil {
+		log.Fatal("Clustering failed:", err)
+	}
+
+	fmt.Printf("Compared %d fingerprints\n", report.Images)
+	fmt.Printf("  clusters of near-identical images: %d\n", report.Clusters)
+	fmt.Printf("  images in them:                    %d\n", report.Clustered)
+}
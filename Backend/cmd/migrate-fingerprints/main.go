@@ -0,0 +1,92 @@
+// Command migrate-fingerprints rewrites every stored fingerprint vector
+// from one version to another, e.g. after changing FINGERPRINT_VERSION:
+//
+//	go run ./cmd/migrate-fingerprints -from 1 -to 3
+//
+// It uses the same VECTOR_STORE settings as the server. Vectors that record
+// their version are converted from that version; -from only applies to
+// vectors stored before versions were recorded. Only versions that
+// can be derived from the stored vectors are supported (1 -> 2 or 3); for
+// anything else the images have to be watermarked and indexed again.
+// Both conversions are idempotent, so an interrupted run can simply be
+// started again. The server performs the same migration on start-up; this
+// command does it offline, ahead of the restart.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/config"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
+	"github.com/google/uuid"
+)
+
+func main() {
+	from := flag.Int("from", int(fingerprint.V1), "version of stored vectors that don't record one")
+	to := flag.Int("to", int(fingerprint.DefaultVersion), "version to convert them to")
+	dryRun := flag.Bool("dry-run", false, "convert without writing anything back")
+	flag.Parse()
+
+	cfg := config.LoadConfig()
+	ctx := context.Background()
+
+	store, err := fingerprint.OpenVectorStore(ctx, fingerprint.StoreConfig{
+		Backend:    cfg.VectorStore,
+		QdrantHost: cfg.QdrantHost,
+		QdrantPort: cfg.QdrantPort,
+		Path:       cfg.VectorStorePath,
+		Collection: fingerprint.CollectionConfig{
+			Name:            cfg.QdrantCollection,
+			Distance:        cfg.QdrantDistance,
+			HNSWM:           cfg.QdrantHNSWM,
+			HNSWEfConstruct: cfg.QdrantHNSWEfConstruct,
+			HNSWEf:          cfg.QdrantHNSWEf,
+			Quantization:    cfg.QdrantQuantization,
+		},
+	})
+	if err != nil {
+		log.Fatal("Failed to open vector store:", err)
+	}
+	defer store.Close()
+
+	total, err := store.Count(ctx)
+	if err != nil {
+		log.Fatal("Failed to count fingerprints:", err)
+	}
+	fmt.Printf("Migrating %d fingerprints from v%d to v%d\n", total, *from, *to)
+
+	migrated := 0
+	err = store.Scroll(ctx, func(id uuid.UUID, vec fingerprint.Vector, v fingerprint.Version) error {
+		if v == 0 {
+			v = fingerprint.Version(*from)
+		}
+		out, err := fingerprint.MigrateVector(vec, v, fingerprint.Version(*to))
+		if err != nil {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+		if !*dryRun {
+			if err := store.Store(ctx, id, out, fingerprint.Version(*to)); err != nil {
+				return fmt.Errorf("%s: %w", id, err)
+			}
+		}
+
+		migrated++
+		if migrated%1000 == 0 {
+			fmt.Printf("  %d / %d\n", migrated, total)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Migration stopped after %d fingerprints: %v", migrated, err)
+	}
+
+	fmt.Printf("Done: %d fingerprints now v%d", migrated, *to)
+	if *dryRun {
+		fmt.Print(" (dry run, nothing written)")
+	}
+	fmt.Println()
+	fmt.Printf("Set FINGERPRINT_VERSION=%d before restarting the server.\n", *to)
+}
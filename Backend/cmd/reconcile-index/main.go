@@ -0,0 +1,101 @@
+// Command reconcile-index compares image_metadata with the vector store
+// and reports rows that have no fingerprint and fingerprints that have no
+// row:
+//
+//	go run ./cmd/reconcile-index              # report only
+//	go run ./cmd/reconcile-index -fix         # also repair
+//	go run ./cmd/reconcile-index -attributes  # also rewrite vector attributes
+//
+// Fixing marks rows without a vector as failed (their originals aren't
+// kept, so the image has to be watermarked again) and deletes vectors
+// without a row. Rows still waiting in the outbox are not orphans and are
+// left alone. -attributes copies every row's filterable attributes (owner,
+// tags, ...) onto its vectors, for images indexed before they were
+// mirrored. It uses the same database and VECTOR_STORE settings as the
+// server.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/config"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/database"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/repository"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/services"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
+)
+
+func main() {
+	fix := flag.Bool("fix", false, "mark rows without a vector failed and delete vectors without a row")
+	list := flag.Bool("list", false, "print the IDs of every orphan found")
+	attributes := flag.Bool("attributes", false, "rewrite the filterable attributes of every stored vector from its row")
+	flag.Parse()
+
+	cfg := config.LoadConfig()
+	ctx := context.Background()
+
+	db, err := database.Connect(cfg)
+	if err != nil {
+		log.Fatal("Failed to connect to database:", err)
+	}
+	defer db.Close()
+
+	store, err := fingerprint.OpenVectorStore(ctx, fingerprint.StoreConfig{
+		Backend:    cfg.VectorStore,
+		QdrantHost: cfg.QdrantHost,
+		QdrantPort: cfg.QdrantPort,
+		Path:       cfg.VectorStorePath,
+		Collection: fingerprint.CollectionConfig{
+			Name:            cfg.QdrantCollection,
+			Distance:        cfg.QdrantDistance,
+			HNSWM:           cfg.QdrantHNSWM,
+			HNSWEfConstruct: cfg.QdrantHNSWEfConstruct,
+			HNSWEf:          cfg.QdrantHNSWEf,
+			Quantization:    cfg.QdrantQuantization,
+		},
+	})
+	if err != nil {
+		log.Fatal("Failed to open vector store:", err)
+	}
+	defer store.Close()
+
+	svc := services.NewImageService(repository.NewDB(db), store, services.ImageServiceConfig{
+		FingerprintVersion: fingerprint.Version(cfg.FingerprintVersion),
+	})
+
+	report, err := svc.Reconcile(ctx, *fix)
+	if err != nil {
+		log.Fatal("Reconciliation failed:", err)
+	}
+
+	fmt.Printf("Checked %d rows and %d vectors\n", report.Rows, report.Vectors)
+	fmt.Printf("  rows without a vector:  %d\n", len(report.RowsWithoutVector))
+	fmt.Printf("  vectors without a row:  %d\n", len(report.VectorsWithoutRow))
+	fmt.Printf("  vectors not yet recorded on their row: %d (the re-index worker backfills these)\n", report.Unrecorded)
+
+	if *list {
+		for _, id := range report.RowsWithoutVector {
+			fmt.Println("row-without-vector", id)
+		}
+		for _, id := range report.VectorsWithoutRow {
+			fmt.Println("vector-without-row", id)
+		}
+	}
+
+	if *attributes {
+		n, err := svc.SyncAttributes(ctx)
+		if err != nil {
+			log.Fatal("Attribute sync failed:", err)
+		}
+		fmt.Printf("Rewrote the attributes of %d vectors\n", n)
+	}
+
+	if *fix {
+		fmt.Println("Fixed: rows marked failed, orphan vectors deleted.")
+	} else if len(report.RowsWithoutVector)+len(report.VectorsWithoutRow) > 0 {
+		fmt.Println("Run again with -fix to repair.")
+	}
+}
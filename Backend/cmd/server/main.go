@@ -4,17 +4,18 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/config"
 	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/database"
 	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/api/handlers"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/api/routes"
 	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/repository"
 	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/services"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/engine"
 	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
 
 	//"github.com/gofiber/fiber"
-	"github.com/gofiber/fiber/v2"
-	"github.com/gofiber/fiber/v2/middleware/cors"
 )
 
 func main() {
@@ -31,38 +32,92 @@ func main() {
 
 	imageRepo := repository.NewDB(db)
 
-	// Connect to Qdrant
-	imageVectorDB, err := fingerprint.NewQdrantDB("localhost", 6334)
+	// Connect to the vector store (Qdrant, or the in-process index)
+	imageVectorDB, err := fingerprint.OpenVectorStore(context.Background(), fingerprint.StoreConfig{
+		Backend:    cfg.VectorStore,
+		QdrantHost: cfg.QdrantHost,
+		QdrantPort: cfg.QdrantPort,
+		Path:       cfg.VectorStorePath,
+		Collection: fingerprint.CollectionConfig{
+			Name:            cfg.QdrantCollection,
+			Distance:        cfg.QdrantDistance,
+			HNSWM:           cfg.QdrantHNSWM,
+			HNSWEfConstruct: cfg.QdrantHNSWEfConstruct,
+			HNSWEf:          cfg.QdrantHNSWEf,
+			Quantization:    cfg.QdrantQuantization,
+		},
+	})
 	if err != nil {
-		log.Fatal("Failed to connect to Qdrant:", err)
+		log.Fatal("Failed to open vector store:", err)
 	}
+	defer imageVectorDB.Close()
 
-	err = imageVectorDB.CreateCollection(context.Background())
+	fingerprintVersion := fingerprint.Version(cfg.FingerprintVersion)
+	if !fingerprintVersion.Valid() {
+		log.Fatal("Unknown FINGERPRINT_VERSION:", cfg.FingerprintVersion)
+	}
+	unversionedAs := fingerprint.Version(cfg.FingerprintUnversionedAs)
+	if !unversionedAs.Valid() {
+		log.Fatal("Unknown FINGERPRINT_UNVERSIONED_AS:", cfg.FingerprintUnversionedAs)
+	}
+
+	thresholds := fingerprint.Thresholds{
+		ExactCopy:     float32(cfg.ExactCopyThreshold),
+		NearDuplicate: float32(cfg.NearDuplicateThreshold),
+		Related:       float32(cfg.RelatedThreshold),
+	}
+	if err := thresholds.Validate(); err != nil {
+		log.Fatal("Invalid SIMILARITY_* thresholds:", err)
+	}
+
+	duplicateAction, err := services.ParseDuplicateAction(cfg.EmbedDuplicateAction)
 	if err != nil {
-		log.Println("Collection may already exist:", err)
+		log.Fatal("Invalid EMBED_DUPLICATE_ACTION:", err)
+	}
+	if cfg.EmbedDuplicateThreshold < 0 || cfg.EmbedDuplicateThreshold > 1 {
+		log.Fatal("EMBED_DUPLICATE_THRESHOLD must be between 0 and 1")
 	}
 
-	imageServices := services.NewImageService(imageRepo, imageVectorDB)
+	imageServices := services.NewImageService(imageRepo, imageVectorDB, services.ImageServiceConfig{
+		WatermarkKey: []byte(cfg.WatermarkKey),
+		Engine: engine.Options{
+			TileCacheBytes: cfg.TileCacheMB << 20,
+			Workers:        cfg.EngineWorkers,
+		},
+		FingerprintVersion: fingerprintVersion,
+		ColourFingerprint:  cfg.ColourFingerprint,
+		UnversionedAs:      unversionedAs,
+		Thresholds:         thresholds,
+		DuplicateAction:    duplicateAction,
+		DuplicateThreshold: float32(cfg.EmbedDuplicateThreshold),
+	})
+
+	// Queries are compared with the stored vectors, so those have to be at
+	// FINGERPRINT_VERSION before the first request
+	if report, err := imageServices.MigrateIndex(context.Background()); err != nil {
+		log.Fatal("Vector index is not at FINGERPRINT_VERSION:", err)
+	} else if report.Processed > 0 {
+		fmt.Printf("Vector index ready: %d recorded, %d migrated, %d failed\n", report.Backfilled, report.Migrated, report.Failed)
+	}
 
-	imageHandler := handlers.NewImageHandler(imageServices)
+	// Retry vector writes that didn't get through at upload time
+	outboxInterval := time.Duration(cfg.OutboxIntervalSeconds) * time.Second
+	if outboxInterval <= 0 {
+		outboxInterval = 5 * time.Second
+	}
+	go imageServices.RunOutboxDispatcher(context.Background(), outboxInterval)
 
-	fmt.Println("Server initialized successfully")
+	// Bring unindexed and stale vectors up to FingerprintVersion
+	if cfg.ReindexIntervalSeconds > 0 {
+		go imageServices.RunReindexer(context.Background(), time.Duration(cfg.ReindexIntervalSeconds)*time.Second)
+	}
 
-	app := fiber.New(fiber.Config{
-	BodyLimit: 20 * 1024 * 1024, // 20 MB
-})
+	imageHandler := handlers.NewImageHandler(imageServices)
 
-	app.Use(cors.New(cors.Config{
-		AllowOrigins:     "http://localhost:3000,http://localhost:5173", // Your frontend URLs
-		AllowMethods:     "GET,POST,PUT,DELETE,OPTIONS",
-		AllowHeaders:     "Origin,Content-Type,Accept,Authorization",
-		AllowCredentials: true, // IMPORTANT: Allow cookies to be sent
-	}))
+	fmt.Println("Server initialized successfully")
 
-	api := app.Group("/api/v1")
+	app := routes.NewApp(imageHandler, cfg.AdminAPIKey)
 
-	api.Post("/watermark", imageHandler.ImageWatermarkHandler)
-	api.Post("/authenticate", imageHandler.ImageAuthHandler)
 	// user.Post("/register",func(c *fiber.Ctx) error {
 
 	// 	return c.status(200).JSON(fiber.Map{
@@ -0,0 +1,131 @@
+// Command transfer-vectors exports the whole vector store to a portable
+// NDJSON file, or imports one, for backups and for seeding a local store:
+//
+//	go run ./cmd/transfer-vectors -export fingerprints.ndjson.gz
+//	VECTOR_STORE=memory VECTOR_STORE_PATH=dev.gob \
+//	    go run ./cmd/transfer-vectors -import fingerprints.ndjson.gz
+//
+// Files ending in .gz are compressed; "-" reads stdin or writes stdout.
+// Imported points replace those with the same image ID. Only the vector
+// store is touched, using the same VECTOR_STORE settings as the server.
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/config"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
+)
+
+func main() {
+	export := flag.String("export", "", "write every stored point to this file")
+	importPath := flag.String("import", "", "load the points of this file into the store")
+	batch := flag.Int("batch", fingerprint.DefaultImportBatch, "points written per request when importing")
+	flag.Parse()
+
+	if (*export == "") == (*importPath == "") {
+		log.Fatal("Give exactly one of -export or -import")
+	}
+
+	cfg := config.LoadConfig()
+	ctx := context.Background()
+
+	store, err := fingerprint.OpenVectorStore(ctx, fingerprint.StoreConfig{
+		Backend:    cfg.VectorStore,
+		QdrantHost: cfg.QdrantHost,
+		QdrantPort: cfg.QdrantPort,
+		Path:       cfg.VectorStorePath,
+		Collection: fingerprint.CollectionConfig{
+			Name:            cfg.QdrantCollection,
+			Distance:        cfg.QdrantDistance,
+			HNSWM:           cfg.QdrantHNSWM,
+			HNSWEfConstruct: cfg.QdrantHNSWEfConstruct,
+			HNSWEf:          cfg.QdrantHNSWEf,
+			Quantization:    cfg.QdrantQuantization,
+		},
+	})
+	if err != nil {
+		log.Fatal("Failed to open vector store:", err)
+	}
+	defer store.Close()
+
+	if *export != "" {
+		n, err := exportTo(ctx, store, *export)
+		if err != nil {
+			log.Fatal("Export failed:", err)
+		}
+		fmt.Fprintf(os.Stderr, "Exported %d points\n", n)
+		return
+	}
+
+	n, err := importFrom(ctx, store, *importPath, *batch)
+	if err != nil {
+		log.Fatalf("Import failed after %d points: %v", n, err)
+	}
+	fmt.Fprintf(os.Stderr, "Imported %d points\n", n)
+}
+
+func exportTo(ctx context.Context, store fingerprint.VectorStore, path string) (int, error) {
+	out := os.Stdout
+	if path != "-" {
+		f, err := os.Create(path)
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	buf := bufio.NewWriter(out)
+	var w io.Writer = buf
+	var gz *gzip.Writer
+	if strings.HasSuffix(path, ".gz") {
+		gz = gzip.NewWriter(buf)
+		w = gz
+	}
+
+	n, err := fingerprint.Export(ctx, store, w)
+	if err != nil {
+		return n, err
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return n, err
+		}
+	}
+	if err := buf.Flush(); err != nil {
+		return n, err
+	}
+	return n, out.Close()
+}
+
+func importFrom(ctx context.Context, store fingerprint.VectorStore, path string, batch int) (int, error) {
+	in := os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return 0, err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	var r io.Reader = bufio.NewReader(in)
+	if strings.HasSuffix(path, ".gz") {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return 0, err
+		}
+		defer gz.Close()
+		r = gz
+	}
+	return fingerprint.Import(ctx, store, r, batch)
+}
@@ -2,19 +2,124 @@ package config
 
 import (
 	"os"
+	"strconv"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
 	DatabaseURL string
+
+	// WatermarkKey is the server secret that orders the reversible
+	// watermark and is needed again to restore the original pixels.
+	WatermarkKey string
+
+	// AdminAPIKey guards the protected endpoints (sent as X-API-KEY).
+	AdminAPIKey string
+
+	// TileCacheMB caps the luminance tiles the watermark engine keeps in
+	// memory per request.
+	TileCacheMB int
+
+	// EngineWorkers is the number of tiles processed in parallel; zero
+	// uses every available core.
+	EngineWorkers int
+
+	// VectorStore picks the fingerprint index: "qdrant" or "memory".
+	VectorStore string
+	QdrantHost  string
+	QdrantPort  int
+
+	// VectorStorePath persists the memory index; empty keeps it in RAM.
+	VectorStorePath string
+
+	// Qdrant collection layout (see fingerprint/collection.go): its name,
+	// "cosine" or "dot" distance, HNSW links per node and candidate list
+	// sizes at build and search time (0 keeps Qdrant's), and "scalar",
+	// "binary" or no quantisation. The server refuses to start when an
+	// existing collection's dimensions or distance don't match.
+	QdrantCollection      string
+	QdrantDistance        string
+	QdrantHNSWM           int
+	QdrantHNSWEfConstruct int
+	QdrantHNSWEf          int
+	QdrantQuantization    string
+
+	// FingerprintVersion selects the fingerprint variant (1-4, see
+	// fingerprint/normalise.go). It defaults to 1, fingerprint.DefaultVersion,
+	// the version of stores written before versions were recorded. On start-up the server
+	// migrates the stored vectors to it before serving (1 -> 2 or 3), and
+	// refuses to start when they can't be derived (v4).
+	FingerprintVersion int
+
+	// ColourFingerprint also stores the colour vector of every embedded
+	// image, so queries can weight colour against structure. Qdrant
+	// collections created before colour vectors existed don't have room
+	// for them.
+	ColourFingerprint bool
+
+	// ReindexIntervalSeconds is how often the background worker looks for
+	// vectors that are unindexed or at another fingerprint version; zero
+	// turns the worker off.
+	ReindexIntervalSeconds int
+
+	// OutboxIntervalSeconds is how often vector writes that failed at
+	// upload time are retried.
+	OutboxIntervalSeconds int
+
+	// Similarity thresholds: the lowest score of an exact copy, a
+	// near-duplicate and a related image (see fingerprint/verdict.go).
+	ExactCopyThreshold     float64
+	NearDuplicateThreshold float64
+	RelatedThreshold       float64
+
+	// FingerprintUnversionedAs is the version assumed for vectors stored
+	// before versions were recorded.
+	FingerprintUnversionedAs int
+
+	// EmbedDuplicateAction is what embedding does with an image that
+	// scores at least EmbedDuplicateThreshold against a catalogued one:
+	// "off", "reject", "warn" or "link". A zero threshold means the
+	// near-duplicate threshold.
+	EmbedDuplicateAction    string
+	EmbedDuplicateThreshold float64
 }
 
 func LoadConfig() *Config {
 	godotenv.Load("../../.env")
 
 	return &Config{
-		DatabaseURL: buildDataBaseURL(),
+		DatabaseURL:   buildDataBaseURL(),
+		WatermarkKey:  getEnv("WATERMARK_SECRET_KEY", ""),
+		AdminAPIKey:   getEnv("ADMIN_API_KEY", ""),
+		TileCacheMB:   getEnvInt("ENGINE_TILE_CACHE_MB", 64),
+		EngineWorkers: getEnvInt("ENGINE_WORKERS", 0),
+
+		VectorStore:     getEnv("VECTOR_STORE", "qdrant"),
+		QdrantHost:      getEnv("QDRANT_HOST", "localhost"),
+		QdrantPort:      getEnvInt("QDRANT_PORT", 6334),
+		VectorStorePath: getEnv("VECTOR_STORE_PATH", ""),
+
+		QdrantCollection:      getEnv("QDRANT_COLLECTION", "image_fingerprints"),
+		QdrantDistance:        getEnv("QDRANT_DISTANCE", "cosine"),
+		QdrantHNSWM:           getEnvInt("QDRANT_HNSW_M", 0),
+		QdrantHNSWEfConstruct: getEnvInt("QDRANT_HNSW_EF_CONSTRUCT", 0),
+		QdrantHNSWEf:          getEnvInt("QDRANT_HNSW_EF", 0),
+		QdrantQuantization:    getEnv("QDRANT_QUANTIZATION", ""),
+
+		FingerprintVersion: getEnvInt("FINGERPRINT_VERSION", 1),
+		ColourFingerprint:  getEnvBool("COLOUR_FINGERPRINT", false),
+
+		ReindexIntervalSeconds:   getEnvInt("REINDEX_INTERVAL_SECONDS", 60),
+		OutboxIntervalSeconds:    getEnvInt("OUTBOX_INTERVAL_SECONDS", 5),
+		FingerprintUnversionedAs: getEnvInt("FINGERPRINT_UNVERSIONED_AS", 1),
+
+		ExactCopyThreshold:     getEnvFloat("SIMILARITY_EXACT_COPY", 0.995),
+		NearDuplicateThreshold: getEnvFloat("SIMILARITY_NEAR_DUPLICATE", 0.95),
+		RelatedThreshold:       getEnvFloat("SIMILARITY_RELATED", 0.85),
+
+		EmbedDuplicateAction:    getEnv("EMBED_DUPLICATE_ACTION", "off"),
+		EmbedDuplicateThreshold: getEnvFloat("EMBED_DUPLICATE_THRESHOLD", 0),
 	}
 }
 
@@ -26,6 +131,33 @@ func getEnv(key, defaultValue string) string {
 	return defaultValue
 }
 
+func getEnvInt(key string, defaultValue int) int {
+	if value := os.Getenv(key); value != "" {
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	}
+	return defaultValue
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	if value := os.Getenv(key); value != "" {
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return defaultValue
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	}
+	return defaultValue
+}
+
 func buildDataBaseURL() string {
 	user := getEnv("DB_USER", "")
 	password := getEnv("DB_PASSWORD", "")
@@ -4,6 +4,9 @@ CREATE EXTENSION IF NOT EXISTS pgcrypto;
 CREATE TABLE IF NOT EXISTS image_metadata (
     id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
 
+    -- Compact key embedded in the watermark payload
+    serial_id BIGSERIAL UNIQUE,
+
     title TEXT NULL,
     description TEXT NULL,
 
@@ -22,9 +25,105 @@ CREATE TABLE IF NOT EXISTS image_metadata (
     updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
 );
 
+-- Tables created before serial_id existed
+ALTER TABLE image_metadata ADD COLUMN IF NOT EXISTS serial_id BIGSERIAL UNIQUE;
+
+-- Vector index bookkeeping: the fingerprint version in the vector store,
+-- when it was written, and why the re-index worker gave up on a row.
+ALTER TABLE image_metadata ADD COLUMN IF NOT EXISTS index_version INTEGER NULL;
+ALTER TABLE image_metadata ADD COLUMN IF NOT EXISTS indexed_at TIMESTAMPTZ NULL;
+ALTER TABLE image_metadata ADD COLUMN IF NOT EXISTS index_error TEXT NULL;
+
+-- Attributes that similarity searches can filter on; they are mirrored
+-- into the vector store payload.
+ALTER TABLE image_metadata ADD COLUMN IF NOT EXISTS owner TEXT NULL;
+ALTER TABLE image_metadata ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT '{}';
+
+-- The catalogued image a near-duplicate upload was linked to (see
+-- EMBED_DUPLICATE_ACTION=link).
+ALTER TABLE image_metadata ADD COLUMN IF NOT EXISTS derived_from UUID NULL
+    REFERENCES image_metadata(id) ON DELETE SET NULL;
+
 -- Useful indexes
 CREATE INDEX IF NOT EXISTS idx_image_metadata_created_at
 ON image_metadata(created_at);
 
 CREATE INDEX IF NOT EXISTS idx_image_metadata_is_indexed
-ON image_metadata(is_indexed);
\ No newline at end of file
+ON image_metadata(is_indexed);
+
+CREATE INDEX IF NOT EXISTS idx_image_metadata_owner
+ON image_metadata(owner);
+
+-- Perceptual hashes, one row per image. BIT(n) columns let Postgres
+-- compute Hamming distances as bit_count(a # b).
+CREATE TABLE IF NOT EXISTS image_hashes (
+    image_id UUID PRIMARY KEY REFERENCES image_metadata(id) ON DELETE CASCADE,
+
+    phash    BIT(64)  NOT NULL,
+    phash256 BIT(256) NOT NULL,
+    dhash    BIT(64)  NOT NULL,
+    ahash    BIT(64)  NOT NULL,
+    whash    BIT(64)  NOT NULL,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Local features (keypoints + binary descriptors) for crop lookups, one
+-- encoded blob per image.
+CREATE TABLE IF NOT EXISTS image_features (
+    image_id UUID PRIMARY KEY REFERENCES image_metadata(id) ON DELETE CASCADE,
+
+    keypoints INTEGER NOT NULL,
+    data      BYTEA   NOT NULL,
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- LSH buckets of every image's descriptors. A crop query counts shared
+-- buckets per image to pick the candidates worth matching in full.
+CREATE TABLE IF NOT EXISTS image_feature_buckets (
+    bucket_key BIGINT NOT NULL,
+    image_id   UUID   NOT NULL REFERENCES image_metadata(id) ON DELETE CASCADE,
+
+    PRIMARY KEY (bucket_key, image_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_image_feature_buckets_image_id
+ON image_feature_buckets(image_id);
+
+-- Transactional outbox: vector-store writes recorded in the same
+-- transaction as their image_metadata row. The dispatcher deletes an
+-- entry once the vectors are stored and marks the row indexed.
+CREATE TABLE IF NOT EXISTS vector_outbox (
+    id       BIGSERIAL PRIMARY KEY,
+    image_id UUID NOT NULL REFERENCES image_metadata(id) ON DELETE CASCADE,
+
+    fingerprint_version INTEGER NOT NULL,
+    structure           BYTEA   NOT NULL,  -- little-endian float32s
+    colour              BYTEA   NULL,
+
+    attempts        INTEGER     NOT NULL DEFAULT 0,
+    last_error      TEXT        NULL,
+    next_attempt_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_vector_outbox_next_attempt_at
+ON vector_outbox(next_attempt_at);
+
+CREATE INDEX IF NOT EXISTS idx_vector_outbox_image_id
+ON vector_outbox(image_id);
+
+-- Groups of near-identical images from the last clustering run
+-- (cmd/cluster-images). Cluster IDs are reassigned, largest first, on
+-- every run.
+CREATE TABLE IF NOT EXISTS image_clusters (
+    image_id   UUID    PRIMARY KEY REFERENCES image_metadata(id) ON DELETE CASCADE,
+    cluster_id INTEGER NOT NULL,
+
+    clustered_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_image_clusters_cluster_id
+ON image_clusters(cluster_id);
@@ -0,0 +1,82 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/services"
+)
+
+func TestClusterCatalogueGroupsNearDuplicates(t *testing.T) {
+	env := newTestApp(t, testConfig())
+	app, svc := env.App, env.Service
+
+	// Two copies of one scene, two more of another, and a loner.
+	watermark(t, app, syntheticPNG(t, 256, 256, 0), `{"title":"a1"}`)
+	watermark(t, app, texturedPNG(t, 256, 256, 9), `{"title":"b1"}`)
+	watermark(t, app, syntheticPNG(t, 256, 256, 0.01), `{"title":"a2"}`)
+	watermark(t, app, syntheticPNG(t, 256, 256, 0.02), `{"title":"a3"}`)
+	watermark(t, app, texturedPNG(t, 256, 256, 9.01), `{"title":"b2"}`)
+	watermark(t, app, blocksPNG(t, 256, 256, 4), `{"title":"alone"}`)
+
+	report, err := svc.ClusterCatalogue(context.Background(), services.ClusterRequest{Threshold: 0.99})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Images != 6 || report.Clusters != 2 || report.Clustered != 5 {
+		t.Fatalf("report = %+v", report)
+	}
+
+	getClusters := func(query, apiKey string) (int, services.ClusterPage) {
+		t.Helper()
+		req := httptest.NewRequest("GET", "/api/v1/admin/clusters"+query, nil)
+		if apiKey != "" {
+			req.Header.Set("X-API-KEY", apiKey)
+		}
+		resp, err := app.Test(req, -1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var page services.ClusterPage
+		if resp.StatusCode == fiber.StatusOK {
+			if err := json.Unmarshal(readBody(t, resp), &page); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return resp.StatusCode, page
+	}
+
+	titles := func(c services.ClusterEntry) []string {
+		var got []string
+		for _, m := range c.Members {
+			got = append(got, *m.Title)
+		}
+		return got
+	}
+
+	status, page := getClusters("", testAdminKey)
+	if status != fiber.StatusOK || page.Total != 2 || len(page.Clusters) != 2 {
+		t.Fatalf("status %d, page %+v", status, page)
+	}
+	// Largest first, oldest record first within a cluster.
+	if got := titles(page.Clusters[0]); len(got) != 3 || got[0] != "a1" || got[1] != "a2" || got[2] != "a3" || page.Clusters[0].Size != 3 {
+		t.Fatalf("first cluster = %v", got)
+	}
+	if got := titles(page.Clusters[1]); len(got) != 2 || got[0] != "b1" || got[1] != "b2" {
+		t.Fatalf("second cluster = %v", got)
+	}
+
+	if status, page = getClusters("?limit=1&offset=1", testAdminKey); status != fiber.StatusOK || len(page.Clusters) != 1 || page.Clusters[0].ID != 2 {
+		t.Fatalf("second page: status %d, %+v", status, page)
+	}
+	if status, _ = getClusters("?limit=0", testAdminKey); status != fiber.StatusBadRequest {
+		t.Fatalf("limit=0: status %d, want 400", status)
+	}
+	if status, _ = getClusters("", ""); status != fiber.StatusUnauthorized {
+		t.Fatalf("without API key: status %d, want 401", status)
+	}
+}
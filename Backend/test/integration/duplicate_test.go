@@ -0,0 +1,104 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image/png"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/services"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
+)
+
+func TestEmbedDetectsNearDuplicates(t *testing.T) {
+	original := syntheticPNG(t, 256, 256, 0)
+
+	// authenticate returns the record a marked image names.
+	authenticate := func(t *testing.T, app *fiber.App, marked []byte) services.AuthResult {
+		t.Helper()
+		var result services.AuthResult
+		if err := json.Unmarshal(expectStatus(t, postImage(t, app, "/api/v1/authenticate", marked, nil), fiber.StatusOK), &result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	for _, action := range []services.DuplicateAction{services.DuplicateOff, services.DuplicateReject, services.DuplicateWarn, services.DuplicateLink} {
+		t.Run(string(action), func(t *testing.T) {
+			cfg := testConfig()
+			cfg.DuplicateAction = action
+			app := newTestApp(t, cfg).App
+			first := authenticate(t, app, watermark(t, app, original, `{"title":"first"}`)).Layers[0].Metadata.ID
+
+			// An unrelated image is never flagged.
+			resp := postImage(t, app, "/api/v1/watermark", texturedPNG(t, 256, 256, 9), map[string]string{"metadata": `{}`})
+			expectStatus(t, resp, fiber.StatusOK)
+			if got := resp.Header.Get("X-Duplicate-Of"); got != "" {
+				t.Fatalf("unrelated image flagged as duplicate of %s", got)
+			}
+
+			// The unmarked original again, as a second user would upload it.
+			resp = postImage(t, app, "/api/v1/watermark", original, map[string]string{"metadata": `{"title":"second"}`})
+			want := fiber.StatusOK
+			if action == services.DuplicateReject {
+				want = fiber.StatusConflict
+			}
+			body := expectStatus(t, resp, want)
+
+			if action == services.DuplicateOff {
+				if got := resp.Header.Get("X-Duplicate-Of"); got != "" {
+					t.Fatalf("check is off but got X-Duplicate-Of %s", got)
+				}
+				return
+			}
+			if resp.Header.Get("X-Duplicate-Of") != first.String() || resp.Header.Get("X-Duplicate-Action") != string(action) {
+				t.Fatalf("X-Duplicate-Of %q, X-Duplicate-Action %q; want %s, %s",
+					resp.Header.Get("X-Duplicate-Of"), resp.Header.Get("X-Duplicate-Action"), first, action)
+			}
+			if resp.Header.Get("X-Duplicate-Score") == "" {
+				t.Fatal("X-Duplicate-Score missing")
+			}
+			if action == services.DuplicateReject {
+				return
+			}
+
+			second := authenticate(t, app, body).Layers[0].Metadata
+			linked := second.DerivedFrom != nil && *second.DerivedFrom == first
+			if linked != (action == services.DuplicateLink) {
+				t.Fatalf("DerivedFrom = %v with action %s", second.DerivedFrom, action)
+			}
+		})
+	}
+}
+
+// A vector whose row is gone must not become derived_from: the row's
+// foreign key would reject it and the upload would fail.
+func TestEmbedIgnoresDuplicatesWithoutARow(t *testing.T) {
+	cfg := testConfig()
+	cfg.DuplicateAction = services.DuplicateLink
+	env := newTestApp(t, cfg)
+	app, vectors := env.App, env.Vectors
+
+	original := syntheticPNG(t, 256, 256, 0)
+	img, err := png.Decode(bytes.NewReader(original))
+	if err != nil {
+		t.Fatal(err)
+	}
+	vec, err := fingerprint.CreateFingerprintVersion(img, fingerprint.DefaultVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vectors.Store(context.Background(), uuid.New(), vec, fingerprint.DefaultVersion); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := postImage(t, app, "/api/v1/watermark", original, map[string]string{"metadata": `{}`})
+	expectStatus(t, resp, fiber.StatusOK)
+	if got := resp.Header.Get("X-Duplicate-Of"); got != "" {
+		t.Fatalf("upload linked to the orphan vector %s", got)
+	}
+}
@@ -0,0 +1,512 @@
+// Package integration drives the HTTP API end to end: the real Fiber app,
+// handlers, service and watermark engine, with the in-memory metadata and
+// vector stores standing in for Postgres and Qdrant.
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"math"
+	"math/rand"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/api/handlers"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/api/routes"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/repository"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/services"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
+)
+
+const testAdminKey = "integration-admin-key"
+
+// testEnv is the API on fresh in-process stores, with the parts tests
+// reach into directly.
+type testEnv struct {
+	App     *fiber.App
+	Service *services.ImageService
+	Repo    *repository.MemoryDB
+	Vectors fingerprint.VectorStore // as the service sees it
+}
+
+// testConfig is the configuration most tests run with. The fingerprint
+// version is left at the default, as in production.
+func testConfig() services.ImageServiceConfig {
+	return services.ImageServiceConfig{
+		WatermarkKey:      []byte("integration-test-key"),
+		ColourFingerprint: true,
+	}
+}
+
+// newVectorStore returns an empty, unpersisted vector store.
+func newVectorStore(t *testing.T) *fingerprint.MemoryStore {
+	t.Helper()
+
+	vectors, err := fingerprint.NewMemoryStore("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vectors
+}
+
+// newTestApp boots the API with fresh in-process stores.
+func newTestApp(t *testing.T, cfg services.ImageServiceConfig) *testEnv {
+	t.Helper()
+	return newTestAppOn(t, cfg, newVectorStore(t))
+}
+
+// newTestAppOn is newTestApp on a given vector store, for tests that put a
+// double in front of it.
+func newTestAppOn(t *testing.T, cfg services.ImageServiceConfig, vectors fingerprint.VectorStore) *testEnv {
+	t.Helper()
+
+	repo := repository.NewMemoryDB()
+	svc := services.NewImageService(repo, vectors, cfg)
+	return &testEnv{
+		App:     routes.NewApp(handlers.NewImageHandler(svc), testAdminKey),
+		Service: svc,
+		Repo:    repo,
+		Vectors: vectors,
+	}
+}
+
+// syntheticPNG renders a smooth, low-saturation picture. Strongly
+// saturated colours clip when the luminance is modified, so real-photo
+// tones keep the test about the API rather than gamut limits.
+func syntheticPNG(t *testing.T, w, h int, phase float64) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			v := 128 + 45*math.Sin(float64(x)/19+phase) + 30*math.Cos(float64(y)/27-phase)
+			img.SetRGBA(x, y, color.RGBA{uint8(v), uint8(v*0.95 + 6), uint8(v*0.85 + 16), 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// texturedPNG has structure in both directions at once. The separable
+// pattern of syntheticPNG concentrates its energy in a few DCT
+// coefficients, which leaves most pHash bits to noise.
+func texturedPNG(t *testing.T, w, h int, phase float64) []byte {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			fx, fy := float64(x)/float64(w), float64(y)/float64(h)
+			v := 128 + 50*math.Sin(7*fx+phase) + 35*math.Cos(5*fy-2*phase) + 25*math.Sin(13*fx*fy+phase)
+			img.SetRGBA(x, y, color.RGBA{uint8(v), uint8(v*0.95 + 6), uint8(v*0.85 + 16), 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// blocksPNG scatters shaded rectangles in mid tones. Smooth patterns have
+// hardly any corners, which local-feature lookups depend on.
+func blocksPNG(t *testing.T, w, h int, seed int64) []byte {
+	t.Helper()
+
+	rng := rand.New(rand.NewSource(seed))
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = 128
+	}
+	for i := 0; i < 250; i++ {
+		x, y := rng.Intn(w), rng.Intn(h)
+		size := 8 + rng.Intn(40)
+		base := 60 + rng.Float64()*130
+		shade := rng.Float64()*2 - 1
+		for dy := 0; dy < size && y+dy < h; dy++ {
+			for dx := 0; dx < size*2/3 && x+dx < w; dx++ {
+				v := base + shade*float64(dx+dy)
+				img.SetRGBA(x+dx, y+dy, color.RGBA{uint8(v), uint8(v*0.95 + 6), uint8(v*0.85 + 16), 255})
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// postImage sends a multipart form with the image and extra fields.
+func postImage(t *testing.T, app *fiber.App, path string, img []byte, fields map[string]string) *http.Response {
+	t.Helper()
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+
+	part, err := w.CreateFormFile("image", "upload.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(img)
+	for k, v := range fields {
+		w.WriteField(k, v)
+	}
+	w.Close()
+
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", w.FormDataContentType())
+
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func readBody(t *testing.T, resp *http.Response) []byte {
+	t.Helper()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func expectStatus(t *testing.T, resp *http.Response, want int) []byte {
+	t.Helper()
+	body := readBody(t, resp)
+	if resp.StatusCode != want {
+		t.Fatalf("status %d, want %d; body: %s", resp.StatusCode, want, body)
+	}
+	return body
+}
+
+// watermark embeds metadata into img and returns the marked PNG.
+func watermark(t *testing.T, app *fiber.App, img []byte, metadata string) []byte {
+	t.Helper()
+
+	resp := postImage(t, app, "/api/v1/watermark", img, map[string]string{"metadata": metadata})
+	marked := expectStatus(t, resp, fiber.StatusOK)
+
+	if ct := resp.Header.Get(fiber.HeaderContentType); ct != "image/png" {
+		t.Fatalf("Content-Type = %q, want image/png", ct)
+	}
+
+	fp, err := fingerprint.ParseVector(resp.Header.Get("X-Fingerprint"))
+	if err != nil || len(fp) != fingerprint.Dimension {
+		t.Fatalf("X-Fingerprint: %d values, err %v", len(fp), err)
+	}
+	return marked
+}
+
+func TestWatermarkThenAuthenticate(t *testing.T) {
+	app := newTestApp(t, testConfig()).App
+
+	marked := watermark(t, app, syntheticPNG(t, 512, 512, 0),
+		`{"title":"Harbour at dawn","description":"synthetic","is_ai_generated":true,"captured_at":"2024-01-15T10:30:00Z"}`)
+
+	resp := postImage(t, app, "/api/v1/authenticate", marked, map[string]string{"k": "3"})
+	body := expectStatus(t, resp, fiber.StatusOK)
+
+	var result services.AuthResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatalf("decode auth result: %v\n%s", err, body)
+	}
+
+	if !result.WatermarkValid {
+		t.Fatal("WatermarkValid = false")
+	}
+	if len(result.Layers) != 1 || result.Layers[0].Metadata == nil {
+		t.Fatalf("Layers = %+v, want exactly the base layer", result.Layers)
+	}
+
+	meta := result.Layers[0].Metadata
+	if meta.Title == nil || *meta.Title != "Harbour at dawn" {
+		t.Errorf("Title = %v", meta.Title)
+	}
+	if meta.Description == nil || *meta.Description != "synthetic" {
+		t.Errorf("Description = %v", meta.Description)
+	}
+	if !meta.IsAIGenerated {
+		t.Error("IsAIGenerated = false")
+	}
+	if meta.MimeType == nil || *meta.MimeType != "image/png" {
+		t.Errorf("MimeType = %v", meta.MimeType)
+	}
+	if meta.CapturedAt == nil || meta.CapturedAt.UTC().Format("2006-01-02T15:04:05Z") != "2024-01-15T10:30:00Z" {
+		t.Errorf("CapturedAt = %v", meta.CapturedAt)
+	}
+
+	// The marked image itself is the closest fingerprint in the index.
+	if len(result.SimilarImages) == 0 || result.SimilarImages[0].ID != meta.ID {
+		t.Fatalf("SimilarImages does not start with the watermarked record %s", meta.ID)
+	}
+	if result.SimilarityScores[0] < 0.99 {
+		t.Errorf("self similarity %.4f, want ~1", result.SimilarityScores[0])
+	}
+}
+
+func TestAuthenticateWeightsColour(t *testing.T) {
+	app := newTestApp(t, testConfig()).App
+
+	marked := watermark(t, app, syntheticPNG(t, 512, 512, 0), `{"title":"colour"}`)
+	watermark(t, app, texturedPNG(t, 512, 512, 2), `{"title":"other"}`)
+
+	resp := postImage(t, app, "/api/v1/authenticate", marked, map[string]string{"k": "2", "colour_weight": "0.5"})
+	var result services.AuthResult
+	if err := json.Unmarshal(expectStatus(t, resp, fiber.StatusOK), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.SimilarImages) != 2 || len(result.StructureScores) != 2 || len(result.ColourScores) != 2 {
+		t.Fatalf("got %d images, %d structure and %d colour scores; want 2 each",
+			len(result.SimilarImages), len(result.StructureScores), len(result.ColourScores))
+	}
+	if result.SimilarImages[0].Title == nil || *result.SimilarImages[0].Title != "colour" {
+		t.Fatalf("blended ranking does not start with the marked image: %+v", result.SimilarImages[0])
+	}
+	if result.ColourScores[0] < 0.99 {
+		t.Errorf("colour self-similarity %.4f, want ~1", result.ColourScores[0])
+	}
+	want := (result.StructureScores[0] + result.ColourScores[0]) / 2
+	if d := result.SimilarityScores[0] - want; d > 1e-5 || d < -1e-5 {
+		t.Errorf("blended score %.5f, want %.5f", result.SimilarityScores[0], want)
+	}
+
+	expectStatus(t, postImage(t, app, "/api/v1/authenticate", marked, map[string]string{"colour_weight": "1.5"}), fiber.StatusBadRequest)
+}
+
+func TestAuthenticateClassifiesSimilarImages(t *testing.T) {
+	app := newTestApp(t, testConfig()).App
+
+	marked := watermark(t, app, syntheticPNG(t, 256, 256, 0), `{"title":"copy"}`)
+	watermark(t, app, texturedPNG(t, 256, 256, 9), `{"title":"unrelated"}`)
+
+	authenticate := func(fields map[string]string) services.AuthResult {
+		t.Helper()
+		resp := postImage(t, app, "/api/v1/authenticate", marked, fields)
+		var result services.AuthResult
+		if err := json.Unmarshal(expectStatus(t, resp, fiber.StatusOK), &result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	result := authenticate(map[string]string{"k": "2"})
+	if result.Verdict != fingerprint.VerdictExactCopy {
+		t.Fatalf("Verdict = %s, want exact-copy (scores %v)", result.Verdict, result.SimilarityScores)
+	}
+	if len(result.SimilarVerdicts) != 2 || result.SimilarVerdicts[1] != fingerprint.VerdictUnrelated {
+		t.Fatalf("SimilarVerdicts = %v (scores %v)", result.SimilarVerdicts, result.SimilarityScores)
+	}
+
+	// min_score leaves the unrelated image out.
+	result = authenticate(map[string]string{"k": "2", "min_score": "0.85"})
+	if len(result.SimilarImages) != 1 || *result.SimilarImages[0].Title != "copy" {
+		t.Fatalf("min_score 0.85 returned %d images (scores %v)", len(result.SimilarImages), result.SimilarityScores)
+	}
+}
+
+func TestWatermarkTwiceConflicts(t *testing.T) {
+	app := newTestApp(t, testConfig()).App
+
+	marked := watermark(t, app, syntheticPNG(t, 512, 512, 1), `{"title":"first owner"}`)
+
+	resp := postImage(t, app, "/api/v1/watermark", marked, map[string]string{"metadata": `{"title":"second owner"}`})
+	body := expectStatus(t, resp, fiber.StatusConflict)
+
+	if !bytes.Contains(body, []byte("image is already watermarked")) {
+		t.Fatalf("unexpected error body: %s", body)
+	}
+}
+
+func TestAddLayerKeepsChainInOrder(t *testing.T) {
+	app := newTestApp(t, testConfig()).App
+
+	marked := watermark(t, app, syntheticPNG(t, 512, 512, 1), `{"title":"first owner"}`)
+
+	resp := postImage(t, app, "/api/v1/watermark", marked, map[string]string{"metadata": `{"title":"second owner"}`, "add_layer": "true"})
+	layered := expectStatus(t, resp, fiber.StatusOK)
+
+	resp = postImage(t, app, "/api/v1/authenticate", layered, nil)
+	var result services.AuthResult
+	if err := json.Unmarshal(expectStatus(t, resp, fiber.StatusOK), &result); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(result.Layers) != 2 {
+		t.Fatalf("got %d layers, want 2: %+v", len(result.Layers), result.Layers)
+	}
+	for i, want := range []string{"first owner", "second owner"} {
+		l := result.Layers[i]
+		if l.Layer != i || l.Metadata == nil || l.Metadata.Title == nil || *l.Metadata.Title != want {
+			t.Fatalf("layer %d = %+v, want layer %d titled %q", i, l, i, want)
+		}
+	}
+}
+
+func TestReversibleMasterIsRecognised(t *testing.T) {
+	app := newTestApp(t, testConfig()).App
+
+	resp := postImage(t, app, "/api/v1/watermark", blocksPNG(t, 512, 512, 4), map[string]string{"metadata": `{"title":"archive master"}`, "mode": "reversible"})
+	master := expectStatus(t, resp, fiber.StatusOK)
+
+	var result services.AuthResult
+	if err := json.Unmarshal(expectStatus(t, postImage(t, app, "/api/v1/authenticate", master, nil), fiber.StatusOK), &result); err != nil {
+		t.Fatal(err)
+	}
+	if !result.WatermarkValid || len(result.Layers) != 1 || *result.Layers[0].Metadata.Title != "archive master" {
+		t.Fatalf("authenticate on a reversible master = %+v", result)
+	}
+
+	// Uploading the master again in either mode doesn't create a second record.
+	for _, mode := range []string{"robust", "reversible"} {
+		resp := postImage(t, app, "/api/v1/watermark", master, map[string]string{"metadata": `{"title":"copy"}`, "mode": mode})
+		expectStatus(t, resp, fiber.StatusConflict)
+	}
+}
+
+func TestAuthenticateUnwatermarkedNotFound(t *testing.T) {
+	app := newTestApp(t, testConfig()).App
+
+	resp := postImage(t, app, "/api/v1/authenticate", syntheticPNG(t, 512, 512, 2), nil)
+	body := expectStatus(t, resp, fiber.StatusNotFound)
+
+	if !bytes.Contains(body, []byte("no watermark detected in image")) {
+		t.Fatalf("unexpected error body: %s", body)
+	}
+}
+
+func TestInvalidRequests(t *testing.T) {
+	app := newTestApp(t, testConfig()).App
+	img := syntheticPNG(t, 256, 256, 3)
+
+	tests := []struct {
+		name   string
+		path   string
+		fields map[string]string
+		want   int
+	}{
+		{"missing metadata", "/api/v1/watermark", nil, fiber.StatusBadRequest},
+		{"bad metadata JSON", "/api/v1/watermark", map[string]string{"metadata": "{"}, fiber.StatusBadRequest},
+		{"bad mode", "/api/v1/watermark", map[string]string{"metadata": "{}", "mode": "fragile"}, fiber.StatusBadRequest},
+		{"bad k", "/api/v1/authenticate", map[string]string{"k": "0"}, fiber.StatusBadRequest},
+		{"bad min_score", "/api/v1/authenticate", map[string]string{"min_score": "1.5"}, fiber.StatusBadRequest},
+		{"unwatermark without API key", "/api/v1/unwatermark", nil, fiber.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expectStatus(t, postImage(t, app, tt.path, img, tt.fields), tt.want)
+		})
+	}
+}
+
+type hashLookupResult struct {
+	Kind    string
+	Query   string
+	Matches []struct {
+		Distance int
+		Metadata struct{ Title *string }
+	}
+}
+
+func TestHashLookupFindsWatermarkedImage(t *testing.T) {
+	app := newTestApp(t, testConfig()).App
+
+	original := texturedPNG(t, 512, 512, 0)
+	watermark(t, app, original, `{"title":"hashed"}`)
+	watermark(t, app, texturedPNG(t, 512, 512, 3), `{"title":"other"}`)
+
+	// The unmarked original is a near-duplicate of the stored marked copy.
+	resp := postImage(t, app, "/api/v1/lookup/hash", original, map[string]string{"kind": "phash", "max_distance": "4"})
+	body := expectStatus(t, resp, fiber.StatusOK)
+
+	var result hashLookupResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Matches) == 0 || result.Matches[0].Metadata.Title == nil || *result.Matches[0].Metadata.Title != "hashed" {
+		t.Fatalf("lookup did not find the marked copy first: %s", body)
+	}
+
+	// Looking up by the returned hex hash gives the same answer.
+	resp = postImage(t, app, "/api/v1/lookup/hash", nil, map[string]string{"hash": result.Query, "max_distance": "4"})
+	var byHash hashLookupResult
+	if err := json.Unmarshal(expectStatus(t, resp, fiber.StatusOK), &byHash); err != nil {
+		t.Fatal(err)
+	}
+	if len(byHash.Matches) != len(result.Matches) {
+		t.Fatalf("lookup by hash found %d matches, by image %d", len(byHash.Matches), len(result.Matches))
+	}
+
+	expectStatus(t, postImage(t, app, "/api/v1/lookup/hash", nil, map[string]string{"hash": "abc"}), fiber.StatusBadRequest)
+	expectStatus(t, postImage(t, app, "/api/v1/lookup/hash", original, map[string]string{"kind": "md5"}), fiber.StatusBadRequest)
+}
+
+type cropLookupResult struct {
+	Keypoints int
+	Matches   []struct {
+		Rect     services.CropRect
+		Scale    float64
+		Inliers  int
+		Metadata struct{ Title *string }
+	}
+}
+
+func TestCropLookupLocatesCrop(t *testing.T) {
+	app := newTestApp(t, testConfig()).App
+
+	marked := watermark(t, app, blocksPNG(t, 640, 480, 1), `{"title":"cropped"}`)
+	watermark(t, app, blocksPNG(t, 640, 480, 2), `{"title":"other"}`)
+
+	src, err := png.Decode(bytes.NewReader(marked))
+	if err != nil {
+		t.Fatal(err)
+	}
+	area := image.Rect(200, 120, 520, 360)
+	crop := image.NewRGBA(image.Rect(0, 0, area.Dx(), area.Dy()))
+	for y := 0; y < area.Dy(); y++ {
+		for x := 0; x < area.Dx(); x++ {
+			crop.Set(x, y, src.At(area.Min.X+x, area.Min.Y+y))
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, crop); err != nil {
+		t.Fatal(err)
+	}
+
+	resp := postImage(t, app, "/api/v1/lookup/crop", buf.Bytes(), nil)
+	body := expectStatus(t, resp, fiber.StatusOK)
+
+	var result cropLookupResult
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Matches) == 0 || result.Matches[0].Metadata.Title == nil || *result.Matches[0].Metadata.Title != "cropped" {
+		t.Fatalf("crop lookup did not find the original first: %s", body)
+	}
+	r := result.Matches[0].Rect
+	if math.Abs(float64(r.X-area.Min.X)) > 6 || math.Abs(float64(r.Y-area.Min.Y)) > 6 ||
+		math.Abs(float64(r.Width-area.Dx())) > 8 || math.Abs(float64(r.Height-area.Dy())) > 8 {
+		t.Fatalf("crop located at %+v, want about %v", r, area)
+	}
+
+	expectStatus(t, postImage(t, app, "/api/v1/lookup/crop", buf.Bytes(), map[string]string{"limit": "0"}), fiber.StatusBadRequest)
+}
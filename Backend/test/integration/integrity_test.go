@@ -0,0 +1,67 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image/png"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/services"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
+)
+
+func TestAuthenticateChecksContentAgainstRecord(t *testing.T) {
+	env := newTestApp(t, testConfig())
+	app, vectors := env.App, env.Vectors
+	ctx := context.Background()
+
+	marked := watermark(t, app, syntheticPNG(t, 256, 256, 0), `{"title":"original"}`)
+
+	integrity := func() *services.IntegrityCheck {
+		t.Helper()
+		resp := postImage(t, app, "/api/v1/authenticate", marked, nil)
+		var result services.AuthResult
+		if err := json.Unmarshal(expectStatus(t, resp, fiber.StatusOK), &result); err != nil {
+			t.Fatal(err)
+		}
+		if result.Integrity == nil || result.Integrity.ImageID != result.Layers[0].Metadata.ID {
+			t.Fatalf("Integrity = %+v", result.Integrity)
+		}
+		return result.Integrity
+	}
+
+	check := integrity()
+	if check.Verdict != services.IntegrityMatch || check.Score < 0.99 {
+		t.Fatalf("untouched copy: %+v", check)
+	}
+
+	// The record now describes other content, as if the mark had been
+	// lifted onto this image from somewhere else.
+	other, err := png.Decode(bytes.NewReader(texturedPNG(t, 256, 256, 7)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherVec, err := fingerprint.CreateFingerprintVersion(other, fingerprint.DefaultVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := vectors.Store(ctx, check.ImageID, otherVec, fingerprint.DefaultVersion); err != nil {
+		t.Fatal(err)
+	}
+	check = integrity()
+	if check.Verdict != services.IntegrityMismatch {
+		t.Fatalf("transplanted mark: %+v", check)
+	}
+
+	// Without a fingerprint on record there is nothing to compare.
+	if err := vectors.Delete(ctx, check.ImageID); err != nil {
+		t.Fatal(err)
+	}
+	check = integrity()
+	if check.Verdict != services.IntegrityUnknown || check.Score != 0 {
+		t.Fatalf("no fingerprint on record: %+v", check)
+	}
+}
@@ -0,0 +1,190 @@
+package integration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/models"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/services"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
+)
+
+// flakyStore is a vector store whose writes fail while down is set.
+type flakyStore struct {
+	fingerprint.VectorStore
+	down atomic.Bool
+}
+
+var errStoreDown = errors.New("vector store unavailable")
+
+func (f *flakyStore) Store(ctx context.Context, id uuid.UUID, vec fingerprint.Vector, v fingerprint.Version) error {
+	if f.down.Load() {
+		return errStoreDown
+	}
+	return f.VectorStore.Store(ctx, id, vec, v)
+}
+
+func (f *flakyStore) StoreNamed(ctx context.Context, id uuid.UUID, name string, vec fingerprint.Vector) error {
+	if f.down.Load() {
+		return errStoreDown
+	}
+	return f.VectorStore.StoreNamed(ctx, id, name, vec)
+}
+
+func TestOutboxRetriesFailedVectorWrites(t *testing.T) {
+	vectors := &flakyStore{VectorStore: newVectorStore(t)}
+	cfg := testConfig()
+	cfg.OutboxRetryDelay = time.Millisecond
+	env := newTestAppOn(t, cfg, vectors)
+	app, svc := env.App, env.Service
+	ctx := context.Background()
+
+	// The upload succeeds while the vector store is down; the write waits
+	// in the outbox.
+	vectors.down.Store(true)
+	marked := watermark(t, app, syntheticPNG(t, 256, 256, 4), `{"title":"queued"}`)
+
+	_, p := getIndexProgress(t, app, testAdminKey)
+	if p.Total != 1 || p.Pending != 1 || p.Current != 0 {
+		t.Fatalf("progress with the store down = %+v", p)
+	}
+	if n, _ := vectors.Count(ctx); n != 0 {
+		t.Fatalf("%d vectors stored while the store was down", n)
+	}
+
+	// The re-index worker must not give up on a row the outbox will write.
+	if report, err := svc.ReindexBatch(ctx, 100); err != nil || report.Processed != 0 {
+		t.Fatalf("ReindexBatch took a pending row: %+v, %v", report, err)
+	}
+
+	time.Sleep(5 * time.Millisecond)
+	report, err := svc.DispatchOutbox(ctx, 100)
+	if err != nil || report.Failed != 1 {
+		t.Fatalf("dispatch with the store still down = %+v, %v", report, err)
+	}
+
+	vectors.down.Store(false)
+	time.Sleep(10 * time.Millisecond)
+	report, err = svc.DispatchOutbox(ctx, 100)
+	if err != nil || report.Delivered != 1 {
+		t.Fatalf("dispatch after recovery = %+v, %v", report, err)
+	}
+
+	_, p = getIndexProgress(t, app, testAdminKey)
+	if p.Current != 1 || p.Pending != 0 {
+		t.Fatalf("progress after delivery = %+v", p)
+	}
+
+	// The delivered fingerprint is searchable.
+	resp := postImage(t, app, "/api/v1/authenticate", marked, map[string]string{"k": "1"})
+	var result services.AuthResult
+	if err := json.Unmarshal(expectStatus(t, resp, fiber.StatusOK), &result); err != nil {
+		t.Fatal(err)
+	}
+	if len(result.SimilarImages) != 1 || result.SimilarImages[0].ID != result.Layers[0].Metadata.ID {
+		t.Fatalf("SimilarImages = %+v", result.SimilarImages)
+	}
+}
+
+func TestReconcileFindsOrphans(t *testing.T) {
+	env := newTestApp(t, testConfig())
+	app, svc, repo, vectors := env.App, env.Service, env.Repo, env.Vectors
+	ctx := context.Background()
+
+	watermark(t, app, syntheticPNG(t, 256, 256, 5), `{"title":"consistent"}`)
+
+	// A row whose vector write was lost, and a vector whose row is gone.
+	lostRow, _, err := repo.InsertImageMetadata(ctx, models.ImageMetadata{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	strayVector := uuid.New()
+	vec := make(fingerprint.Vector, fingerprint.Dimension)
+	vec[0] = 1
+	if err := vectors.Store(ctx, strayVector, vec, fingerprint.V3); err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := svc.Reconcile(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Rows != 2 || report.Vectors != 2 ||
+		len(report.RowsWithoutVector) != 1 || report.RowsWithoutVector[0] != lostRow ||
+		len(report.VectorsWithoutRow) != 1 || report.VectorsWithoutRow[0] != strayVector {
+		t.Fatalf("Reconcile = %+v", report)
+	}
+
+	if _, err := svc.Reconcile(ctx, true); err != nil {
+		t.Fatal(err)
+	}
+	report, err = svc.Reconcile(ctx, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.RowsWithoutVector) != 0 || len(report.VectorsWithoutRow) != 0 || report.Vectors != 1 {
+		t.Fatalf("Reconcile after fixing = %+v", report)
+	}
+	_, p := getIndexProgress(t, app, testAdminKey)
+	if p.Failed != 1 || p.Current != 1 {
+		t.Fatalf("progress after fixing = %+v", p)
+	}
+}
+
+// scrollHookStore runs afterScroll once a Scroll has finished.
+type scrollHookStore struct {
+	fingerprint.VectorStore
+	afterScroll func()
+}
+
+func (h *scrollHookStore) Scroll(ctx context.Context, fn func(uuid.UUID, fingerprint.Vector, fingerprint.Version) error) error {
+	err := h.VectorStore.Scroll(ctx, fn)
+	if h.afterScroll != nil {
+		h.afterScroll()
+	}
+	return err
+}
+
+func TestReconcileKeepsRowsIndexedDuringTheScroll(t *testing.T) {
+	vectors := &scrollHookStore{VectorStore: newVectorStore(t)}
+	env := newTestAppOn(t, testConfig(), vectors)
+	svc, repo := env.Service, env.Repo
+	ctx := context.Background()
+
+	id, _, err := repo.InsertImageMetadata(ctx, models.ImageMetadata{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The dispatcher writes the vector after the scroll but before the
+	// rows are read.
+	vectors.afterScroll = func() {
+		vec := make(fingerprint.Vector, fingerprint.Dimension)
+		vec[0] = 1
+		if err := vectors.Store(ctx, id, vec, fingerprint.V1); err != nil {
+			t.Fatal(err)
+		}
+		if err := repo.MarkIndexed(ctx, id, fingerprint.V1); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := svc.Reconcile(ctx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.RowsWithoutVector) != 0 {
+		t.Fatalf("row indexed during the scroll reported without a vector: %+v", report)
+	}
+	p, err := svc.IndexProgress(ctx)
+	if err != nil || p.Failed != 0 || p.Current != 1 {
+		t.Fatalf("progress after fixing = %+v, %v", p, err)
+	}
+}
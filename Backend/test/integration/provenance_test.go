@@ -0,0 +1,55 @@
+package integration
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/services"
+)
+
+func TestAuthenticateFallbackTracesProvenance(t *testing.T) {
+	app := newTestApp(t, testConfig()).App
+
+	marked := watermark(t, app, syntheticPNG(t, 256, 256, 0), `{"title":"original"}`)
+	watermark(t, app, texturedPNG(t, 256, 256, 9), `{"title":"other"}`)
+
+	authenticate := func(img []byte) services.AuthResult {
+		t.Helper()
+		resp := postImage(t, app, "/api/v1/authenticate", img, map[string]string{"fallback": "true"})
+		var result services.AuthResult
+		if err := json.Unmarshal(expectStatus(t, resp, fiber.StatusOK), &result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+
+	// The unmarked original stands in for a copy whose mark was stripped.
+	stripped := authenticate(syntheticPNG(t, 256, 256, 0))
+	p := stripped.Provenance
+	if stripped.WatermarkValid || len(stripped.Layers) != 0 || stripped.Integrity != nil {
+		t.Fatalf("watermark fields set without a watermark: %+v", stripped)
+	}
+	if p == nil || p.Original == nil || *p.Original.Title != "original" || p.Score < 0.95 {
+		t.Fatalf("Provenance = %+v", p)
+	}
+	if p.Reason != "no watermark detected in image" ||
+		p.Verdict != "watermark missing, likely derived from "+p.Original.ID.String() {
+		t.Fatalf("Provenance = %+v", p)
+	}
+
+	unknown := authenticate(blocksPNG(t, 256, 256, 4))
+	if p := unknown.Provenance; p == nil || p.Original != nil || p.Verdict != services.ProvenanceNoOriginal {
+		t.Fatalf("unrelated image: Provenance = %+v, similar %v", p, unknown.SimilarityScores)
+	}
+
+	// A readable watermark is answered as usual.
+	if result := authenticate(marked); !result.WatermarkValid || result.Provenance != nil {
+		t.Fatalf("watermarked image: %+v", result)
+	}
+
+	// Without the fallback nothing changes.
+	expectStatus(t, postImage(t, app, "/api/v1/authenticate", syntheticPNG(t, 256, 256, 0), nil), fiber.StatusNotFound)
+	expectStatus(t, postImage(t, app, "/api/v1/authenticate", marked, map[string]string{"fallback": "maybe"}), fiber.StatusBadRequest)
+}
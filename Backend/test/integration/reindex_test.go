@@ -0,0 +1,211 @@
+package integration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image/png"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/models"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/services"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
+)
+
+func getIndexProgress(t *testing.T, app *fiber.App, apiKey string) (int, services.IndexProgress) {
+	t.Helper()
+
+	req := httptest.NewRequest("GET", "/api/v1/admin/index", nil)
+	if apiKey != "" {
+		req.Header.Set("X-API-KEY", apiKey)
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var p services.IndexProgress
+	if resp.StatusCode == fiber.StatusOK {
+		if err := json.Unmarshal(readBody(t, resp), &p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode, p
+}
+
+func TestReindexMigratesStaleVectors(t *testing.T) {
+	cfg := testConfig()
+	cfg.FingerprintVersion = fingerprint.V3
+	env := newTestApp(t, cfg)
+	app, svc, repo, vectors := env.App, env.Service, env.Repo, env.Vectors
+	ctx := context.Background()
+
+	img, err := png.Decode(bytes.NewReader(syntheticPNG(t, 256, 256, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1, err := fingerprint.CreateFingerprintVersion(img, fingerprint.V1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v3, err := fingerprint.CreateFingerprintVersion(img, fingerprint.V3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// An image embedded through the API is indexed at the configured version.
+	watermark(t, app, syntheticPNG(t, 256, 256, 2), `{"title":"fresh"}`)
+
+	// Legacy rows: an unversioned v1 vector, a v3 vector whose row was
+	// never marked, a v2 vector that can't be converted, and no vector.
+	ids := make([]uuid.UUID, 4)
+	for i := range ids {
+		id, _, err := repo.InsertImageMetadata(ctx, models.ImageMetadata{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids[i] = id
+	}
+	mustStore := func(id uuid.UUID, vec fingerprint.Vector, v fingerprint.Version) {
+		if err := vectors.Store(ctx, id, vec, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mustStore(ids[0], v1, 0)
+	mustStore(ids[1], v3, fingerprint.V3)
+	mustStore(ids[2], v1, fingerprint.V2)
+
+	status, p := getIndexProgress(t, app, testAdminKey)
+	if status != fiber.StatusOK {
+		t.Fatalf("progress returned %d", status)
+	}
+	if p.Total != 5 || p.Current != 1 || p.Unindexed != 4 || p.TargetVersion != int(fingerprint.V3) {
+		t.Fatalf("progress before re-index = %+v", p)
+	}
+
+	report, err := svc.ReindexBatch(ctx, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report != (services.ReindexReport{Processed: 4, Backfilled: 1, Migrated: 1, Failed: 2}) {
+		t.Fatalf("ReindexBatch = %+v", report)
+	}
+
+	// The unversioned v1 vector now is the v3 one.
+	got, v, err := vectors.Get(ctx, ids[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != fingerprint.V3 {
+		t.Fatalf("migrated vector records v%d", v)
+	}
+	want, _, _ := vectors.Get(ctx, ids[1])
+	for i := range got {
+		if d := got[i] - want[i]; d > 1e-6 || d < -1e-6 {
+			t.Fatalf("migrated vector differs at %d: %v vs %v", i, got[i], want[i])
+		}
+	}
+
+	_, p = getIndexProgress(t, app, testAdminKey)
+	if p.Current != 3 || p.Failed != 2 || p.Unindexed != 0 || p.Stale != 0 {
+		t.Fatalf("progress after re-index = %+v", p)
+	}
+
+	// Nothing is left to do.
+	report, err = svc.ReindexBatch(ctx, 100)
+	if err != nil || report.Processed != 0 {
+		t.Fatalf("second ReindexBatch = %+v, %v", report, err)
+	}
+
+	if status, _ := getIndexProgress(t, app, ""); status != fiber.StatusUnauthorized {
+		t.Fatalf("progress without API key returned %d, want 401", status)
+	}
+}
+
+func TestMigrateIndexBeforeServing(t *testing.T) {
+	ctx := context.Background()
+	img, err := png.Decode(bytes.NewReader(syntheticPNG(t, 256, 256, 1)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1, err := fingerprint.CreateFingerprintVersion(img, fingerprint.V1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A server configured for target, on a store from before versions were
+	// recorded: unversioned v1 vectors whose rows were never marked indexed.
+	legacyApp := func(t *testing.T, target fingerprint.Version) *testEnv {
+		cfg := testConfig()
+		cfg.FingerprintVersion = target
+		env := newTestApp(t, cfg)
+		for i := 0; i < 3; i++ {
+			id, _, err := env.Repo.InsertImageMetadata(ctx, models.ImageMetadata{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := env.Vectors.Store(ctx, id, v1, 0); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return env
+	}
+
+	t.Run("derivable", func(t *testing.T) {
+		svc := legacyApp(t, fingerprint.V3).Service
+
+		report, err := svc.MigrateIndex(ctx)
+		if err != nil || report != (services.ReindexReport{Processed: 3, Migrated: 3}) {
+			t.Fatalf("MigrateIndex = %+v, %v", report, err)
+		}
+		p, err := svc.IndexProgress(ctx)
+		if err != nil || p.Current != 3 || p.Stale+p.Unindexed+p.Failed != 0 {
+			t.Fatalf("progress after MigrateIndex = %+v, %v", p, err)
+		}
+
+		// A second start has nothing to do.
+		if report, err := svc.MigrateIndex(ctx); err != nil || report.Processed != 0 {
+			t.Fatalf("second MigrateIndex = %+v, %v", report, err)
+		}
+	})
+
+	t.Run("not derivable", func(t *testing.T) {
+		svc := legacyApp(t, fingerprint.V4).Service
+
+		if _, err := svc.MigrateIndex(ctx); err == nil {
+			t.Fatal("MigrateIndex to v4 succeeded on a v1 store")
+		}
+		p, err := svc.IndexProgress(ctx)
+		if err != nil || p.Unindexed != 3 || p.Failed != 0 {
+			t.Fatalf("refused migration changed the index: %+v, %v", p, err)
+		}
+	})
+
+	// Rows already recorded at v3 can't go back to v1, even though v1 is
+	// the oldest version.
+	t.Run("recorded newer", func(t *testing.T) {
+		env := legacyApp(t, fingerprint.V1)
+		ids, err := env.Repo.ListPendingIndex(ctx, fingerprint.V1, 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, id := range ids {
+			if err := env.Repo.MarkIndexed(ctx, id, fingerprint.V3); err != nil {
+				t.Fatal(err)
+			}
+		}
+		svc := env.Service
+
+		if _, err := svc.MigrateIndex(ctx); err == nil {
+			t.Fatal("MigrateIndex to v1 succeeded on a v3 store")
+		}
+		p, err := svc.IndexProgress(ctx)
+		if err != nil || p.Stale != 3 || p.Failed != 0 {
+			t.Fatalf("refused migration changed the index: %+v, %v", p, err)
+		}
+	})
+}
@@ -0,0 +1,161 @@
+package integration
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/services"
+	"github.com/JacobGeorgeMathew/MiniProject_Media_Authentication_Platform-/Backend/internals/watermark/fingerprint"
+)
+
+func decodePage(t *testing.T, resp *http.Response) services.SimilarPage {
+	t.Helper()
+	var page services.SimilarPage
+	if err := json.Unmarshal(expectStatus(t, resp, fiber.StatusOK), &page); err != nil {
+		t.Fatal(err)
+	}
+	return page
+}
+
+func doRequest(t *testing.T, app *fiber.App, method, path string, body []byte) *http.Response {
+	t.Helper()
+	req := httptest.NewRequest(method, path, bytes.NewReader(body))
+	if body != nil {
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	}
+	resp, err := app.Test(req, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestSimilarityEndpoints(t *testing.T) {
+	app := newTestApp(t, testConfig()).App
+
+	original := syntheticPNG(t, 256, 256, 0)
+	resp := postImage(t, app, "/api/v1/watermark", original, map[string]string{"metadata": `{"title":"a"}`})
+	marked := expectStatus(t, resp, fiber.StatusOK)
+	fpHeader := []byte(resp.Header.Get("X-Fingerprint"))
+
+	watermark(t, app, syntheticPNG(t, 256, 256, 0.05), `{"title":"b"}`)
+	watermark(t, app, texturedPNG(t, 256, 256, 9), `{"title":"c"}`)
+
+	// The catalogue ID of the first image, from authenticating it.
+	var auth services.AuthResult
+	if err := json.Unmarshal(expectStatus(t, postImage(t, app, "/api/v1/authenticate", marked, nil), fiber.StatusOK), &auth); err != nil {
+		t.Fatal(err)
+	}
+	id := auth.Layers[0].Metadata.ID
+
+	t.Run("by ID", func(t *testing.T) {
+		page := decodePage(t, doRequest(t, app, http.MethodGet, "/api/v1/similar/"+id.String()+"?limit=1", nil))
+		if len(page.Neighbours) != 1 || *page.Neighbours[0].Metadata.Title != "b" ||
+			page.NextOffset == nil || *page.NextOffset != 1 {
+			t.Fatalf("first page = %+v", page)
+		}
+
+		page = decodePage(t, doRequest(t, app, http.MethodGet, "/api/v1/similar/"+id.String()+"?limit=1&offset=1", nil))
+		if len(page.Neighbours) != 1 || *page.Neighbours[0].Metadata.Title != "c" || page.NextOffset != nil {
+			t.Fatalf("last page = %+v", page)
+		}
+	})
+
+	t.Run("by fingerprint", func(t *testing.T) {
+		fp, err := fingerprint.ParseVector(string(fpHeader))
+		if err != nil {
+			t.Fatal(err)
+		}
+		asArray, _ := json.Marshal(fp.Float64s())
+		asString, _ := json.Marshal(string(fpHeader))
+
+		for name, body := range map[string][]byte{"header value": fpHeader, "JSON string": asString, "JSON array": asArray} {
+			page := decodePage(t, doRequest(t, app, http.MethodPost, "/api/v1/similar/fingerprint?limit=2", body))
+			if len(page.Neighbours) != 2 || page.Neighbours[0].ImageID != id || page.Neighbours[0].Score < 0.999 {
+				t.Fatalf("%s: page = %+v", name, page)
+			}
+		}
+	})
+
+	t.Run("by image", func(t *testing.T) {
+		page := decodePage(t, postImage(t, app, "/api/v1/similar/image", marked, map[string]string{"min_score": "0.95"}))
+		for _, n := range page.Neighbours {
+			if n.Score < 0.95 {
+				t.Fatalf("neighbour below min_score: %+v", n)
+			}
+		}
+		if len(page.Neighbours) == 0 || page.Neighbours[0].ImageID != id || page.Neighbours[0].Verdict != "exact-copy" {
+			t.Fatalf("page = %+v", page)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		expectStatus(t, doRequest(t, app, http.MethodGet, "/api/v1/similar/not-a-uuid", nil), fiber.StatusBadRequest)
+		expectStatus(t, doRequest(t, app, http.MethodGet, "/api/v1/similar/"+uuid.NewString(), nil), fiber.StatusNotFound)
+		expectStatus(t, doRequest(t, app, http.MethodGet, "/api/v1/similar/"+id.String()+"?limit=0", nil), fiber.StatusBadRequest)
+		expectStatus(t, doRequest(t, app, http.MethodPost, "/api/v1/similar/fingerprint", []byte("[1,2,3]")), fiber.StatusBadRequest)
+		expectStatus(t, doRequest(t, app, http.MethodPost, "/api/v1/similar/fingerprint", []byte("{")), fiber.StatusBadRequest)
+		expectStatus(t, doRequest(t, app, http.MethodPost, "/api/v1/similar/fingerprint", []byte("AAAA")), fiber.StatusBadRequest)
+	})
+}
+
+func TestSimilarityFiltersOnAttributes(t *testing.T) {
+	app := newTestApp(t, testConfig()).App
+
+	query := syntheticPNG(t, 256, 256, 0)
+	watermark(t, app, syntheticPNG(t, 256, 256, 0.02), `{"title":"acme photo","owner":"acme","tags":["news","2025"]}`)
+	watermark(t, app, syntheticPNG(t, 256, 256, 0.04), `{"title":"acme render","owner":"acme","is_ai_generated":true,"tags":["news"]}`)
+	watermark(t, app, syntheticPNG(t, 256, 256, 0.06), `{"title":"other photo","owner":"other"}`)
+
+	titles := func(fields map[string]string) []string {
+		t.Helper()
+		page := decodePage(t, postImage(t, app, "/api/v1/similar/image", query, fields))
+		var got []string
+		for _, n := range page.Neighbours {
+			got = append(got, *n.Metadata.Title)
+		}
+		sort.Strings(got)
+		return got
+	}
+
+	today := time.Now().UTC().Format(time.DateOnly)
+	tomorrow := time.Now().UTC().AddDate(0, 0, 1).Format(time.DateOnly)
+
+	tests := []struct {
+		name   string
+		fields map[string]string
+		want   []string
+	}{
+		{"no filter", nil, []string{"acme photo", "acme render", "other photo"}},
+		{"owner and not AI", map[string]string{"owner": "acme", "is_ai_generated": "false"}, []string{"acme photo"}},
+		{"tags", map[string]string{"tags": "news, 2025"}, []string{"acme photo"}},
+		{"mime type", map[string]string{"mime_type": "image/jpeg"}, nil},
+		{"created today", map[string]string{"owner": "other", "created_from": today, "created_to": tomorrow}, []string{"other photo"}},
+		{"created before today", map[string]string{"created_to": today}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := titles(tt.fields); !slices.Equal(got, tt.want) {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("errors", func(t *testing.T) {
+		// Parsed before the image is looked up, so an unknown ID still
+		// answers 400.
+		for _, q := range []string{"is_ai_generated=maybe", "created_from=yesterday", "created_from=2025-02-01&created_to=2025-01-01"} {
+			expectStatus(t, doRequest(t, app, http.MethodGet, "/api/v1/similar/"+uuid.NewString()+"?"+q, nil), fiber.StatusBadRequest)
+		}
+	})
+}